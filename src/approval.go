@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	PolicyFixed      = "fixed"
+	PolicyPercentage = "percentage"
+	PolicyUnanimous  = "unanimous"
+
+	RejectionVeto     = "veto"
+	RejectionMajority = "majority"
+)
+
+var defaultApprovalPolicy = ApprovalPolicy{Mode: PolicyFixed, Count: 3, Rejection: RejectionVeto, Source: "default"}
+
+type ApprovalPolicy struct {
+	Mode               string     `json:"mode"`
+	Count              int        `json:"count,omitempty"`
+	Percent            int        `json:"percent,omitempty"`
+	MandatoryApprovers []string   `json:"mandatoryApprovers,omitempty"`
+	Rejection          string     `json:"rejection"`
+	Source             string     `json:"source,omitempty"`
+	UpdatedBy          string     `json:"updatedBy,omitempty"`
+	UpdatedAt          *time.Time `json:"updatedAt,omitempty"`
+}
+
+type ApprovalVote struct {
+	BidID     uuid.UUID `json:"-"`
+	LotID     uuid.UUID `json:"-"`
+	Username  string    `json:"username"`
+	Decision  string    `json:"decision"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ApprovalState struct {
+	BidID              uuid.UUID      `json:"bidId"`
+	LotID              *uuid.UUID     `json:"lotId,omitempty"`
+	Status             string         `json:"status"`
+	Policy             ApprovalPolicy `json:"policy"`
+	Required           int            `json:"required"`
+	RemainingApprovals int            `json:"remainingApprovals"`
+	ApprovedBy         []string       `json:"approvedBy"`
+	RejectedBy         []string       `json:"rejectedBy"`
+	Pending            []string       `json:"pending"`
+	MissingMandatory   []string       `json:"missingMandatory"`
+	Votes              []ApprovalVote `json:"votes"`
+}
+
+func (policy ApprovalPolicy) Required(eligible int) int {
+	required := eligible
+	switch policy.Mode {
+	case PolicyFixed:
+		required = min(policy.Count, eligible)
+	case PolicyPercentage:
+		required = (eligible*policy.Percent + 99) / 100
+	}
+	return max(required, 1)
+}
+
+func CheckApprovalPolicy(ctx context.Context, w http.ResponseWriter, organizationId uuid.UUID, policy *ApprovalPolicy) bool {
+	switch policy.Mode {
+	case PolicyFixed:
+		if policy.Count < 1 {
+			SendErrorResponse(w, ErrorResponse{"count must be positive"}, http.StatusBadRequest)
+			return false
+		}
+		policy.Percent = 0
+	case PolicyPercentage:
+		if policy.Percent < 1 || policy.Percent > 100 {
+			SendErrorResponse(w, ErrorResponse{"percent must be between 1 and 100"}, http.StatusBadRequest)
+			return false
+		}
+		policy.Count = 0
+	case PolicyUnanimous:
+		policy.Count, policy.Percent = 0, 0
+	default:
+		SendErrorResponse(w, ErrorResponse{"Invalid policy mode"}, http.StatusBadRequest)
+		return false
+	}
+	if policy.Rejection == "" {
+		policy.Rejection = RejectionVeto
+	}
+	if policy.Rejection != RejectionVeto && policy.Rejection != RejectionMajority {
+		SendErrorResponse(w, ErrorResponse{"Invalid rejection rule"}, http.StatusBadRequest)
+		return false
+	}
+	seen := map[string]bool{}
+	var mandatory []string
+	for _, username := range policy.MandatoryApprovers {
+		if seen[username] {
+			continue
+		}
+		seen[username] = true
+		role, ok := GetMemberRole(ctx, w, organizationId, username)
+		if !ok {
+			return false
+		}
+		if !RoleAllows(role, PermDecideBid) {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("User %s can't approve bids of the organization", username)}, http.StatusBadRequest)
+			return false
+		}
+		mandatory = append(mandatory, username)
+	}
+	policy.MandatoryApprovers = mandatory
+	policy.Source, policy.UpdatedBy, policy.UpdatedAt = "", "", nil
+	return true
+}
+
+func ResolveApprovalPolicy(ctx context.Context, w http.ResponseWriter, tender Tender) (ApprovalPolicy, bool) {
+	policy, err := storage.Policies.GetTenderPolicy(ctx, tender.ID)
+	if err == nil {
+		policy.Source = "tender"
+		return policy, true
+	}
+	if errors.Is(err, ErrNotFound) {
+		policy, err = storage.Policies.GetOrganizationPolicy(ctx, tender.OrganizationID)
+		if err == nil {
+			policy.Source = "organization"
+			return policy, true
+		}
+		if errors.Is(err, ErrNotFound) {
+			return defaultApprovalPolicy, true
+		}
+	}
+	log.Println(err.Error())
+	SendErrorResponse(w, ErrorResponse{"Failed to find approval policy"}, http.StatusInternalServerError)
+	return policy, false
+}
+
+func ListApprovers(ctx context.Context, w http.ResponseWriter, organizationId uuid.UUID) ([]string, bool) {
+	members, err := storage.Organizations.ListMembers(ctx, organizationId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find members"}, http.StatusInternalServerError)
+		return nil, false
+	}
+	var approvers []string
+	for _, member := range members {
+		if RoleAllows(member.Role, PermDecideBid) {
+			approvers = append(approvers, member.Username)
+		}
+	}
+	return approvers, true
+}
+
+func EvaluateApproval(ctx context.Context, w http.ResponseWriter, tender Tender, bid Bid, lotId uuid.UUID) (ApprovalState, bool) {
+	state := ApprovalState{BidID: bid.ID, LotID: nullID(lotId), ApprovedBy: []string{}, RejectedBy: []string{}, Pending: []string{}, MissingMandatory: []string{}}
+	policy, ok := ResolveApprovalPolicy(ctx, w, tender)
+	if !ok {
+		return state, false
+	}
+	approvers, ok := ListApprovers(ctx, w, tender.OrganizationID)
+	if !ok {
+		return state, false
+	}
+	votes, err := storage.Bids.ListVotes(ctx, bid.ID, lotId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find approvals"}, http.StatusInternalServerError)
+		return state, false
+	}
+	state.Policy, state.Votes = policy, votes
+	if state.Votes == nil {
+		state.Votes = []ApprovalVote{}
+	}
+	voted := map[string]string{}
+	for _, vote := range votes {
+		voted[vote.Username] = vote.Decision
+		if vote.Decision == "Approved" {
+			state.ApprovedBy = append(state.ApprovedBy, vote.Username)
+		} else {
+			state.RejectedBy = append(state.RejectedBy, vote.Username)
+		}
+	}
+	for _, username := range approvers {
+		if _, ok := voted[username]; !ok {
+			state.Pending = append(state.Pending, username)
+		}
+	}
+	mandatoryRejected := false
+	for _, username := range policy.MandatoryApprovers {
+		switch voted[username] {
+		case "Approved":
+		case "Rejected":
+			mandatoryRejected = true
+			state.MissingMandatory = append(state.MissingMandatory, username)
+		default:
+			state.MissingMandatory = append(state.MissingMandatory, username)
+		}
+	}
+	state.Required = policy.Required(len(approvers))
+	state.RemainingApprovals = max(state.Required-len(state.ApprovedBy), len(state.MissingMandatory), 0)
+	unreachable := mandatoryRejected || len(state.ApprovedBy)+len(state.Pending) < state.Required
+	switch {
+	case policy.Rejection == RejectionVeto && len(state.RejectedBy) > 0,
+		policy.Rejection == RejectionMajority && (len(state.RejectedBy)*2 > len(approvers) || unreachable):
+		state.Status = "Rejected"
+	case len(state.ApprovedBy) >= state.Required && len(state.MissingMandatory) == 0:
+		state.Status = "Approved"
+	default:
+		state.Status = "Pending"
+	}
+	return state, true
+}
+
+func CheckUserVoteExists(ctx context.Context, w http.ResponseWriter, bid Bid, lotId uuid.UUID, username string) bool {
+	exists, err := storage.Bids.VoteExists(ctx, bid.ID, lotId, username)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find approvals"}, http.StatusInternalServerError)
+		return false
+	}
+	if exists {
+		SendErrorResponse(w, ErrorResponse{"User has already voted on this bid"}, http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+func RecordVote(ctx context.Context, w http.ResponseWriter, bid *Bid, decision, username string) bool {
+	if decision == "Approved" {
+		bid.ApprovedCount++
+	}
+	bid.ModifiedBy = username
+	bid.Version++
+	err := storage.Bids.Update(ctx, bid)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to edit bid decision"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func ReadApprovalPolicy(ctx context.Context, w http.ResponseWriter, r *http.Request, organizationId uuid.UUID) (ApprovalPolicy, bool) {
+	var policy ApprovalPolicy
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return policy, false
+	}
+	if err := json.Unmarshal(buf.Bytes(), &policy); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return policy, false
+	}
+	return policy, CheckApprovalPolicy(ctx, w, organizationId, &policy)
+}
+
+func sendPolicy(w http.ResponseWriter, policy ApprovalPolicy) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(policy)
+}
+
+func ShowOrganizationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowOrganizationPolicyHandler started")
+	ctx := r.Context()
+	organizationId, _, ok := GetMemberTarget(ctx, w, r, PermViewMembers)
+	if !ok {
+		return
+	}
+	policy, err := storage.Policies.GetOrganizationPolicy(ctx, organizationId)
+	if errors.Is(err, ErrNotFound) {
+		policy, err = defaultApprovalPolicy, nil
+	} else if err == nil {
+		policy.Source = "organization"
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find approval policy"}, http.StatusInternalServerError)
+		return
+	}
+	sendPolicy(w, policy)
+}
+
+func SetOrganizationPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SetOrganizationPolicyHandler started")
+	ctx := r.Context()
+	organizationId, username, ok := GetMemberTarget(ctx, w, r, PermManageMembers)
+	if !ok {
+		return
+	}
+	policy, ok := ReadApprovalPolicy(ctx, w, r, organizationId)
+	if !ok {
+		return
+	}
+	policy.UpdatedBy = username
+	if err := storage.Policies.SetOrganizationPolicy(ctx, organizationId, &policy); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to save approval policy"}, http.StatusInternalServerError)
+		return
+	}
+	policy.Source = "organization"
+	sendPolicy(w, policy)
+}
+
+func GetPolicyTender(ctx context.Context, w http.ResponseWriter, r *http.Request, perm Permission) (Tender, string, bool) {
+	var tender Tender
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return tender, username, false
+	}
+	tenderId, ok := ParseID(w, mux.Vars(r)["tenderId"], "tender")
+	if !ok {
+		return tender, username, false
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return tender, username, false
+	}
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return tender, username, false
+	}
+	return tender, username, Authorize(ctx, w, tender.OrganizationID, username, perm)
+}
+
+func ShowTenderPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderPolicyHandler started")
+	ctx := r.Context()
+	tender, _, ok := GetPolicyTender(ctx, w, r, PermViewBid)
+	if !ok {
+		return
+	}
+	policy, ok := ResolveApprovalPolicy(ctx, w, tender)
+	if !ok {
+		return
+	}
+	sendPolicy(w, policy)
+}
+
+func SetTenderPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SetTenderPolicyHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetPolicyTender(ctx, w, r, PermEditTender)
+	if !ok {
+		return
+	}
+	policy, ok := ReadApprovalPolicy(ctx, w, r, tender.OrganizationID)
+	if !ok {
+		return
+	}
+	policy.UpdatedBy = username
+	if err := storage.Policies.SetTenderPolicy(ctx, tender.ID, &policy); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to save approval policy"}, http.StatusInternalServerError)
+		return
+	}
+	policy.Source = "tender"
+	sendPolicy(w, policy)
+}
+
+func DeleteTenderPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("DeleteTenderPolicyHandler started")
+	ctx := r.Context()
+	tender, _, ok := GetPolicyTender(ctx, w, r, PermEditTender)
+	if !ok {
+		return
+	}
+	err := storage.Policies.DeleteTenderPolicy(ctx, tender.ID)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to delete approval policy"}, http.StatusInternalServerError)
+		return
+	}
+	policy, ok := ResolveApprovalPolicy(ctx, w, tender)
+	if !ok {
+		return
+	}
+	sendPolicy(w, policy)
+}
+
+func ShowBidApprovalsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowBidApprovalsHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	bidId, ok := ParseID(w, mux.Vars(r)["bidId"], "bid")
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
+		return
+	}
+	var bid Bid
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
+		bid = bd
+	} else {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, bid.TenderID); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermViewBid) {
+		return
+	}
+	lot, ok := GetDecisionLot(ctx, w, bid, r.URL.Query().Get("lotId"))
+	if !ok {
+		return
+	}
+	lotId := uuid.Nil
+	if lot != nil {
+		lotId = lot.ID
+	}
+	state, ok := EvaluateApproval(ctx, w, tender, bid, lotId)
+	if !ok {
+		return
+	}
+	sort.Strings(state.Pending)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(state)
+}
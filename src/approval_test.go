@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func (env *testEnv) decide(bid Bid, key, decision, lot string, status int) {
+	env.t.Helper()
+	path := "/api/bids/" + bid.ID.String() + "/submit_decision?decision=" + decision
+	if lot != "" {
+		path += "&lotId=" + lot
+	}
+	env.expect("PUT", path, key, nil, status, nil)
+}
+
+func (env *testEnv) storedBid(id uuid.UUID) Bid {
+	env.t.Helper()
+	bid, err := storage.Bids.Get(context.Background(), id)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	return bid
+}
+
+func TestDecisionRequiresSubmittedBidAndOpenTender(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	draft := env.createBid(tender, nil)
+	env.decide(draft, keyAlice, "Approved", "", http.StatusConflict)
+
+	canceled := env.createBid(tender, nil)
+	env.expect("PUT", "/api/bids/"+canceled.ID.String()+"/status?status="+BidCanceled, keyBob, nil, http.StatusOK, nil)
+	env.decide(canceled, keyAlice, "Rejected", "", http.StatusConflict)
+
+	submitted := env.submitBid(tender, nil)
+	ctx := context.Background()
+	stored, err := storage.Tenders.Get(ctx, tender.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored.Status = "Closed"
+	if err := storage.Tenders.Update(ctx, &stored); err != nil {
+		t.Fatal(err)
+	}
+	env.decide(submitted, keyAlice, "Approved", "", http.StatusConflict)
+	for _, bid := range []Bid{draft, canceled, submitted} {
+		if votes, _ := storage.Bids.ListVotes(ctx, bid.ID, uuid.Nil); len(votes) != 0 {
+			t.Fatalf("vote recorded for bid %s: %+v", bid.Name, votes)
+		}
+	}
+}
+
+func TestDecisionDefaultPolicyNeedsAllApprovers(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.submitBid(tender, nil)
+
+	env.decide(bid, keyBob, "Approved", "", http.StatusForbidden)
+	env.decide(bid, keyAlice, "Approved", "", http.StatusOK)
+	if got := env.storedBid(bid.ID); got.Decision != "None" || got.Status != BidSubmitted {
+		t.Fatalf("bid decided after one of two approvals: %s %s", got.Decision, got.Status)
+	}
+	env.decide(bid, keyAlice, "Approved", "", http.StatusNotFound)
+	env.decide(bid, keyDave, "Approved", "", http.StatusOK)
+	if got := env.storedBid(bid.ID); got.Decision != "Approved" || got.Status != BidCanceled {
+		t.Fatalf("got %s %s, want Approved Canceled", got.Decision, got.Status)
+	}
+	w := env.expect("GET", "/api/tenders/"+tender.ID.String()+"/status", keyAlice, nil, http.StatusOK, nil)
+	if status := w.Body.String(); status != "Closed" {
+		t.Fatalf("tender is %s after the award, want Closed", status)
+	}
+	env.decide(bid, keyDave, "Rejected", "", http.StatusBadRequest)
+}
+
+func TestDecisionVetoRejects(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.submitBid(tender, nil)
+
+	env.decide(bid, keyDave, "Rejected", "", http.StatusOK)
+	if got := env.storedBid(bid.ID); got.Decision != "Rejected" {
+		t.Fatalf("got decision %s, want Rejected", got.Decision)
+	}
+	env.decide(bid, keyAlice, "Approved", "", http.StatusBadRequest)
+}
+
+func TestDecisionFixedPolicy(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	path := "/api/tenders/" + tender.ID.String() + "/approval-policy"
+	env.expect("PUT", path, keyDave, map[string]interface{}{"mode": "fixed", "count": 1}, http.StatusForbidden, nil)
+	env.expect("PUT", path, keyAlice, map[string]interface{}{"mode": "fixed", "count": 0}, http.StatusBadRequest, nil)
+	var policy ApprovalPolicy
+	env.expect("PUT", path, keyAlice, map[string]interface{}{"mode": "fixed", "count": 1}, http.StatusOK, &policy)
+	if policy.Source != "tender" || policy.Rejection != RejectionVeto {
+		t.Fatalf("got policy %+v", policy)
+	}
+	bid := env.submitBid(tender, nil)
+	env.decide(bid, keyDave, "Approved", "", http.StatusOK)
+	if got := env.storedBid(bid.ID); got.Decision != "Approved" {
+		t.Fatalf("got decision %s after the single required approval", got.Decision)
+	}
+}
+
+func TestDecisionMandatoryApprover(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	env.expect("PUT", "/api/tenders/"+tender.ID.String()+"/approval-policy", keyAlice,
+		map[string]interface{}{"mode": "fixed", "count": 1, "mandatoryApprovers": []string{"dave"}}, http.StatusOK, nil)
+	bid := env.submitBid(tender, nil)
+	env.decide(bid, keyAlice, "Approved", "", http.StatusOK)
+	var state ApprovalState
+	env.expect("GET", "/api/bids/"+bid.ID.String()+"/approvals", keyAlice, nil, http.StatusOK, &state)
+	if state.Status != "Pending" || len(state.MissingMandatory) != 1 || state.MissingMandatory[0] != "dave" {
+		t.Fatalf("got state %+v", state)
+	}
+	env.decide(bid, keyDave, "Approved", "", http.StatusOK)
+	if got := env.storedBid(bid.ID); got.Decision != "Approved" {
+		t.Fatalf("got decision %s", got.Decision)
+	}
+}
+
+func TestDecisionPerLot(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.createTender("Routes", map[string]interface{}{"currency": "RUB"})
+	var first, second Lot
+	lots := "/api/tenders/" + tender.ID.String() + "/lots"
+	env.expect("POST", lots, keyAlice, map[string]interface{}{"name": "Route 1"}, http.StatusOK, &first)
+	env.expect("POST", lots, keyAlice, map[string]interface{}{"name": "Route 2"}, http.StatusOK, &second)
+	env.expect("PUT", "/api/tenders/"+tender.ID.String()+"/status?status=Published", keyAlice, nil, http.StatusOK, nil)
+	bid := env.submitBid(tender, map[string]interface{}{"lotIds": []uuid.UUID{first.ID, second.ID}})
+
+	env.decide(bid, keyAlice, "Approved", "", http.StatusBadRequest)
+	env.decide(bid, keyAlice, "Rejected", first.ID.String(), http.StatusOK)
+	env.decide(bid, keyDave, "Approved", first.ID.String(), http.StatusBadRequest)
+	if got := env.storedBid(bid.ID); got.Decision != "None" || got.Status != BidSubmitted {
+		t.Fatalf("rejecting one lot decided the whole bid: %s %s", got.Decision, got.Status)
+	}
+	env.decide(bid, keyAlice, "Approved", second.ID.String(), http.StatusOK)
+	env.decide(bid, keyDave, "Approved", second.ID.String(), http.StatusOK)
+	if got := env.storedBid(bid.ID); got.Decision != "Approved" || got.Status != BidCanceled {
+		t.Fatalf("got %s %s once all lots are resolved, want Approved Canceled", got.Decision, got.Status)
+	}
+	awarded, err := storage.Lots.Get(context.Background(), second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if awarded.Status != LotAwarded || awarded.AwardedBidID == nil || *awarded.AwardedBidID != bid.ID {
+		t.Fatalf("got lot %+v", awarded)
+	}
+}
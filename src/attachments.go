@@ -0,0 +1,407 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	AttachmentTender = "tender"
+	AttachmentBid    = "bid"
+	AttachmentReview = "review"
+)
+
+type Attachment struct {
+	ID         uuid.UUID `json:"id"`
+	ParentType string    `json:"parentType"`
+	ParentID   uuid.UUID `json:"parentId"`
+	Name       string    `json:"name"`
+	MimeType   string    `json:"mimeType"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	UploadedBy string    `json:"uploadedBy"`
+	AddedIn    int       `json:"addedInVersion"`
+	RemovedIn  *int      `json:"removedInVersion,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func GetAttachmentParent(ctx context.Context, w http.ResponseWriter, parentType string, parentId uuid.UUID, write bool) (int, bool) {
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return 0, false
+	}
+	switch parentType {
+	case AttachmentTender:
+		if !CheckTenderExists(ctx, w, parentId) {
+			return 0, false
+		}
+		tender, ok := GetTenderInfo(ctx, w, parentId)
+		if !ok {
+			return 0, false
+		}
+		if write {
+			ok = Authorize(ctx, w, tender.OrganizationID, user.Username, PermEditTender)
+		} else {
+			ok = CheckTenderVisible(ctx, w, tender, user.Username, PermViewTender)
+		}
+		return tender.Version, ok
+	case AttachmentBid:
+		if !CheckBidExists(ctx, w, parentId) {
+			return 0, false
+		}
+		bid, ok := GetBidInfo(ctx, w, parentId)
+		if !ok {
+			return 0, false
+		}
+		if IsBidAuthor(ctx, bid, user) {
+			ok = true
+		} else if write {
+			SendErrorResponse(w, ErrorResponse{"Only the bid author can change its attachments"}, http.StatusForbidden)
+			ok = false
+		} else {
+			ok = CheckBidNotSealed(w, bid) && Authorize(ctx, w, bid.OrganizationID, user.Username, PermViewBid)
+		}
+		if !ok || !write {
+			return bid.Version, ok
+		}
+		tender, ok := GetTenderInfo(ctx, w, bid.TenderID)
+		return bid.Version, ok && CheckSubmissionOpen(w, tender)
+	case AttachmentReview:
+		review, err := storage.Reviews.Get(ctx, parentId)
+		if errors.Is(err, ErrNotFound) {
+			SendErrorResponse(w, ErrorResponse{"No such review"}, http.StatusNotFound)
+			return 0, false
+		}
+		if err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to find review"}, http.StatusInternalServerError)
+			return 0, false
+		}
+		if review.Username == user.Username {
+			return 0, true
+		}
+		if write {
+			SendErrorResponse(w, ErrorResponse{"Only the review author can change its attachments"}, http.StatusForbidden)
+			return 0, false
+		}
+		bid, ok := GetBidInfo(ctx, w, review.BidID)
+		return 0, ok && Authorize(ctx, w, bid.OrganizationID, user.Username, PermViewBid)
+	}
+	SendErrorResponse(w, ErrorResponse{"Unknown attachment parent"}, http.StatusBadRequest)
+	return 0, false
+}
+
+func BumpAttachmentParent(ctx context.Context, w http.ResponseWriter, parentType string, parentId uuid.UUID, username string) (int, bool) {
+	switch parentType {
+	case AttachmentTender:
+		tender, ok := GetTenderInfoForUpdate(ctx, w, parentId)
+		if !ok || !AddTenderToVersionsList(ctx, w, tender) {
+			return 0, false
+		}
+		tender.ModifiedBy = username
+		tender.Version++
+		if err := storage.Tenders.Update(ctx, &tender); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to edit tender"}, http.StatusInternalServerError)
+			return 0, false
+		}
+		return tender.Version, true
+	case AttachmentBid:
+		bid, ok := GetBidInfoForUpdate(ctx, w, parentId)
+		if !ok || !AddBidToVersionsList(ctx, w, bid) {
+			return 0, false
+		}
+		bid.ModifiedBy = username
+		bid.Version++
+		if err := storage.Bids.Update(ctx, &bid); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to edit bid"}, http.StatusInternalServerError)
+			return 0, false
+		}
+		return bid.Version, true
+	}
+	return 0, true
+}
+
+func GetAttachmentInfo(ctx context.Context, w http.ResponseWriter, r *http.Request) (Attachment, bool) {
+	attachmentId, ok := ParseID(w, mux.Vars(r)["attachmentId"], "attachment")
+	if !ok {
+		return Attachment{}, false
+	}
+	attachment, err := storage.Attachments.Get(ctx, attachmentId)
+	if errors.Is(err, ErrNotFound) {
+		SendErrorResponse(w, ErrorResponse{"No such attachment"}, http.StatusNotFound)
+		return attachment, false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find attachment"}, http.StatusInternalServerError)
+		return attachment, false
+	}
+	return attachment, true
+}
+
+func DetectAttachmentType(w http.ResponseWriter, r *http.Request, data []byte) (string, bool) {
+	declared := r.Header.Get("Content-Type")
+	if declared == "" || strings.HasPrefix(declared, "application/octet-stream") {
+		declared = http.DetectContentType(data)
+	}
+	mediaType, _, err := mime.ParseMediaType(declared)
+	if err != nil {
+		SendErrorResponse(w, ErrorResponse{"Invalid Content-Type"}, http.StatusBadRequest)
+		return "", false
+	}
+	if !hasStatus(attachmentMimeTypes, mediaType) {
+		SendErrorResponse(w, ErrorResponse{"File type " + mediaType + " is not allowed"}, http.StatusUnsupportedMediaType)
+		return "", false
+	}
+	return mediaType, true
+}
+
+func RestoreAttachments(ctx context.Context, w http.ResponseWriter, parentType string, parentId uuid.UUID, vers, version int) bool {
+	current, err := storage.Attachments.ListActive(ctx, parentType, parentId, version-1)
+	if err == nil {
+		var target []Attachment
+		if target, err = storage.Attachments.ListActive(ctx, parentType, parentId, vers); err == nil {
+			err = restoreAttachmentSet(ctx, current, target, version)
+		}
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to restore attachments"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func restoreAttachmentSet(ctx context.Context, current, target []Attachment, version int) error {
+	kept := map[uuid.UUID]bool{}
+	for _, attachment := range target {
+		kept[attachment.ID] = true
+	}
+	active := map[uuid.UUID]bool{}
+	for _, attachment := range current {
+		active[attachment.ID] = true
+		if kept[attachment.ID] {
+			continue
+		}
+		if err := storage.Attachments.Remove(ctx, attachment.ID, version); err != nil {
+			return err
+		}
+	}
+	for _, attachment := range target {
+		if active[attachment.ID] {
+			continue
+		}
+		attachment.AddedIn, attachment.RemovedIn = version, nil
+		if err := storage.Attachments.Create(ctx, &attachment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func UploadAttachment(w http.ResponseWriter, r *http.Request, parentType, rawId string) {
+	ctx := r.Context()
+	parentId, ok := ParseID(w, rawId, parentType)
+	if !ok {
+		return
+	}
+	if _, ok := GetAttachmentParent(ctx, w, parentType, parentId, true); !ok {
+		return
+	}
+	name := strings.TrimSpace(filepath.Base(r.URL.Query().Get("name")))
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		SendErrorResponse(w, ErrorResponse{"File name is required"}, http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, attachmentMaxBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		SendErrorResponse(w, ErrorResponse{"File exceeds " + strconv.FormatInt(attachmentMaxBytes, 10) + " bytes"}, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil || len(data) == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	mimeType, ok := DetectAttachmentType(w, r, data)
+	if !ok {
+		return
+	}
+	sum := sha256.Sum256(data)
+	attachment := Attachment{ParentType: parentType, ParentID: parentId, Name: name, MimeType: mimeType, Size: int64(len(data)),
+		SHA256: hex.EncodeToString(sum[:])}
+	if user, ok := CurrentPrincipal(ctx); ok {
+		attachment.UploadedBy = user.Username
+	}
+	if err := blobs.Put(ctx, attachment.SHA256, bytes.NewReader(data)); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to store file"}, http.StatusInternalServerError)
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if version, ok := BumpAttachmentParent(ctx, w, parentType, parentId, attachment.UploadedBy); ok {
+			attachment.AddedIn = version
+		} else {
+			return false
+		}
+		if err := storage.Attachments.Create(ctx, &attachment); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to create attachment"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+func ShowAttachments(w http.ResponseWriter, r *http.Request, parentType, rawId string) {
+	ctx := r.Context()
+	parentId, ok := ParseID(w, rawId, parentType)
+	if !ok {
+		return
+	}
+	version, ok := GetAttachmentParent(ctx, w, parentType, parentId, false)
+	if !ok {
+		return
+	}
+	if v := r.URL.Query().Get("version"); v != "" {
+		vers, ok := ParseVersion(w, v)
+		if !ok {
+			return
+		}
+		if vers > version {
+			SendErrorResponse(w, ErrorResponse{"No such version"}, http.StatusNotFound)
+			return
+		}
+		version = vers
+	}
+	attachments, err := storage.Attachments.ListActive(ctx, parentType, parentId, version)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find attachments"}, http.StatusInternalServerError)
+		return
+	}
+	if attachments == nil {
+		attachments = []Attachment{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(attachments)
+}
+
+func UploadTenderAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("UploadTenderAttachmentHandler started")
+	UploadAttachment(w, r, AttachmentTender, mux.Vars(r)["tenderId"])
+}
+
+func ShowTenderAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderAttachmentsHandler started")
+	ShowAttachments(w, r, AttachmentTender, mux.Vars(r)["tenderId"])
+}
+
+func UploadBidAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("UploadBidAttachmentHandler started")
+	UploadAttachment(w, r, AttachmentBid, mux.Vars(r)["bidId"])
+}
+
+func ShowBidAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowBidAttachmentsHandler started")
+	ShowAttachments(w, r, AttachmentBid, mux.Vars(r)["bidId"])
+}
+
+func UploadReviewAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("UploadReviewAttachmentHandler started")
+	UploadAttachment(w, r, AttachmentReview, mux.Vars(r)["reviewId"])
+}
+
+func ShowReviewAttachmentsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowReviewAttachmentsHandler started")
+	ShowAttachments(w, r, AttachmentReview, mux.Vars(r)["reviewId"])
+}
+
+func DownloadAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("DownloadAttachmentHandler started")
+	ctx := r.Context()
+	attachment, ok := GetAttachmentInfo(ctx, w, r)
+	if !ok {
+		return
+	}
+	if _, ok := GetAttachmentParent(ctx, w, attachment.ParentType, attachment.ParentID, false); !ok {
+		return
+	}
+	file, err := blobs.Open(ctx, attachment.SHA256)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to read file"}, http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	w.Header().Set("Content-Type", attachment.MimeType)
+	w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-SHA256", attachment.SHA256)
+	w.WriteHeader(http.StatusOK)
+	if _, err := io.Copy(w, file); err != nil {
+		log.Println(err.Error())
+	}
+}
+
+func DeleteAttachmentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("DeleteAttachmentHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	attachment, ok := GetAttachmentInfo(ctx, w, r)
+	if !ok {
+		return
+	}
+	version, ok := GetAttachmentParent(ctx, w, attachment.ParentType, attachment.ParentID, true)
+	if !ok {
+		return
+	}
+	if attachment.AddedIn > version || attachment.RemovedIn != nil && *attachment.RemovedIn <= version {
+		SendErrorResponse(w, ErrorResponse{"Attachment is not part of the current version"}, http.StatusConflict)
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if vers, ok := BumpAttachmentParent(ctx, w, attachment.ParentType, attachment.ParentID, username); ok {
+			version = vers
+		} else {
+			return false
+		}
+		if err := storage.Attachments.Remove(ctx, attachment.ID, version); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to delete attachment"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
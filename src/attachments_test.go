@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func (env *testEnv) upload(path, key, name, contentType, content string, status int) Attachment {
+	env.t.Helper()
+	r := httptest.NewRequest("POST", path+"?name="+name, bytes.NewBufferString(content))
+	r.Header.Set("X-API-Key", key)
+	r.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, r)
+	if w.Code != status {
+		env.t.Fatalf("upload %s: got %d, want %d: %s", name, w.Code, status, w.Body.String())
+	}
+	var attachment Attachment
+	if status == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &attachment); err != nil {
+			env.t.Fatal(err)
+		}
+	}
+	return attachment
+}
+
+func attachmentNames(attachments []Attachment) string {
+	names := ""
+	for _, attachment := range attachments {
+		names += attachment.Name + ";"
+	}
+	return names
+}
+
+func TestAttachmentUploadAndDownload(t *testing.T) {
+	env := newTestEnv(t)
+	blobs = LocalBlobStore{t.TempDir()}
+	tender := env.publishTender("Road", nil)
+	path := "/api/tenders/" + tender.ID.String() + "/attachments"
+	env.upload(path, keyBob, "spec.txt", "text/plain", "spec", http.StatusForbidden)
+	env.upload(path, keyAlice, "run.sh", "application/x-sh", "#!/bin/sh", http.StatusUnsupportedMediaType)
+	attachment := env.upload(path, keyAlice, "spec.txt", "text/plain", "road spec", http.StatusOK)
+	if attachment.Size != 9 || attachment.UploadedBy != "alice" {
+		t.Fatalf("got %+v", attachment)
+	}
+	w := env.expect("GET", "/api/attachments/"+attachment.ID.String(), keyBob, nil, http.StatusOK, nil)
+	if w.Body.String() != "road spec" || w.Header().Get("X-Content-SHA256") != attachment.SHA256 {
+		t.Fatalf("downloaded %q with hash %s", w.Body.String(), w.Header().Get("X-Content-SHA256"))
+	}
+	env.expect("DELETE", "/api/attachments/"+attachment.ID.String(), keyBob, nil, http.StatusForbidden, nil)
+	env.expect("DELETE", "/api/attachments/"+attachment.ID.String(), keyAlice, nil, http.StatusNoContent, nil)
+	var attachments []Attachment
+	env.expect("GET", path, keyAlice, nil, http.StatusOK, &attachments)
+	if len(attachments) != 0 {
+		t.Fatalf("deleted attachment still listed: %+v", attachments)
+	}
+}
+
+func TestAttachmentsRestoredOnRollback(t *testing.T) {
+	env := newTestEnv(t)
+	blobs = LocalBlobStore{t.TempDir()}
+	tender := env.publishTender("Road", nil)
+	id := tender.ID.String()
+	path := "/api/tenders/" + id + "/attachments"
+	env.upload(path, keyAlice, "a.txt", "text/plain", "a", http.StatusOK)
+	env.expect("PATCH", "/api/tenders/"+id+"/edit", keyAlice, map[string]interface{}{"name": "Road 2"}, http.StatusOK, &tender)
+	env.upload(path, keyAlice, "b.txt", "text/plain", "b", http.StatusOK)
+
+	var attachments []Attachment
+	env.expect("GET", path, keyAlice, nil, http.StatusOK, &attachments)
+	if got := attachmentNames(attachments); got != "a.txt;b.txt;" {
+		t.Fatalf("got %s before rollback", got)
+	}
+	version := tender.Version - 1
+	env.expect("PUT", "/api/tenders/"+id+"/rollback/"+strconv.Itoa(version), keyAlice, nil, http.StatusOK, &tender)
+	env.expect("GET", path, keyAlice, nil, http.StatusOK, &attachments)
+	if got := attachmentNames(attachments); got != "a.txt;" {
+		t.Fatalf("got %s after rollback to version %d", got, version)
+	}
+	env.expect("GET", path+"?version="+strconv.Itoa(tender.Version-1), keyAlice, nil, http.StatusOK, &attachments)
+	if got := attachmentNames(attachments); got != "a.txt;b.txt;" {
+		t.Fatalf("history of version %d changed to %s", tender.Version-1, got)
+	}
+}
+
+func TestBidAttachmentsChangedOnlyByAuthor(t *testing.T) {
+	env := newTestEnv(t)
+	blobs = LocalBlobStore{t.TempDir()}
+	tender := env.publishTender("Road", nil)
+	bid := env.createBid(tender, nil)
+	path := "/api/bids/" + bid.ID.String() + "/attachments"
+	env.upload(path, keyAlice, "offer.txt", "text/plain", "forged", http.StatusForbidden)
+	env.upload(path, keyCarol, "offer.txt", "text/plain", "forged", http.StatusForbidden)
+	attachment := env.upload(path, keyBob, "offer.txt", "text/plain", "offer", http.StatusOK)
+	env.expect("GET", path, keyAlice, nil, http.StatusOK, nil)
+	env.expect("DELETE", "/api/attachments/"+attachment.ID.String(), keyAlice, nil, http.StatusForbidden, nil)
+	env.expect("DELETE", "/api/attachments/"+attachment.ID.String(), keyBob, nil, http.StatusNoContent, nil)
+}
+
+func TestAttachmentChangesCreateVersions(t *testing.T) {
+	env := newTestEnv(t)
+	blobs = LocalBlobStore{t.TempDir()}
+	tender := env.publishTender("Road", nil)
+	id := tender.ID.String()
+	path := "/api/tenders/" + id + "/attachments"
+	attachment := env.upload(path, keyAlice, "spec.txt", "text/plain", "spec", http.StatusOK)
+	if attachment.AddedIn != tender.Version+1 {
+		t.Fatalf("attachment added in version %d, want %d", attachment.AddedIn, tender.Version+1)
+	}
+	env.expect("DELETE", "/api/attachments/"+attachment.ID.String(), keyAlice, nil, http.StatusNoContent, nil)
+
+	var current TenderVersion
+	env.expect("GET", "/api/tenders/"+id+"/versions/"+strconv.Itoa(tender.Version+2), keyAlice, nil, http.StatusOK, &current)
+	if current.ModifiedBy != "alice" {
+		t.Fatalf("got %+v after deleting the attachment", current)
+	}
+	var attachments []Attachment
+	for version, want := range map[int]string{tender.Version: "", tender.Version + 1: "spec.txt;", tender.Version + 2: ""} {
+		env.expect("GET", path+"?version="+strconv.Itoa(version), keyAlice, nil, http.StatusOK, &attachments)
+		if got := attachmentNames(attachments); got != want {
+			t.Fatalf("version %d has attachments %q, want %q", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ModeStandard = "standard"
+	ModeAuction  = "auction"
+)
+
+type AuctionOffer struct {
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type RankingEntry struct {
+	Position  int             `json:"position"`
+	Amount    decimal.Decimal `json:"amount"`
+	Currency  string          `json:"currency"`
+	OfferedAt time.Time       `json:"offeredAt"`
+	Own       bool            `json:"own,omitempty"`
+	BidID     *uuid.UUID      `json:"bidId,omitempty"`
+}
+
+type AuctionRanking struct {
+	TenderID           uuid.UUID        `json:"tenderId"`
+	Status             string           `json:"status"`
+	SubmissionDeadline *time.Time       `json:"submissionDeadline,omitempty"`
+	Step               *decimal.Decimal `json:"step"`
+	BestAmount         *decimal.Decimal `json:"bestAmount,omitempty"`
+	Entries            []RankingEntry   `json:"entries"`
+}
+
+type AuctionBid struct {
+	Bid
+	AuthorType string           `json:"authorType,omitempty"`
+	AuthorID   *uuid.UUID       `json:"authorId,omitempty"`
+	Amount     *decimal.Decimal `json:"amount,omitempty"`
+	Currency   string           `json:"currency,omitempty"`
+	LineItems  []BidLineItem    `json:"lineItems,omitempty"`
+}
+
+func AuctionBidHidden(ctx context.Context, tender Tender, bid Bid, user Principal) bool {
+	if tender.Mode != ModeAuction || IsBidAuthor(ctx, bid, user) {
+		return false
+	}
+	owner, err := allows(ctx, tender.OrganizationID, user.Username, PermViewBid)
+	if err != nil {
+		log.Println(err.Error())
+	}
+	return !owner
+}
+
+func MaskAuctionBid(ctx context.Context, tender Tender, bid Bid, user Principal) AuctionBid {
+	view := AuctionBid{Bid: bid}
+	if !AuctionBidHidden(ctx, tender, bid, user) {
+		view.AuthorType, view.AuthorID = bid.AuthorType, &bid.AuthorID
+		view.Amount, view.Currency, view.LineItems = bid.Amount, bid.Currency, bid.LineItems
+	}
+	return view
+}
+
+func CheckTenderMode(ctx context.Context, w http.ResponseWriter, tender *Tender, current Tender) bool {
+	if tender.Mode == "" {
+		tender.Mode = ModeStandard
+	}
+	switch tender.Mode {
+	case ModeStandard:
+		tender.AuctionStep, tender.AuctionExtension = nil, 0
+	case ModeAuction:
+		if tender.Sealed {
+			SendErrorResponse(w, ErrorResponse{"Sealed bids can't be used in an auction"}, http.StatusBadRequest)
+			return false
+		}
+		if tender.SubmissionDeadline == nil || tender.Currency == "" {
+			SendErrorResponse(w, ErrorResponse{"Auction requires submissionDeadline and currency"}, http.StatusBadRequest)
+			return false
+		}
+		if tender.AuctionStep == nil || !tender.AuctionStep.IsPositive() {
+			SendErrorResponse(w, ErrorResponse{"Auction requires a positive auctionStep"}, http.StatusBadRequest)
+			return false
+		}
+		if tender.AuctionExtension < 0 {
+			SendErrorResponse(w, ErrorResponse{"auctionExtensionSeconds must not be negative"}, http.StatusBadRequest)
+			return false
+		}
+	default:
+		SendErrorResponse(w, ErrorResponse{"Invalid tender mode"}, http.StatusBadRequest)
+		return false
+	}
+	if current.ID == uuid.Nil {
+		return true
+	}
+	if tender.Mode == current.Mode && sameAmount(tender.AuctionStep, current.AuctionStep) && tender.AuctionExtension == current.AuctionExtension {
+		return true
+	}
+	count, err := storage.Bids.CountForTender(ctx, current.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
+		return false
+	}
+	if count > 0 {
+		SendErrorResponse(w, ErrorResponse{"Auction settings can't be changed after bids were submitted"}, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func CheckAuctionPricing(w http.ResponseWriter, tender Tender, bid, current Bid) bool {
+	if tender.Mode != ModeAuction {
+		return true
+	}
+	if !sameAmount(bid.Amount, current.Amount) || len(bid.LineItems) > 0 {
+		SendErrorResponse(w, ErrorResponse{"Auction prices are submitted through the offer endpoint"}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func IsBidAuthor(ctx context.Context, bid Bid, user Principal) bool {
+	if bid.AuthorType != "Organization" {
+		return bid.AuthorID == user.ID
+	}
+	role, err := storage.Organizations.GetRole(ctx, bid.AuthorID, user.Username)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Println(err.Error())
+		}
+		return false
+	}
+	return RoleAllows(role, PermEditBid)
+}
+
+func CheckAuctionOffer(ctx context.Context, w http.ResponseWriter, tender Tender, bid Bid, amount decimal.Decimal) bool {
+	if bid.Status != BidSubmitted || bid.Decision != "None" {
+		SendErrorResponse(w, ErrorResponse{"Only submitted bids without a decision can make offers"}, http.StatusConflict)
+		return false
+	}
+	if bid.Amount != nil && !amount.LessThan(*bid.Amount) {
+		SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Offer must be lower than the current price %s", bid.Amount)}, http.StatusBadRequest)
+		return false
+	}
+	ranked, err := storage.Bids.ListRanked(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
+		return false
+	}
+	if len(ranked) == 0 {
+		return true
+	}
+	if limit := ranked[0].Amount.Sub(*tender.AuctionStep); amount.GreaterThan(limit) {
+		SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Offer must be at most %s", limit)}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func ExtendAuction(tender *Tender, now time.Time) bool {
+	if tender.AuctionExtension <= 0 || tender.SubmissionDeadline == nil {
+		return false
+	}
+	window := time.Duration(tender.AuctionExtension) * time.Second
+	if tender.SubmissionDeadline.Sub(now) >= window {
+		return false
+	}
+	deadline := now.Add(window)
+	tender.SubmissionDeadline = &deadline
+	return true
+}
+
+func SubmitOfferHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SubmitOfferHandler started")
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	bidId, ok := ParseID(w, mux.Vars(r)["bidId"], "bid")
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
+		return
+	}
+	var bid Bid
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
+		bid = bd
+	} else {
+		return
+	}
+	if !IsBidAuthor(ctx, bid, user) {
+		SendErrorResponse(w, ErrorResponse{"Only the bid author can submit offers"}, http.StatusForbidden)
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var offer AuctionOffer
+	if err := json.Unmarshal(buf.Bytes(), &offer); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	if !offer.Amount.IsPositive() {
+		SendErrorResponse(w, ErrorResponse{"Amount must be positive"}, http.StatusBadRequest)
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		var tender Tender
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, bid.TenderID); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if tender.Mode != ModeAuction {
+			SendErrorResponse(w, ErrorResponse{"Tender is not an auction"}, http.StatusBadRequest)
+			return false
+		}
+		if tender.Status != "Published" {
+			SendErrorResponse(w, ErrorResponse{"Auction is not running"}, http.StatusForbidden)
+			return false
+		}
+		if !CheckSubmissionOpen(w, tender) {
+			return false
+		}
+		if bd, ok := GetBidInfoForUpdate(ctx, w, bidId); ok {
+			bid = bd
+		} else {
+			return false
+		}
+		if !CheckAuctionOffer(ctx, w, tender, bid, offer.Amount) {
+			return false
+		}
+		if !AddBidToVersionsList(ctx, w, bid) {
+			return false
+		}
+		bid.Amount, bid.Currency, bid.LineItems = &offer.Amount, tender.Currency, nil
+		if !CheckBidWithinTender(w, tender, bid) {
+			return false
+		}
+		bid.ModifiedBy = user.Username
+		bid.Version++
+		if err := storage.Bids.Update(ctx, &bid); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to submit offer"}, http.StatusInternalServerError)
+			return false
+		}
+		if ExtendAuction(&tender, time.Now()) {
+			if err := storage.Tenders.Update(ctx, &tender); err != nil {
+				log.Println(err.Error())
+				SendErrorResponse(w, ErrorResponse{"Failed to extend auction"}, http.StatusInternalServerError)
+				return false
+			}
+			log.Printf("Auction %s extended until %s\n", tender.ID, tender.SubmissionDeadline.Format(time.RFC3339))
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	SetETag(w, bid.ID, bid.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bid)
+}
+
+func ShowAuctionRankingHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowAuctionRankingHandler started")
+	ctx := r.Context()
+	tender, _, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	user, _ := CurrentPrincipal(ctx)
+	if tender.Mode != ModeAuction {
+		SendErrorResponse(w, ErrorResponse{"Tender is not an auction"}, http.StatusBadRequest)
+		return
+	}
+	ranked, err := storage.Bids.ListRanked(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
+		return
+	}
+	ranking := AuctionRanking{tender.ID, tender.Status, tender.SubmissionDeadline, tender.AuctionStep, nil, []RankingEntry{}}
+	for i, bid := range ranked {
+		entry := RankingEntry{Position: i + 1, Amount: *bid.Amount, Currency: bid.Currency, OfferedAt: bid.ModifiedAt}
+		if IsBidAuthor(ctx, bid, user) {
+			id := bid.ID
+			entry.Own, entry.BidID = true, &id
+		}
+		ranking.Entries = append(ranking.Entries, entry)
+	}
+	if len(ranked) > 0 {
+		ranking.BestAmount = ranked[0].Amount
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ranking)
+}
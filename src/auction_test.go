@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func (env *testEnv) publishAuction(name string, deadline time.Time, extension int) Tender {
+	env.t.Helper()
+	return env.publishTender(name, map[string]interface{}{
+		"mode":                    ModeAuction,
+		"currency":                "RUB",
+		"auctionStep":             "10",
+		"auctionExtensionSeconds": extension,
+		"submissionDeadline":      deadline.UTC().Format(time.RFC3339),
+	})
+}
+
+func (env *testEnv) offer(bid Bid, key, amount string, status int) {
+	env.t.Helper()
+	env.expect("PUT", "/api/bids/"+bid.ID.String()+"/offer", key, map[string]string{"amount": amount}, status, nil)
+}
+
+func TestAuctionOffersRespectStep(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishAuction("Road", time.Now().Add(time.Hour), 0)
+	bobBid := env.submitBid(tender, nil)
+	var carolBid Bid
+	env.expect("POST", "/api/bids/new", keyCarol, map[string]interface{}{
+		"name": "carol", "description": "d", "tenderId": tender.ID, "authorType": "User", "authorId": testCarol,
+	}, http.StatusOK, &carolBid)
+	env.expect("PUT", "/api/bids/"+carolBid.ID.String()+"/submit", keyCarol, nil, http.StatusOK, nil)
+
+	env.offer(bobBid, keyBob, "100", http.StatusOK)
+	env.offer(bobBid, keyCarol, "50", http.StatusForbidden)
+	env.offer(carolBid, keyCarol, "95", http.StatusBadRequest)
+	env.offer(carolBid, keyCarol, "90", http.StatusOK)
+	env.offer(bobBid, keyBob, "100", http.StatusBadRequest)
+
+	var ranking AuctionRanking
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/ranking", keyBob, nil, http.StatusOK, &ranking)
+	if len(ranking.Entries) != 2 || ranking.BestAmount == nil || ranking.BestAmount.String() != "90" {
+		t.Fatalf("got ranking %+v", ranking)
+	}
+	first, second := ranking.Entries[0], ranking.Entries[1]
+	if first.Own || first.BidID != nil || !second.Own || second.BidID == nil || *second.BidID != bobBid.ID {
+		t.Fatalf("ranking exposes other bidders or hides own bid: %+v", ranking.Entries)
+	}
+}
+
+func TestAuctionExtendsNearDeadline(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishAuction("Road", time.Now().Add(time.Minute), 600)
+	bid := env.submitBid(tender, nil)
+	env.offer(bid, keyBob, "100", http.StatusOK)
+	var ranking AuctionRanking
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/ranking", keyAlice, nil, http.StatusOK, &ranking)
+	if ranking.SubmissionDeadline == nil || time.Until(*ranking.SubmissionDeadline) < 9*time.Minute {
+		t.Fatalf("deadline was not extended: %v", ranking.SubmissionDeadline)
+	}
+
+	far := env.publishAuction("Bridge", time.Now().Add(time.Hour), 600)
+	bid = env.submitBid(far, nil)
+	env.offer(bid, keyBob, "100", http.StatusOK)
+	env.expect("GET", "/api/tenders/"+far.ID.String()+"/ranking", keyAlice, nil, http.StatusOK, &ranking)
+	if !ranking.SubmissionDeadline.Equal(*far.SubmissionDeadline) {
+		t.Fatalf("deadline moved from %v to %v", far.SubmissionDeadline, ranking.SubmissionDeadline)
+	}
+}
+
+func TestAuctionOffersOnlyFromUndecidedSubmittedBids(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishAuction("Road", time.Now().Add(time.Hour), 0)
+	draft := env.createBid(tender, nil)
+	env.offer(draft, keyBob, "100", http.StatusConflict)
+	withdrawn := env.submitBid(tender, nil)
+	env.offer(withdrawn, keyBob, "100", http.StatusOK)
+	env.expect("PUT", "/api/bids/"+withdrawn.ID.String()+"/withdraw", keyBob, map[string]string{"reason": "changed plans"}, http.StatusOK, nil)
+	env.offer(withdrawn, keyBob, "90", http.StatusConflict)
+
+	var ranking AuctionRanking
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/ranking", keyAlice, nil, http.StatusOK, &ranking)
+	if len(ranking.Entries) != 0 {
+		t.Fatalf("withdrawn bid still ranked: %+v", ranking.Entries)
+	}
+}
+
+func TestAuctionBidsHideBidders(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishAuction("Road", time.Now().Add(time.Hour), 0)
+	bid := env.submitBid(tender, map[string]interface{}{"description": "asphalt works"})
+	env.offer(bid, keyBob, "100", http.StatusOK)
+	env.offer(bid, keyBob, "80", http.StatusOK)
+
+	var listed []map[string]interface{}
+	env.expect("GET", "/api/bids/"+tender.ID.String()+"/list", keyAlice, nil, http.StatusOK, &listed)
+	if len(listed) != 1 || listed[0]["authorId"] != testBob.String() || listed[0]["amount"] != "80" {
+		t.Fatalf("tender owner can't see the bidder: %+v", listed)
+	}
+	var hits []map[string]interface{}
+	env.expect("GET", "/api/bids/search?q=asphalt", keyCarol, nil, http.StatusOK, &hits)
+	if len(hits) != 1 || hits[0]["authorId"] != nil || hits[0]["amount"] != nil {
+		t.Fatalf("search exposes the bidder: %+v", hits)
+	}
+	env.expect("GET", "/api/bids/search?q=asphalt", keyBob, nil, http.StatusOK, &hits)
+	if len(hits) != 1 || hits[0]["authorId"] != testBob.String() || hits[0]["amount"] != "80" {
+		t.Fatalf("author can't see own bid: %+v", hits)
+	}
+}
+
+func TestAuctionBidVersionsHideBidders(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishAuction("Road", time.Now().Add(time.Hour), 0)
+	bid := env.submitBid(tender, nil)
+	env.offer(bid, keyBob, "100", http.StatusOK)
+	env.offer(bid, keyBob, "80", http.StatusOK)
+	path := "/api/bids/" + bid.ID.String()
+
+	var versions []map[string]interface{}
+	env.expect("GET", path+"/versions", keyCarol, nil, http.StatusOK, &versions)
+	for _, version := range versions {
+		if version["modifiedBy"] != nil {
+			t.Fatalf("versions expose the bidder: %+v", versions)
+		}
+	}
+	var version map[string]interface{}
+	env.expect("GET", path+"/versions/3", keyCarol, nil, http.StatusOK, &version)
+	if version["authorId"] != nil || version["amount"] != nil || version["modifiedBy"] != nil {
+		t.Fatalf("version exposes the bidder: %+v", version)
+	}
+	var diff VersionDiff
+	env.expect("GET", path+"/versions/diff?from=3", keyCarol, nil, http.StatusOK, &diff)
+	if len(diff.Changes) != 0 {
+		t.Fatalf("diff exposes the price history: %+v", diff.Changes)
+	}
+
+	for _, key := range []string{keyBob, keyAlice} {
+		env.expect("GET", path+"/versions/3", key, nil, http.StatusOK, &version)
+		if version["authorId"] != testBob.String() || version["amount"] != "100" || version["modifiedBy"] != "bob" {
+			t.Fatalf("got %+v", version)
+		}
+		env.expect("GET", path+"/versions/diff?from=3", key, nil, http.StatusOK, &diff)
+		if len(diff.Changes) != 1 || diff.Changes[0].Field != "amount" {
+			t.Fatalf("got diff %+v", diff.Changes)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	authMethodAPIKey = "apikey"
+	authMethodToken  = "token"
+	authMethodLegacy = "legacy"
+)
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+var jwtSecret []byte
+
+var tokenTTL = time.Hour
+
+var legacyAuth bool
+
+type APIKey struct {
+	ID         uuid.UUID `json:"id"`
+	EmployeeID uuid.UUID `json:"employeeId"`
+	Username   string    `json:"username"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type Principal struct {
+	ID       uuid.UUID
+	Username string
+	Method   string
+}
+
+type principalKey struct{}
+
+type TokenClaims struct {
+	Username string `json:"username"`
+	jwt.RegisteredClaims
+}
+
+type TokenResponse struct {
+	Token     string `json:"token"`
+	TokenType string `json:"tokenType"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+func initAuth() error {
+	if secret := os.Getenv("AUTH_JWT_SECRET"); secret != "" {
+		jwtSecret = []byte(secret)
+	} else {
+		jwtSecret = make([]byte, 32)
+		if _, err := rand.Read(jwtSecret); err != nil {
+			return err
+		}
+		log.Println("AUTH_JWT_SECRET is not set, using a random secret: tokens will not survive a restart")
+	}
+	if d, ok, err := envDuration("AUTH_TOKEN_TTL"); err != nil {
+		return err
+	} else if ok {
+		tokenTTL = d
+	}
+	legacyAuth = os.Getenv("AUTH_LEGACY_USERNAME") == "true"
+	if legacyAuth {
+		log.Println("Legacy username parameter authentication is enabled")
+	}
+	return nil
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func GenerateAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "tk_" + hex.EncodeToString(buf), nil
+}
+
+func IssueToken(principal Principal) (string, error) {
+	now := time.Now()
+	claims := TokenClaims{
+		Username: principal.Username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   principal.ID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(tokenTTL)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+}
+
+func parseToken(ctx context.Context, value string) (Principal, error) {
+	var claims TokenClaims
+	_, err := jwt.ParseWithClaims(value, &claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtSecret, nil
+	})
+	if err != nil {
+		log.Println(err.Error())
+		return Principal{}, errInvalidCredentials
+	}
+	id, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Principal{}, errInvalidCredentials
+	}
+	username, err := storage.Organizations.GetUsername(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return Principal{}, errInvalidCredentials
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{id, username, authMethodToken}, nil
+}
+
+func parseAPIKey(ctx context.Context, value string) (Principal, error) {
+	key, err := storage.APIKeys.FindByHash(ctx, HashAPIKey(value))
+	if errors.Is(err, ErrNotFound) {
+		return Principal{}, errInvalidCredentials
+	}
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{key.EmployeeID, key.Username, authMethodAPIKey}, nil
+}
+
+func parseLegacyUsername(ctx context.Context, r *http.Request) (Principal, bool, error) {
+	url := r.URL.Query()
+	username := url.Get("username")
+	if username == "" {
+		username = url.Get("requesterUsername")
+	}
+	if username == "" {
+		return Principal{}, false, nil
+	}
+	id, err := storage.Organizations.GetUserID(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return Principal{}, false, errInvalidCredentials
+	}
+	if err != nil {
+		return Principal{}, false, err
+	}
+	return Principal{id, username, authMethodLegacy}, true, nil
+}
+
+func Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var principal Principal
+		var err error
+		header := r.Header.Get("Authorization")
+		switch {
+		case strings.HasPrefix(header, "Bearer "):
+			principal, err = parseToken(ctx, strings.TrimPrefix(header, "Bearer "))
+		case strings.HasPrefix(header, "ApiKey "):
+			principal, err = parseAPIKey(ctx, strings.TrimPrefix(header, "ApiKey "))
+		case r.Header.Get("X-API-Key") != "":
+			principal, err = parseAPIKey(ctx, r.Header.Get("X-API-Key"))
+		case header != "":
+			err = errInvalidCredentials
+		case legacyAuth:
+			var ok bool
+			principal, ok, err = parseLegacyUsername(ctx, r)
+			if err == nil && !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+		default:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			if errors.Is(err, errInvalidCredentials) {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				SendErrorResponse(w, ErrorResponse{"Invalid credentials"}, http.StatusUnauthorized)
+				return
+			}
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to authenticate"}, http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, principalKey{}, principal)))
+	})
+}
+
+func CurrentPrincipal(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+func GetCurrentUser(ctx context.Context, w http.ResponseWriter) (Principal, bool) {
+	principal, ok := CurrentPrincipal(ctx)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+		return principal, false
+	}
+	return principal, true
+}
+
+func GetCurrentUsername(ctx context.Context, w http.ResponseWriter) (string, bool) {
+	principal, ok := GetCurrentUser(ctx, w)
+	return principal.Username, ok
+}
+
+func IssueTokenHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("IssueTokenHandler started")
+	ctx := r.Context()
+	principal, ok := CurrentPrincipal(ctx)
+	if !ok {
+		buf := new(bytes.Buffer)
+		buf.ReadFrom(r.Body)
+		var body struct {
+			APIKey string `json:"apiKey"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &body); err != nil || body.APIKey == "" {
+			w.Header().Set("WWW-Authenticate", "ApiKey")
+			SendErrorResponse(w, ErrorResponse{"No API key provided"}, http.StatusUnauthorized)
+			return
+		}
+		pr, err := parseAPIKey(ctx, body.APIKey)
+		if err != nil {
+			if errors.Is(err, errInvalidCredentials) {
+				SendErrorResponse(w, ErrorResponse{"Invalid credentials"}, http.StatusUnauthorized)
+				return
+			}
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to authenticate"}, http.StatusInternalServerError)
+			return
+		}
+		principal = pr
+	}
+	if principal.Method != authMethodAPIKey {
+		SendErrorResponse(w, ErrorResponse{"Tokens can only be issued for an API key"}, http.StatusForbidden)
+		return
+	}
+	token, err := IssueToken(principal)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to issue token"}, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TokenResponse{token, "Bearer", int(tokenTTL.Seconds())})
+}
+
+func RunAPIKeyCommand(args []string) error {
+	if backend := os.Getenv("STORAGE"); backend != "" && backend != "postgres" {
+		return fmt.Errorf("api keys can only be managed for the postgres backend, use MEMORY_SEED for %q", backend)
+	}
+	if err := initStorage("postgres"); err != nil {
+		return err
+	}
+	defer db.Close()
+	ctx := context.Background()
+	usage := errors.New("usage: server apikey [create <username> [name] | revoke <key id>]")
+	if len(args) < 2 {
+		return usage
+	}
+	switch args[0] {
+	case "create":
+		id, err := storage.Organizations.GetUserID(ctx, args[1])
+		if err != nil {
+			return fmt.Errorf("unable to find employee %q: %v", args[1], err)
+		}
+		name := ""
+		if len(args) > 2 {
+			name = args[2]
+		}
+		secret, err := GenerateAPIKey()
+		if err != nil {
+			return err
+		}
+		key, err := storage.APIKeys.Create(ctx, id, name, HashAPIKey(secret))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("id:  %s\nkey: %s\n", key.ID, secret)
+		fmt.Fprintln(os.Stderr, "Store the key now, it cannot be shown again")
+		return nil
+	case "revoke":
+		id, err := uuid.Parse(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid key id: %q", args[1])
+		}
+		return storage.APIKeys.Revoke(ctx, id)
+	default:
+		return usage
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func signTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, expiresAt time.Time) string {
+	t.Helper()
+	claims := TokenClaims{
+		Username: "alice",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   testAlice.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// withAuthorization sends a request carrying the given Authorization header.
+func (env *testEnv) withAuthorization(method, path, authorization string) *httptest.ResponseRecorder {
+	env.t.Helper()
+	r := httptest.NewRequest(method, path, nil)
+	r.Header.Set("Authorization", authorization)
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, r)
+	return w
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.createTender("Road", nil)
+	if tender.CreatorUsername != "alice" {
+		t.Fatalf("tender created by %q, want the key owner", tender.CreatorUsername)
+	}
+	if w := env.withAuthorization("GET", "/api/tenders/my", "ApiKey "+keyBob); w.Code != http.StatusOK {
+		t.Fatalf("ApiKey header: got %d", w.Code)
+	}
+	env.expect("GET", "/api/tenders/my", "key-unknown", nil, http.StatusUnauthorized, nil)
+	env.expect("GET", "/api/tenders/my", "", nil, http.StatusUnauthorized, nil)
+	env.expect("GET", "/api/tenders/my?username=alice", "", nil, http.StatusUnauthorized, nil)
+}
+
+func TestTokenIssueAndUse(t *testing.T) {
+	env := newTestEnv(t)
+	var issued TokenResponse
+	env.expect("POST", "/api/auth/token", keyAlice, nil, http.StatusOK, &issued)
+	if issued.TokenType != "Bearer" || issued.Token == "" {
+		t.Fatalf("got %+v", issued)
+	}
+	if w := env.withAuthorization("GET", "/api/tenders/my", "Bearer "+issued.Token); w.Code != http.StatusOK {
+		t.Fatalf("issued token: got %d: %s", w.Code, w.Body.String())
+	}
+	if w := env.withAuthorization("POST", "/api/auth/token", "Bearer "+issued.Token); w.Code != http.StatusForbidden {
+		t.Fatalf("token renewal from a token: got %d, want 403", w.Code)
+	}
+}
+
+func TestTokenRejectsForeignAlgorithmAndExpiry(t *testing.T) {
+	env := newTestEnv(t)
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"HS512", signTestToken(t, jwt.SigningMethodHS512, jwtSecret, time.Now().Add(time.Hour))},
+		{"none", signTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, time.Now().Add(time.Hour))},
+		{"expired", signTestToken(t, jwt.SigningMethodHS256, jwtSecret, time.Now().Add(-time.Minute))},
+		{"wrong secret", signTestToken(t, jwt.SigningMethodHS256, []byte("other-secret"), time.Now().Add(time.Hour))},
+	}
+	if w := env.withAuthorization("GET", "/api/tenders/my", "Bearer "+signTestToken(t, jwt.SigningMethodHS256, jwtSecret, time.Now().Add(time.Hour))); w.Code != http.StatusOK {
+		t.Fatalf("valid token: got %d", w.Code)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := env.withAuthorization("GET", "/api/tenders/my", "Bearer "+tt.token); w.Code != http.StatusUnauthorized {
+				t.Fatalf("got %d, want 401", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+const (
+	RoleViewer   = "viewer"
+	RoleEditor   = "editor"
+	RoleApprover = "approver"
+	RoleAdmin    = "admin"
+)
+
+type Permission string
+
+const (
+	PermViewTender         Permission = "tender:view"
+	PermCreateTender       Permission = "tender:create"
+	PermEditTender         Permission = "tender:edit"
+	PermChangeTenderStatus Permission = "tender:status"
+	PermRollbackTender     Permission = "tender:rollback"
+	PermViewBid            Permission = "bid:view"
+	PermCreateBid          Permission = "bid:create"
+	PermEditBid            Permission = "bid:edit"
+	PermChangeBidStatus    Permission = "bid:status"
+	PermRollbackBid        Permission = "bid:rollback"
+	PermDecideBid          Permission = "bid:decide"
+	PermScoreBid           Permission = "bid:score"
+	PermReviewBid          Permission = "bid:review"
+	PermViewMembers        Permission = "members:view"
+	PermManageMembers      Permission = "members:manage"
+	PermManageContract     Permission = "contract:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleViewer: {
+		PermViewTender, PermViewBid, PermViewMembers,
+	},
+	RoleEditor: {
+		PermViewTender, PermViewBid, PermViewMembers,
+		PermCreateTender, PermEditTender, PermRollbackTender,
+		PermCreateBid, PermEditBid, PermChangeBidStatus, PermRollbackBid,
+	},
+	RoleApprover: {
+		PermViewTender, PermViewBid, PermViewMembers,
+		PermDecideBid, PermScoreBid, PermReviewBid,
+	},
+	RoleAdmin: {
+		PermViewTender, PermViewBid, PermViewMembers,
+		PermCreateTender, PermEditTender, PermChangeTenderStatus, PermRollbackTender,
+		PermCreateBid, PermEditBid, PermChangeBidStatus, PermRollbackBid,
+		PermDecideBid, PermScoreBid, PermReviewBid,
+		PermManageMembers, PermManageContract,
+	},
+}
+
+type Membership struct {
+	OrganizationID uuid.UUID `json:"organizationId"`
+	UserID         uuid.UUID `json:"userId"`
+	Username       string    `json:"username"`
+	Role           string    `json:"role"`
+}
+
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func RoleAllows(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+func Authorize(ctx context.Context, w http.ResponseWriter, org uuid.UUID, username string, perm Permission) bool {
+	role, err := storage.Organizations.GetRole(ctx, org, username)
+	if errors.Is(err, ErrNotFound) {
+		SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
+		return false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to authorize"}, http.StatusInternalServerError)
+		return false
+	}
+	if !RoleAllows(role, perm) {
+		SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Role %s does not allow %s", role, perm)}, http.StatusForbidden)
+		return false
+	}
+	return true
+}
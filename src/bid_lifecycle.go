@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	BidDraft     = "Created"
+	BidSubmitted = "Published"
+	BidWithdrawn = "Withdrawn"
+	BidCanceled  = "Canceled"
+)
+
+type BidWithdrawal struct {
+	Reason string `json:"reason"`
+}
+
+func GetAuthorBid(ctx context.Context, w http.ResponseWriter, r *http.Request) (Bid, Principal, bool) {
+	var bid Bid
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return bid, user, false
+	}
+	bidId, ok := ParseID(w, mux.Vars(r)["bidId"], "bid")
+	if !ok {
+		return bid, user, false
+	}
+	if !CheckBidExists(ctx, w, bidId) {
+		return bid, user, false
+	}
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
+		bid = bd
+	} else {
+		return bid, user, false
+	}
+	if !IsBidAuthor(ctx, bid, user) {
+		SendErrorResponse(w, ErrorResponse{"Only the bid author can change its status"}, http.StatusForbidden)
+		return bid, user, false
+	}
+	return bid, user, true
+}
+
+func ChangeBidStatus(ctx context.Context, w http.ResponseWriter, r *http.Request, bidId uuid.UUID, status, reason, username string) (Bid, bool) {
+	var bid Bid
+	if status == BidWithdrawn && strings.TrimSpace(reason) == "" {
+		SendErrorResponse(w, ErrorResponse{"Withdrawal reason is required"}, http.StatusBadRequest)
+		return bid, false
+	}
+	expected, ok := GetExpectedVersion(w, r, nil)
+	if !ok {
+		return bid, false
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if bd, ok := GetBidInfoForUpdate(ctx, w, bidId); ok {
+			bid = bd
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, bid.ID, bid.Version, expected) {
+			return false
+		}
+		if err := ApplyBidTransition(ctx, &bid, status, reason, username); err != nil {
+			SendTransitionError(w, err, "Failed to edit bid status")
+			return false
+		}
+		return true
+	})
+	return bid, ok
+}
+
+func SubmitBidHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SubmitBidHandler started")
+	ctx := r.Context()
+	bid, user, ok := GetAuthorBid(ctx, w, r)
+	if !ok {
+		return
+	}
+	bid, ok = ChangeBidStatus(ctx, w, r, bid.ID, BidSubmitted, "", user.Username)
+	if !ok {
+		return
+	}
+	SetETag(w, bid.ID, bid.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bid)
+}
+
+func WithdrawBidHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("WithdrawBidHandler started")
+	ctx := r.Context()
+	bid, user, ok := GetAuthorBid(ctx, w, r)
+	if !ok {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var withdrawal BidWithdrawal
+	if err := json.Unmarshal(buf.Bytes(), &withdrawal); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	bid, ok = ChangeBidStatus(ctx, w, r, bid.ID, BidWithdrawn, withdrawal.Reason, user.Username)
+	if !ok {
+		return
+	}
+	SetETag(w, bid.ID, bid.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(bid)
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestBidLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.createBid(tender, nil)
+	path := "/api/bids/" + bid.ID.String()
+
+	env.expect("PUT", path+"/withdraw", keyBob, map[string]interface{}{"reason": "early"}, http.StatusConflict, nil)
+	env.expect("PUT", path+"/submit", keyBob, nil, http.StatusOK, nil)
+	env.expect("PUT", path+"/submit", keyBob, nil, http.StatusConflict, nil)
+	env.expect("PUT", path+"/withdraw", keyBob, map[string]interface{}{"reason": ""}, http.StatusBadRequest, nil)
+	var withdrawn Bid
+	env.expect("PUT", path+"/withdraw", keyBob, map[string]interface{}{"reason": "price changed"}, http.StatusOK, &withdrawn)
+	if withdrawn.Status != BidWithdrawn || withdrawn.WithdrawalReason != "price changed" {
+		t.Fatalf("got %+v", withdrawn)
+	}
+	env.decide(bid, keyAlice, "Approved", "", http.StatusConflict)
+	env.expect("PUT", path+"/submit", keyBob, nil, http.StatusOK, nil)
+	env.decide(bid, keyAlice, "Rejected", "", http.StatusOK)
+	env.expect("PUT", path+"/withdraw", keyBob, map[string]interface{}{"reason": "late"}, http.StatusConflict, nil)
+}
+
+func TestBidStatusChangedOnlyByAuthor(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.createBid(tender, nil)
+	env.expect("PUT", "/api/bids/"+bid.ID.String()+"/submit", keyAlice, nil, http.StatusForbidden, nil)
+	env.expect("PUT", "/api/bids/"+bid.ID.String()+"/withdraw", keyAlice, map[string]interface{}{"reason": "r"}, http.StatusForbidden, nil)
+	env.expect("PUT", "/api/bids/"+bid.ID.String()+"/status?status=Published", keyAlice, nil, http.StatusForbidden, nil)
+	env.expect("PUT", "/api/bids/"+bid.ID.String()+"/submit", keyBob, nil, http.StatusOK, nil)
+}
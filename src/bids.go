@@ -6,36 +6,50 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 )
 
 type BidReview struct {
 	ID          uuid.UUID `json:"id"`
 	Description string    `json:"description"`
 	CreatedAt   time.Time `json:"createdAt"`
+	BidID       uuid.UUID `json:"-"`
+	Username    string    `json:"-"`
 }
 
 type Bid struct {
-	ID             uuid.UUID `json:"id"`
-	Name           string    `json:"name"`
-	Description    string    `json:"description"`
-	Status         string    `json:"status"`
-	TenderID       uuid.UUID `json:"tenderId"`
-	AuthorType     string    `json:"authorType"`
-	AuthorID       uuid.UUID `json:"authorId"`
-	OrganizationID uuid.UUID `json:"-"`
-	Decision       string    `json:"-"`
-	ApprovedCount  int       `json:"-"`
-	Version        int       `json:"version"`
-	CreatedAt      time.Time `json:"createdAt"`
+	ID               uuid.UUID        `json:"id"`
+	Name             string           `json:"name"`
+	Description      string           `json:"description"`
+	Status           string           `json:"status"`
+	TenderID         uuid.UUID        `json:"tenderId"`
+	AuthorType       string           `json:"authorType"`
+	AuthorID         uuid.UUID        `json:"authorId"`
+	Amount           *decimal.Decimal `json:"amount,omitempty"`
+	Currency         string           `json:"currency,omitempty"`
+	LineItems        []BidLineItem    `json:"lineItems,omitempty"`
+	LotIDs           []uuid.UUID      `json:"lotIds,omitempty"`
+	AboveReserve     bool             `json:"aboveReserve,omitempty"`
+	WithdrawalReason string           `json:"withdrawalReason,omitempty"`
+	WithdrawnAt      *time.Time       `json:"withdrawnAt,omitempty"`
+	Sealed           bool             `json:"-"`
+	SealedData       []byte           `json:"-"`
+	OrganizationID   uuid.UUID        `json:"-"`
+	Decision         string           `json:"-"`
+	ApprovedCount    int              `json:"-"`
+	Version          int              `json:"version"`
+	CreatedAt        time.Time        `json:"createdAt"`
+	ModifiedBy       string           `json:"-"`
+	ModifiedAt       time.Time        `json:"-"`
 }
 
 func CreateBidHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("CreateBidHandler started")
+	ctx := r.Context()
 	buf := new(bytes.Buffer)
 	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
 		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
@@ -47,23 +61,46 @@ func CreateBidHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
 		return
 	}
+	if !CheckBidPricing(w, &bid) {
+		return
+	}
+	user, authenticated := CurrentPrincipal(ctx)
+	if !authenticated && !legacyAuth {
+		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+		return
+	}
 	if bid.AuthorType == "Organization" {
-		if !CheckOrganizationExists(w, bid.AuthorID.String()) {
+		if !CheckOrganizationExists(ctx, w, bid.AuthorID) {
+			return
+		}
+		if authenticated && !Authorize(ctx, w, bid.AuthorID, user.Username, PermCreateBid) {
 			return
 		}
 		bid.OrganizationID = bid.AuthorID
 	} else {
-		if oi, ok := GetOrganizationId(w, bid.AuthorID.String()); ok {
+		if authenticated && bid.AuthorID != user.ID {
+			SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
+			return
+		}
+		if oi, ok := GetOrganizationId(ctx, w, bid.AuthorID); ok {
 			bid.OrganizationID = oi
 		} else {
 			return
 		}
+		if un, ok := GetUsername(ctx, w, bid.AuthorID); ok {
+			bid.ModifiedBy = un
+		} else {
+			return
+		}
+	}
+	if authenticated {
+		bid.ModifiedBy = user.Username
 	}
-	if !CheckTenderExists(w, bid.TenderID.String()) {
+	if !CheckTenderExists(ctx, w, bid.TenderID) {
 		return
 	}
 	var tender Tender
-	if tn, ok := GetTenderInfo(w, bid.TenderID.String()); ok {
+	if tn, ok := GetTenderInfo(ctx, w, bid.TenderID); ok {
 		tender = tn
 	} else {
 		return
@@ -72,13 +109,24 @@ func CreateBidHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
 		return
 	}
-	query := `INSERT INTO bid (name, description, tender_id, author_type, author_id, organization_id)
-              VALUES ($1, $2, $3, $4, $5, $6)
-              RETURNING id, status, version, decision, created_at`
-	err := db.QueryRow(context.Background(), query, bid.Name, bid.Description, bid.TenderID, bid.AuthorType, bid.AuthorID, tender.OrganizationID).Scan(&bid.ID, &bid.Status, &bid.Version, &bid.Decision, &bid.CreatedAt)
+	if !CheckBidderInvited(ctx, w, tender, bid) {
+		return
+	}
+	if !CheckSubmissionOpen(w, tender) {
+		return
+	}
+	if !CheckBidWithinTender(w, tender, bid) || !CheckAuctionPricing(w, tender, bid, Bid{}) {
+		return
+	}
+	if !CheckBidLots(ctx, w, tender, &bid) {
+		return
+	}
+	bid.Sealed = tender.BidsSealed()
+	bid.OrganizationID = tender.OrganizationID
+	err := storage.Bids.Create(ctx, &bid)
 	if err != nil {
 		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to create tender"}, http.StatusInternalServerError)
+		SendErrorResponse(w, ErrorResponse{"Failed to create bid"}, http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -88,61 +136,24 @@ func CreateBidHandler(w http.ResponseWriter, r *http.Request) {
 
 func ShowUsersBidsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ShowUsersBidsHandler started")
-	var err error
-	url := r.URL.Query()
-	query := "SELECT id, name, description, status, tender_id, author_type, author_id, organization_id, version, decision, approved_count, created_at\nFROM bid"
-	args := []interface{}{}
-	if us := url.Get("username"); us != "" {
-		user_id := ""
-		if ui, ok := GetUserId(w, us); ok {
-			user_id = ui
-		} else {
-			return
-		}
-		query += "\nWHERE author_id = $" + strconv.Itoa(len(args)+1)
-		args = append(args, user_id)
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
 		return
 	}
-	query += "\nORDER BY name ASC"
-	if lim := url.Get("limit"); lim != "" {
-		limit := 5
-		if limit, err = strconv.Atoi(lim); err != nil {
-			log.Println(err.Error())
-			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nLIMIT $" + strconv.Itoa(len(args)+1)
-		args = append(args, limit)
-	}
-	if off := url.Get("offset"); off != "" {
-		offset := 0
-		if offset, err = strconv.Atoi(off); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nOFFSET $" + strconv.Itoa(len(args)+1)
-		args = append(args, offset)
+	user_id := user.ID
+	url := r.URL.Query()
+	page, ok := ParsePage(w, url, bidSorts)
+	if !ok {
+		return
 	}
-	rows, err := db.Query(context.Background(), query, args...)
+	bids, total, err := storage.Bids.ListByAuthor(ctx, user_id, page.Lookahead())
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	var bids []Bid
-	for rows.Next() {
-		var bid Bid
-		err := rows.Scan(&bid.ID, &bid.Name, &bid.Description, &bid.Status, &bid.TenderID, &bid.AuthorType, &bid.AuthorID, &bid.OrganizationID, &bid.Version, &bid.Decision, &bid.ApprovedCount, &bid.CreatedAt)
-		if err != nil {
-			log.Println(err.Error())
-			SendErrorResponse(w, ErrorResponse{"Can't scan rows"}, http.StatusInternalServerError)
-			return
-		}
-		bids = append(bids, bid)
-	}
+	bids = WritePage(w, r, page, bids, total)
 	answer, er := json.Marshal(bids)
 	if er != nil {
 		log.Println(er.Error())
@@ -156,84 +167,70 @@ func ShowUsersBidsHandler(w http.ResponseWriter, r *http.Request) {
 
 func ShowTenderBidsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ShowTenderBidsHandler started")
-	var err error
-	username := ""
+	ctx := r.Context()
 	url := r.URL.Query()
 	vars := mux.Vars(r)
-	tenderId := vars["tenderId"]
-	query := "SELECT id, name, description, status, tender_id, author_type, author_id, organization_id, version, decision, approved_count, created_at\nFROM bid\n"
-	args := []interface{}{}
-	if us := url.Get("username"); us != "" {
-		username = us
-		user_id := ""
-		if ui, ok := GetUserId(w, us); ok {
-			user_id = ui
-		} else {
-			return
-		}
-		var organization_id uuid.UUID
-		if oi, ok := GetOrganizationId(w, user_id); ok {
-			organization_id = oi
-		}
-		query += "\nWHERE (status = 'Published' OR organization_id = $1) AND tender_id = $2"
-		args = append(args, organization_id, tenderId)
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
 		return
 	}
-	if !CheckTenderExists(w, tenderId) {
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	username := user.Username
+	var organization_id uuid.UUID
+	if oi, ok := GetOrganizationId(ctx, w, user.ID); ok {
+		organization_id = oi
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
 		return
 	}
 	var tender Tender
-	if tn, ok := GetTenderInfo(w, tenderId); ok {
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
 		tender = tn
 	} else {
 		return
 	}
-	if tender.Status != "Published" {
-		if !CheckOrganizationUser(w, tender.OrganizationID, username) {
-			return
-		}
+	if !CheckTenderVisible(ctx, w, tender, username, PermViewBid) {
+		return
 	}
-	query += "\nORDER BY name ASC"
-	if lim := url.Get("limit"); lim != "" {
-		limit := 5
-		if limit, err = strconv.Atoi(lim); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nLIMIT $" + strconv.Itoa(len(args)+1)
-		args = append(args, limit)
+	page, ok := ParsePage(w, url, bidSorts)
+	if !ok {
+		return
 	}
-	if off := url.Get("offset"); off != "" {
-		offset := 0
-		if offset, err = strconv.Atoi(off); err != nil {
-			log.Println(err.Error())
-			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nOFFSET $" + strconv.Itoa(len(args)+1)
-		args = append(args, offset)
+	filter, ok := ParseBidFilter(w, url)
+	if !ok {
+		return
+	}
+	if !UnsealIfDue(ctx, w, &tender) {
+		return
+	}
+	if tender.BidsSealed() {
+		SendErrorResponse(w, ErrorResponse{"Bids are sealed until the submission deadline"}, http.StatusForbidden)
+		return
 	}
-	rows, err := db.Query(context.Background(), query, args...)
+	bids, total, err := storage.Bids.ListForTender(ctx, tenderId, organization_id, filter, page.Lookahead())
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	var bids []Bid
-	for rows.Next() {
-		var bid Bid
-		err := rows.Scan(&bid.ID, &bid.Name, &bid.Description, &bid.Status, &bid.TenderID, &bid.AuthorType, &bid.AuthorID, &bid.OrganizationID, &bid.Version, &bid.Decision, &bid.ApprovedCount, &bid.CreatedAt)
-		if err != nil {
-			log.Println(err.Error())
-			SendErrorResponse(w, ErrorResponse{"Can't scan rows"}, http.StatusInternalServerError)
-			return
+	if CanSeeReservePrice(ctx, tender, username) {
+		FlagBidsAboveReserve(bids, tender)
+	}
+	bids = WritePage(w, r, page, bids, total)
+	var answer []byte
+	var er error
+	if tender.Mode == ModeAuction {
+		views := make([]AuctionBid, len(bids))
+		for i, bid := range bids {
+			views[i] = MaskAuctionBid(ctx, tender, bid, user)
 		}
-		bids = append(bids, bid)
+		answer, er = json.Marshal(views)
+	} else {
+		answer, er = json.Marshal(bids)
 	}
-	answer, er := json.Marshal(bids)
 	if er != nil {
 		log.Println(er.Error())
 		SendErrorResponse(w, ErrorResponse{"Can't write answer"}, http.StatusInternalServerError)
@@ -246,32 +243,30 @@ func ShowTenderBidsHandler(w http.ResponseWriter, r *http.Request) {
 
 func ShowBidStatusHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ShowBidStatusHandler started")
-	username := ""
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
 	vars := mux.Vars(r)
-	bidId := vars["bidId"]
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	bidId, ok := ParseID(w, vars["bidId"], "bid")
+	if !ok {
 		return
 	}
-	if !CheckBidExists(w, bidId) {
+	if !CheckBidExists(ctx, w, bidId) {
 		return
 	}
 	var bid Bid
-	if bd, ok := GetBidInfo(w, bidId); ok {
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
 		bid = bd
 	} else {
 		return
 	}
-	if bid.Status != "Published" {
-		if !CheckOrganizationUser(w, bid.OrganizationID, username) {
-			return
-		}
+	if !CheckBidVisible(ctx, w, bid, user) {
+		return
+	}
+	if CheckNotModified(w, r, bid.ID, bid.Version) {
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -280,48 +275,22 @@ func ShowBidStatusHandler(w http.ResponseWriter, r *http.Request) {
 
 func EditBidStatusHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("EditBidStatusHandler started")
-	username := ""
-	vars := mux.Vars(r)
-	bidId := vars["bidId"]
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
-		return
-	}
-	if !CheckBidExists(w, bidId) {
-		return
-	}
-	var bid Bid
-	if bd, ok := GetBidInfo(w, bidId); ok {
-		bid = bd
-	} else {
-		return
-	}
-	if !CheckOrganizationUser(w, bid.OrganizationID, username) {
+	ctx := r.Context()
+	bid, user, ok := GetAuthorBid(ctx, w, r)
+	if !ok {
 		return
 	}
-	if !AddBidToVersionsList(w, bid) {
+	url := r.URL.Query()
+	status := url.Get("status")
+	if !hasStatus(bidStatuses, status) {
+		SendErrorResponse(w, ErrorResponse{"Invalid status"}, http.StatusBadRequest)
 		return
 	}
-	status := ""
-	if st := url.Get("status"); st != "" {
-		status = st
-	}
-	query := `UPDATE bid
-			  SET status = $1, version = $2, updated_at = NOW()
-			  WHERE id = $3
-			  RETURNING status, version`
-	err := db.QueryRow(context.Background(), query, status, bid.Version+1, bid.ID).Scan(&bid.Status, &bid.Version)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit bid status"}, http.StatusInternalServerError)
+	bid, ok = ChangeBidStatus(ctx, w, r, bid.ID, status, url.Get("reason"), user.Username)
+	if !ok {
 		return
 	}
+	SetETag(w, bid.ID, bid.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(bid)
@@ -329,33 +298,30 @@ func EditBidStatusHandler(w http.ResponseWriter, r *http.Request) {
 
 func EditBidHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("EditBidHandler started")
-	var err error
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
 		return
 	}
 	vars := mux.Vars(r)
-	bidId := vars["bidId"]
-	if !CheckBidExists(w, bidId) {
+	bidId, ok := ParseID(w, vars["bidId"], "bid")
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
 		return
 	}
 	var bid Bid
-	if bd, ok := GetBidInfo(w, bidId); ok {
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
 		bid = bd
 	} else {
 		return
 	}
-	if !CheckOrganizationUser(w, bid.OrganizationID, username) {
+	if !IsBidAuthor(ctx, bid, user) {
+		SendErrorResponse(w, ErrorResponse{"Only the bid author can edit it"}, http.StatusForbidden)
 		return
 	}
-	if !AddBidToVersionsList(w, bid) {
+	if !CheckBidNotSealed(w, bid) {
 		return
 	}
 	buf := new(bytes.Buffer)
@@ -363,21 +329,57 @@ func EditBidHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
 		return
 	}
-	if err = json.Unmarshal(buf.Bytes(), &bid); err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+	expected, ok := GetExpectedVersion(w, r, buf.Bytes())
+	if !ok {
 		return
 	}
-	query := `UPDATE bid
-			  SET name = $1, description = $2, version = $3, updated_at = NOW()
-			  WHERE id = $4
-			  RETURNING version`
-	err = db.QueryRow(context.Background(), query, bid.Name, bid.Description, bid.Version+1, bid.ID).Scan(&bid.Version)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit bid"}, http.StatusInternalServerError)
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if bd, ok := GetBidInfoForUpdate(ctx, w, bidId); ok {
+			bid = bd
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, bid.ID, bid.Version, expected) {
+			return false
+		}
+		patch := bid
+		if err := json.Unmarshal(buf.Bytes(), &patch); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+			return false
+		}
+		var tender Tender
+		if tn, ok := GetTenderInfo(ctx, w, bid.TenderID); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckSubmissionOpen(w, tender) {
+			return false
+		}
+		if !AddBidToVersionsList(ctx, w, bid) {
+			return false
+		}
+		current := bid
+		bid.Name, bid.Description = patch.Name, patch.Description
+		ApplyPricingPatch(&bid, patch, buf.Bytes())
+		if !CheckBidPricing(w, &bid) || !CheckBidWithinTender(w, tender, bid) || !CheckAuctionPricing(w, tender, bid, current) {
+			return false
+		}
+		bid.ModifiedBy = user.Username
+		bid.Version++
+		err := storage.Bids.Update(ctx, &bid)
+		if err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to edit bid"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
 		return
 	}
+	SetETag(w, bid.ID, bid.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(bid)
@@ -385,42 +387,43 @@ func EditBidHandler(w http.ResponseWriter, r *http.Request) {
 
 func SubmitDecisionHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("SubmitDecisionHandler started")
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
+	url := r.URL.Query()
 	vars := mux.Vars(r)
-	bidId := vars["bidId"]
-	if !CheckBidExists(w, bidId) {
+	bidId, ok := ParseID(w, vars["bidId"], "bid")
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
 		return
 	}
 	var bid Bid
-	if bd, ok := GetBidInfo(w, bidId); ok {
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
 		bid = bd
 	} else {
 		return
 	}
-	if !CheckTenderExists(w, bid.TenderID.String()) {
+	if !CheckTenderExists(ctx, w, bid.TenderID) {
 		return
 	}
 	var tender Tender
-	if tn, ok := GetTenderInfo(w, bid.TenderID.String()); ok {
+	if tn, ok := GetTenderInfo(ctx, w, bid.TenderID); ok {
 		tender = tn
 	} else {
 		return
 	}
-	if !CheckOrganizationUser(w, tender.OrganizationID, username) {
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermDecideBid) {
 		return
 	}
-	if bid.Decision != "None" {
-		SendErrorResponse(w, ErrorResponse{"Decision already made"}, http.StatusBadRequest)
+	if tender.Status == "Cancelled" {
+		SendErrorResponse(w, ErrorResponse{"Tender has been cancelled"}, http.StatusConflict)
+		return
+	}
+	if !CheckBidNotSealed(w, bid) {
 		return
 	}
 	decision := ""
@@ -434,49 +437,96 @@ func SubmitDecisionHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"No decision provided"}, http.StatusNotFound)
 		return
 	}
-	if !AddBidToVersionsList(w, bid) {
+	lot, ok := GetDecisionLot(ctx, w, bid, url.Get("lotId"))
+	if !ok {
 		return
 	}
-	if decision == "Rejected" {
-		bid.ApprovedCount--
-		if !MakeDecision(w, &bid, decision) {
-			return
+	if !CheckDecisionOpen(ctx, w, tender, bid, lot) {
+		return
+	}
+	if !CheckScoringComplete(ctx, w, tender, bid, username) {
+		return
+	}
+	lotId := uuid.Nil
+	if lot != nil {
+		lotId = lot.ID
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, bid.TenderID); ok {
+			tender = tn
+		} else {
+			return false
 		}
-		if !ClosingTender(w, bid.TenderID.String()) {
-			return
+		if tender.Status == "Cancelled" {
+			SendErrorResponse(w, ErrorResponse{"Tender has been cancelled"}, http.StatusConflict)
+			return false
 		}
-	} else {
-		resp := 0
-		if rs, ok := CountResponsibles(w, bid); ok {
-			resp = rs
+		if lot != nil {
+			if lt, ok := GetLotInfo(ctx, w, bid.TenderID, lotId, true); ok {
+				*lot = lt
+			} else {
+				return false
+			}
+			if !CheckLotOpen(w, *lot) {
+				return false
+			}
+		}
+		if bd, ok := GetBidInfoForUpdate(ctx, w, bidId); ok {
+			bid = bd
 		} else {
-			return
+			return false
 		}
-		if !CheckUserApproveExists(w, bid, username) {
-			return
+		if !CheckDecisionOpen(ctx, w, tender, bid, lot) {
+			return false
+		}
+		if !AddBidToVersionsList(ctx, w, bid) {
+			return false
+		}
+		if !CheckUserVoteExists(ctx, w, bid, lotId, username) {
+			return false
 		}
-		id := ""
-		query := `INSERT INTO bid_approve (bid_id, username)
-				  VALUES ($1, $2)
-				  RETURNING id`
-		err := db.QueryRow(context.Background(), query, bid.ID, username).Scan(&id)
+		err := storage.Bids.AddVote(ctx, &ApprovalVote{BidID: bid.ID, LotID: lotId, Username: username, Decision: decision})
 		if err != nil {
 			log.Println(err.Error())
 			SendErrorResponse(w, ErrorResponse{"Failed to submit approvement"}, http.StatusInternalServerError)
-			return
+			return false
 		}
-		if bid.ApprovedCount+1 >= min(3, resp) {
-			if !MakeDecision(w, &bid, decision) {
-				return
+		var state ApprovalState
+		if st, ok := EvaluateApproval(ctx, w, tender, bid, lotId); ok {
+			state = st
+		} else {
+			return false
+		}
+		switch state.Status {
+		case "Rejected":
+			if lot != nil {
+				if !DecideLot(ctx, w, tender, &bid, decision, username) {
+					return false
+				}
+			} else {
+				bid.ApprovedCount--
+				if !MakeDecision(ctx, w, &bid, "Rejected", username) {
+					return false
+				}
 			}
-			if !ClosingTender(w, bid.TenderID.String()) {
-				return
+			return true
+		case "Approved":
+			if lot != nil {
+				if !AwardLot(ctx, w, lot, bid) || !DecideLot(ctx, w, tender, &bid, decision, username) {
+					return false
+				}
+			} else if !MakeDecision(ctx, w, &bid, "Approved", username) {
+				return false
 			}
-		} else {
-			if !AddApproveBid(w, &bid) {
-				return
+			if !CreateContract(ctx, w, tender, bid, lot) {
+				return false
 			}
+			return ClosingTender(ctx, w, bid.TenderID, username)
 		}
+		return RecordVote(ctx, w, &bid, decision, username)
+	})
+	if !ok {
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -485,60 +535,90 @@ func SubmitDecisionHandler(w http.ResponseWriter, r *http.Request) {
 
 func BidRollbackHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("BidRollbackHandler started")
-	var err error
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
 		return
 	}
 	vars := mux.Vars(r)
-	bidId := vars["bidId"]
-	vers := vars["version"]
-	if !CheckBidExists(w, bidId) {
+	bidId, ok := ParseID(w, vars["bidId"], "bid")
+	if !ok {
+		return
+	}
+	vers, ok := ParseVersion(w, vars["version"])
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
 		return
 	}
 	var bid Bid
-	if tn, ok := GetBidInfo(w, bidId); ok {
+	if tn, ok := GetBidInfo(ctx, w, bidId); ok {
 		bid = tn
 	} else {
 		return
 	}
-	if !CheckOrganizationUser(w, bid.OrganizationID, username) {
+	if !IsBidAuthor(ctx, bid, user) {
+		SendErrorResponse(w, ErrorResponse{"Only the bid author can roll it back"}, http.StatusForbidden)
 		return
 	}
-	if !CheckBidVersionExists(w, bidId, vers) {
+	if !CheckBidNotSealed(w, bid) {
 		return
 	}
-	if !AddBidToVersionsList(w, bid) {
+	if !CheckBidVersionExists(ctx, w, bidId, vers) {
 		return
 	}
-	new_vers := bid.Version + 1
-	if tn, ok := GetBidVersionInfo(w, bidId, vers); ok {
-		bid = tn
-	} else {
+	expected, ok := GetExpectedVersion(w, r, nil)
+	if !ok {
 		return
 	}
-	query := `UPDATE bid
-			  SET name = $1, description = $2, version = $3, status = $4, updated_at = NOW()
-			  WHERE id = $5
-			  RETURNING version`
-	err = db.QueryRow(context.Background(), query, bid.Name, bid.Description, new_vers, bid.Status, bidId).Scan(&bid.Version)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit bid"}, http.StatusInternalServerError)
-		return
-	}
-	if tn, ok := GetBidInfo(w, bidId); ok {
-		bid = tn
-	} else {
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if bd, ok := GetBidInfoForUpdate(ctx, w, bidId); ok {
+			bid = bd
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, bid.ID, bid.Version, expected) {
+			return false
+		}
+		var tender Tender
+		if tn, ok := GetTenderInfo(ctx, w, bid.TenderID); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckSubmissionOpen(w, tender) {
+			return false
+		}
+		if !AddBidToVersionsList(ctx, w, bid) {
+			return false
+		}
+		var old Bid
+		if tn, ok := GetBidVersionInfo(ctx, w, bidId, vers); ok {
+			old = tn
+		} else {
+			return false
+		}
+		current := bid
+		bid.Name, bid.Description = old.Name, old.Description
+		bid.Amount, bid.Currency, bid.LineItems = old.Amount, old.Currency, old.LineItems
+		if !CheckBidWithinTender(w, tender, bid) || !CheckAuctionPricing(w, tender, bid, current) {
+			return false
+		}
+		bid.ModifiedBy = user.Username
+		bid.Version++
+		err := storage.Bids.Update(ctx, &bid)
+		if err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to edit bid"}, http.StatusInternalServerError)
+			return false
+		}
+		return RestoreAttachments(ctx, w, AttachmentBid, bid.ID, vers, bid.Version)
+	})
+	if !ok {
 		return
 	}
+	SetETag(w, bid.ID, bid.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(bid)
@@ -546,29 +626,30 @@ func BidRollbackHandler(w http.ResponseWriter, r *http.Request) {
 
 func BidReviewHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("BidReviewHandler started")
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
+	url := r.URL.Query()
 	vars := mux.Vars(r)
-	bidId := vars["bidId"]
-	if !CheckBidExists(w, bidId) {
+	bidId, ok := ParseID(w, vars["bidId"], "bid")
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
 		return
 	}
 	var bid Bid
-	if bd, ok := GetBidInfo(w, bidId); ok {
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
 		bid = bd
 	} else {
 		return
 	}
-	if !CheckOrganizationUser(w, bid.OrganizationID, username) {
+	if !Authorize(ctx, w, bid.OrganizationID, username, PermReviewBid) {
+		return
+	}
+	if !CheckBidNotSealed(w, bid) {
 		return
 	}
 	review := ""
@@ -578,11 +659,7 @@ func BidReviewHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"No review provided"}, http.StatusBadRequest)
 		return
 	}
-	id := ""
-	query := `INSERT INTO bid_review (bid_id, username, review)
-              VALUES ($1, $2, $3)
-              RETURNING id`
-	err := db.QueryRow(context.Background(), query, bidId, username, review).Scan(&id)
+	_, err := storage.Reviews.Create(ctx, bidId, username, review)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to create review"}, http.StatusInternalServerError)
@@ -595,16 +672,19 @@ func BidReviewHandler(w http.ResponseWriter, r *http.Request) {
 
 func ShowBidReviewsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ShowBidReviewsHandler started")
-	var err error
+	ctx := r.Context()
 	vars := mux.Vars(r)
-	tenderId := vars["tenderId"]
-	author_id := ""
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	var author_id uuid.UUID
 	url := r.URL.Query()
 	if us := url.Get("authorUsername"); us != "" {
-		if !CheckUsernameExists(w, us) {
+		if !CheckUsernameExists(ctx, w, us) {
 			return
 		}
-		if ai, ok := GetUserId(w, us); ok {
+		if ai, ok := GetUserId(ctx, w, us); ok {
 			author_id = ai
 		} else {
 			return
@@ -613,72 +693,34 @@ func ShowBidReviewsHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"No author provided"}, http.StatusUnauthorized)
 		return
 	}
-	requestor_username := ""
-	if us := url.Get("requesterUsername"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		requestor_username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No requestor provided"}, http.StatusUnauthorized)
+	requestor_username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
-	if !CheckTenderExists(w, tenderId) {
+	if !CheckTenderExists(ctx, w, tenderId) {
 		return
 	}
 	var tender Tender
-	if tn, ok := GetTenderInfo(w, tenderId); ok {
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
 		tender = tn
 	} else {
 		return
 	}
-	if !CheckOrganizationUser(w, tender.OrganizationID, requestor_username) {
+	if !Authorize(ctx, w, tender.OrganizationID, requestor_username, PermViewBid) {
 		return
 	}
-	args := []interface{}{}
-	args = append(args, author_id, tenderId)
-	query := `SELECT br.id, br.review, br.created_at 
-			  FROM bid b
-			  JOIN bid_review br 
-			  ON b.id = br.bid_id
-			  WHERE author_id = $1 and author_type = 'User' and tender_id != $2`
-	if lim := url.Get("limit"); lim != "" {
-		limit := 5
-		if limit, err = strconv.Atoi(lim); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nLIMIT $" + strconv.Itoa(len(args)+1)
-		args = append(args, limit)
-	}
-	if off := url.Get("offset"); off != "" {
-		offset := 0
-		if offset, err = strconv.Atoi(off); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nOFFSET $" + strconv.Itoa(len(args)+1)
-		args = append(args, offset)
+	page, ok := ParsePage(w, url, reviewSorts)
+	if !ok {
+		return
 	}
-	rows, err := db.Query(context.Background(), query, args...)
+	reviews, total, err := storage.Reviews.ListByAuthor(ctx, author_id, tenderId, page.Lookahead())
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find reviews"}, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	var bids []BidReview
-	for rows.Next() {
-		var br BidReview
-		err := rows.Scan(&br.ID, &br.Description, &br.CreatedAt)
-		if err != nil {
-			log.Println(err.Error())
-			SendErrorResponse(w, ErrorResponse{"Can't scan rows"}, http.StatusInternalServerError)
-			return
-		}
-		bids = append(bids, br)
-	}
-	answer, er := json.Marshal(bids)
+	reviews = WritePage(w, r, page, reviews, total)
+	answer, er := json.Marshal(reviews)
 	if er != nil {
 		log.Println(er.Error())
 		SendErrorResponse(w, ErrorResponse{"Can't write answer"}, http.StatusInternalServerError)
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCreateBidRequiresCredentials(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	body := map[string]interface{}{"name": "b", "description": "d", "tenderId": tender.ID, "authorType": "User", "authorId": testBob}
+	env.expect("POST", "/api/bids/new", "", body, http.StatusUnauthorized, nil)
+	env.expect("POST", "/api/bids/new", "unknown-key", body, http.StatusUnauthorized, nil)
+	env.expect("POST", "/api/bids/new", keyCarol, body, http.StatusForbidden, nil)
+}
+
+func TestBidEditAndRollbackOnlyByAuthor(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.createBid(tender, nil)
+	edit := "/api/bids/" + bid.ID.String() + "/edit"
+	rollback := "/api/bids/" + bid.ID.String() + "/rollback/1"
+
+	env.expect("PATCH", edit, keyAlice, map[string]interface{}{"name": "hijacked"}, http.StatusForbidden, nil)
+	env.expect("PATCH", edit, keyCarol, map[string]interface{}{"name": "hijacked"}, http.StatusForbidden, nil)
+	var edited Bid
+	env.expect("PATCH", edit, keyBob, map[string]interface{}{"name": "renamed"}, http.StatusOK, &edited)
+	if edited.Name != "renamed" || edited.Description != "test bid" || edited.Version != 2 {
+		t.Fatalf("got %+v", edited)
+	}
+	env.expect("PUT", rollback, keyAlice, nil, http.StatusForbidden, nil)
+	var restored Bid
+	env.expect("PUT", rollback, keyBob, nil, http.StatusOK, &restored)
+	if restored.Name != "bid" || restored.Version != 3 {
+		t.Fatalf("got %+v", restored)
+	}
+}
+
+func TestBidEditVersionConflict(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.createBid(tender, nil)
+	edit := "/api/bids/" + bid.ID.String() + "/edit"
+	env.expect("PATCH", edit, keyBob, map[string]interface{}{"name": "first", "expectedVersion": 1}, http.StatusOK, nil)
+	env.expect("PATCH", edit, keyBob, map[string]interface{}{"name": "stale", "expectedVersion": 1}, http.StatusConflict, nil)
+	if got := env.storedBid(bid.ID); got.Name != "first" {
+		t.Fatalf("stale edit was applied: %s", got.Name)
+	}
+}
+
+func TestDraftBidVisibleToAuthorAndTenderOrganization(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.createBid(tender, nil)
+	status := "/api/bids/" + bid.ID.String() + "/status"
+
+	if w := env.expect("GET", status, keyBob, nil, http.StatusOK, nil); w.Body.String() != BidDraft {
+		t.Fatalf("got status %s", w.Body.String())
+	}
+	env.expect("GET", status, keyAlice, nil, http.StatusOK, nil)
+	env.expect("GET", status, keyCarol, nil, http.StatusForbidden, nil)
+	env.expect("GET", "/api/bids/"+bid.ID.String()+"/versions", keyBob, nil, http.StatusOK, nil)
+}
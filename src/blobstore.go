@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+type LocalBlobStore struct {
+	Dir string
+}
+
+var blobs BlobStore
+
+var (
+	attachmentMaxBytes  int64 = 10 << 20
+	attachmentMimeTypes       = []string{
+		"application/pdf",
+		"application/zip",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"image/png",
+		"image/jpeg",
+		"text/plain",
+	}
+)
+
+func initBlobStore() error {
+	if n, ok, err := envInt("ATTACHMENT_MAX_BYTES"); err != nil {
+		return err
+	} else if ok {
+		attachmentMaxBytes = int64(n)
+	}
+	if types := os.Getenv("ATTACHMENT_MIME_TYPES"); types != "" {
+		attachmentMimeTypes = strings.Split(types, ",")
+	}
+	switch backend := os.Getenv("BLOB_STORE"); backend {
+	case "", "local":
+		dir := os.Getenv("BLOB_DIR")
+		if dir == "" {
+			dir = "attachments"
+		}
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return fmt.Errorf("failed to create blob directory: %w", err)
+		}
+		blobs = LocalBlobStore{dir}
+		log.Printf("Attachments are stored in %s\n", dir)
+	default:
+		return fmt.Errorf("unknown BLOB_STORE %q", backend)
+	}
+	return nil
+}
+
+func (s LocalBlobStore) path(key string) string {
+	return filepath.Join(s.Dir, key[:2], key)
+}
+
+func (s LocalBlobStore) Put(ctx context.Context, key string, r io.Reader) error {
+	path := s.path(key)
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s LocalBlobStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type TenderCancellation struct {
+	Reason string `json:"reason"`
+}
+
+func ChangeTenderStatus(ctx context.Context, w http.ResponseWriter, r *http.Request, tenderId uuid.UUID, status, cause, username string) (Tender, bool) {
+	var tender Tender
+	if status == "Cancelled" {
+		cause = strings.TrimSpace(cause)
+		if cause == "" {
+			SendErrorResponse(w, ErrorResponse{"Cancellation reason is required"}, http.StatusBadRequest)
+			return tender, false
+		}
+	}
+	expected, ok := GetExpectedVersion(w, r, nil)
+	if !ok {
+		return tender, false
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, tender.ID, tender.Version, expected) {
+			return false
+		}
+		if err := ApplyTenderTransition(ctx, &tender, status, username, cause); err != nil {
+			SendTransitionError(w, err, "Failed to edit tender status")
+			return false
+		}
+		return true
+	})
+	return tender, ok
+}
+
+func cancelTenderBids(ctx context.Context, tender Tender, username, reason string) error {
+	bids, _, err := storage.Bids.ListForTender(ctx, tender.ID, tender.OrganizationID, BidFilter{}, Page{})
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Tender %q has been cancelled: %s", tender.Name, reason)
+	for _, bid := range bids {
+		if bid.Decision == "None" && bid.Status != BidCanceled {
+			if err := storage.Bids.AddVersion(ctx, bid); err != nil {
+				return err
+			}
+			bid.Status = BidCanceled
+			bid.ModifiedBy = username
+			bid.Version++
+			if err := storage.Bids.Update(ctx, &bid); err != nil {
+				return err
+			}
+		}
+		if err := NotifyBidAuthor(ctx, bid, EventTenderCancelled, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func CancelTenderHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("CancelTenderHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	tenderId, ok := ParseID(w, mux.Vars(r)["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermChangeTenderStatus) {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var cancellation TenderCancellation
+	if err := json.Unmarshal(buf.Bytes(), &cancellation); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	tender, ok = ChangeTenderStatus(ctx, w, r, tenderId, "Cancelled", cancellation.Reason, username)
+	if !ok {
+		return
+	}
+	SetETag(w, tender.ID, tender.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tender)
+}
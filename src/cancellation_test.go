@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTenderCancelRequiresReason(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.submitBid(tender, nil)
+	path := "/api/tenders/" + tender.ID.String() + "/cancel"
+	env.expect("PUT", path, keyAlice, map[string]interface{}{"reason": ""}, http.StatusBadRequest, nil)
+	env.expect("PUT", path, keyDave, map[string]interface{}{"reason": "budget cut"}, http.StatusForbidden, nil)
+	var cancelled Tender
+	env.expect("PUT", path, keyAlice, map[string]interface{}{"reason": "budget cut"}, http.StatusOK, &cancelled)
+	if cancelled.Status != "Cancelled" {
+		t.Fatalf("got status %s", cancelled.Status)
+	}
+	if got := env.storedBid(bid.ID); got.Status != BidCanceled {
+		t.Fatalf("bid is %s after tender cancellation", got.Status)
+	}
+	env.expect("PUT", path, keyAlice, map[string]interface{}{"reason": "again"}, http.StatusConflict, nil)
+}
+
+func TestTenderCancelNotifiesBidAuthors(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.submitBid(tender, nil)
+	env.expect("PUT", "/api/tenders/"+tender.ID.String()+"/cancel", keyAlice, map[string]interface{}{"reason": "budget cut"}, http.StatusOK, nil)
+	var notifications []Notification
+	env.expect("GET", "/api/notifications/my", keyBob, nil, http.StatusOK, &notifications)
+	if len(notifications) != 1 || notifications[0].BidID == nil || *notifications[0].BidID != bid.ID {
+		t.Fatalf("got notifications %+v", notifications)
+	}
+	env.expect("GET", "/api/notifications/my", keyCarol, nil, http.StatusOK, &notifications)
+	if len(notifications) != 0 {
+		t.Fatalf("notification leaked to another user: %+v", notifications)
+	}
+}
@@ -3,12 +3,19 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+var errTxAborted = errors.New("transaction aborted")
+
 type ErrorResponse struct {
 	Reason string `json:"reason"`
 }
@@ -19,34 +26,107 @@ func SendErrorResponse(w http.ResponseWriter, text ErrorResponse, err int) {
 	json.NewEncoder(w).Encode(text)
 }
 
-func CheckOrganizationUser(w http.ResponseWriter, org uuid.UUID, username string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM organization_responsible ore
-			  JOIN employee e ON ore.user_id = e.id
-			  WHERE ore.organization_id = $1
-			  AND e.username = $2);`
-	err := db.QueryRow(context.Background(), query, org, username).Scan(&exists)
+func ParseID(w http.ResponseWriter, value, entity string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(value)
 	if err != nil {
+		SendErrorResponse(w, ErrorResponse{"Invalid " + entity + " id"}, http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func ParseVersion(w http.ResponseWriter, value string) (int, bool) {
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		SendErrorResponse(w, ErrorResponse{"Invalid version"}, http.StatusBadRequest)
+		return version, false
+	}
+	return version, true
+}
+
+func ETag(id uuid.UUID, version int) string {
+	return fmt.Sprintf("\"%s.%d\"", id, version)
+}
+
+func SetETag(w http.ResponseWriter, id uuid.UUID, version int) {
+	w.Header().Set("ETag", ETag(id, version))
+}
+
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func CheckNotModified(w http.ResponseWriter, r *http.Request, id uuid.UUID, version int) bool {
+	SetETag(w, id, version)
+	if header := r.Header.Get("If-None-Match"); header != "" && matchesETag(header, ETag(id, version)) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
+
+func GetExpectedVersion(w http.ResponseWriter, r *http.Request, body []byte) (*int, bool) {
+	if ev := r.URL.Query().Get("expectedVersion"); ev != "" {
+		version, err := strconv.Atoi(ev)
+		if err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid expectedVersion parameter"}, http.StatusBadRequest)
+			return nil, false
+		}
+		return &version, true
+	}
+	if len(body) == 0 {
+		return nil, true
+	}
+	var precondition struct {
+		ExpectedVersion *int `json:"expectedVersion"`
+	}
+	if err := json.Unmarshal(body, &precondition); err != nil {
 		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to authorize"}, http.StatusInternalServerError)
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return nil, false
+	}
+	return precondition.ExpectedVersion, true
+}
+
+func CheckVersionPrecondition(w http.ResponseWriter, r *http.Request, id uuid.UUID, version int, expected *int) bool {
+	if header := r.Header.Get("If-Match"); header != "" && !matchesETag(header, ETag(id, version)) {
+		SetETag(w, id, version)
+		SendErrorResponse(w, ErrorResponse{"Version precondition failed"}, http.StatusPreconditionFailed)
 		return false
 	}
-	if !exists {
-		SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
+	if expected != nil && *expected != version {
+		SetETag(w, id, version)
+		SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Version conflict: current version is %d", version)}, http.StatusConflict)
 		return false
 	}
 	return true
 }
 
-func CheckUsernameExists(w http.ResponseWriter, username string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM employee
-			  WHERE username = $1);`
-	err := db.QueryRow(context.Background(), query, username).Scan(&exists)
+func RunInTx(ctx context.Context, w http.ResponseWriter, fn func(ctx context.Context) bool) bool {
+	err := storage.Tx.WithTx(ctx, func(ctx context.Context) error {
+		if !fn(ctx) {
+			return errTxAborted
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errTxAborted) {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to save changes"}, http.StatusInternalServerError)
+		}
+		return false
+	}
+	return true
+}
+
+func CheckUsernameExists(ctx context.Context, w http.ResponseWriter, username string) bool {
+	exists, err := storage.Organizations.UserExists(ctx, username)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find user"}, http.StatusInternalServerError)
@@ -59,13 +139,8 @@ func CheckUsernameExists(w http.ResponseWriter, username string) bool {
 	return true
 }
 
-func CheckTenderExists(w http.ResponseWriter, tenderId string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM tender
-			  WHERE id = $1);`
-	err := db.QueryRow(context.Background(), query, tenderId).Scan(&exists)
+func CheckTenderExists(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID) bool {
+	exists, err := storage.Tenders.Exists(ctx, tenderId)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find tender"}, http.StatusInternalServerError)
@@ -78,12 +153,8 @@ func CheckTenderExists(w http.ResponseWriter, tenderId string) bool {
 	return true
 }
 
-func AddTenderToVersionsList(w http.ResponseWriter, tender Tender) bool {
-	var id uuid.UUID
-	query := `INSERT INTO tender_version (tender_id, version, name, description, service_type, status)
-              VALUES ($1, $2, $3, $4, $5, $6)
-              RETURNING id`
-	err := db.QueryRow(context.Background(), query, tender.ID, tender.Version, tender.Name, tender.Description, tender.ServiceType, tender.Status).Scan(&id)
+func AddTenderToVersionsList(ctx context.Context, w http.ResponseWriter, tender Tender) bool {
+	err := storage.Tenders.AddVersion(ctx, tender)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to create reserve copy of tender"}, http.StatusInternalServerError)
@@ -92,12 +163,18 @@ func AddTenderToVersionsList(w http.ResponseWriter, tender Tender) bool {
 	return true
 }
 
-func GetTenderInfo(w http.ResponseWriter, tenderId string) (Tender, bool) {
-	var tender Tender
-	query := `SELECT id, name, description, service_type, status, organization_id, creator_username, version, created_at
-			  FROM tender t
-			  WHERE t.id = $1`
-	err := db.QueryRow(context.Background(), query, tenderId).Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.CreatorUsername, &tender.Version, &tender.CreatedAt)
+func GetTenderInfo(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID) (Tender, bool) {
+	tender, err := storage.Tenders.Get(ctx, tenderId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find tender"}, http.StatusInternalServerError)
+		return tender, false
+	}
+	return tender, true
+}
+
+func GetTenderInfoForUpdate(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID) (Tender, bool) {
+	tender, err := storage.Tenders.GetForUpdate(ctx, tenderId)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find tender"}, http.StatusInternalServerError)
@@ -106,13 +183,8 @@ func GetTenderInfo(w http.ResponseWriter, tenderId string) (Tender, bool) {
 	return tender, true
 }
 
-func CheckTenderVersionExists(w http.ResponseWriter, tenderId, vers string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM tender_version
-			  WHERE tender_id = $1 AND version = $2);`
-	err := db.QueryRow(context.Background(), query, tenderId, vers).Scan(&exists)
+func CheckTenderVersionExists(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID, vers int) bool {
+	exists, err := storage.Tenders.VersionExists(ctx, tenderId, vers)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find tender version"}, http.StatusInternalServerError)
@@ -125,12 +197,8 @@ func CheckTenderVersionExists(w http.ResponseWriter, tenderId, vers string) bool
 	return true
 }
 
-func GetTenderVersionInfo(w http.ResponseWriter, tenderId, vers string) (Tender, bool) {
-	var tender Tender
-	query := `SELECT name, description, service_type, status
-			  FROM tender_version t
-			  WHERE t.tender_id = $1 AND t.version = $2`
-	err := db.QueryRow(context.Background(), query, tenderId, vers).Scan(&tender.Name, &tender.Description, &tender.ServiceType, &tender.Status)
+func GetTenderVersionInfo(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID, vers int) (Tender, bool) {
+	tender, err := storage.Tenders.GetVersion(ctx, tenderId, vers)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find tender version info"}, http.StatusInternalServerError)
@@ -139,13 +207,8 @@ func GetTenderVersionInfo(w http.ResponseWriter, tenderId, vers string) (Tender,
 	return tender, true
 }
 
-func CheckOrganizationExists(w http.ResponseWriter, id string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM organization
-			  WHERE id = $1);`
-	err := db.QueryRow(context.Background(), query, id).Scan(&exists)
+func CheckOrganizationExists(ctx context.Context, w http.ResponseWriter, id uuid.UUID) bool {
+	exists, err := storage.Organizations.Exists(ctx, id)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find organization"}, http.StatusInternalServerError)
@@ -158,12 +221,8 @@ func CheckOrganizationExists(w http.ResponseWriter, id string) bool {
 	return true
 }
 
-func GetUserId(w http.ResponseWriter, username string) (string, bool) {
-	user_id := ""
-	query := `SELECT id
-			  FROM employee e
-			  WHERE e.username = $1`
-	err := db.QueryRow(context.Background(), query, username).Scan(&user_id)
+func GetUserId(ctx context.Context, w http.ResponseWriter, username string) (uuid.UUID, bool) {
+	user_id, err := storage.Organizations.GetUserID(ctx, username)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find user"}, http.StatusInternalServerError)
@@ -172,12 +231,18 @@ func GetUserId(w http.ResponseWriter, username string) (string, bool) {
 	return user_id, true
 }
 
-func GetOrganizationId(w http.ResponseWriter, author_id string) (uuid.UUID, bool) {
-	var organization_id uuid.UUID
-	query := `SELECT organization_id
-			  FROM organization_responsible
-			  WHERE user_id = $1`
-	err := db.QueryRow(context.Background(), query, author_id).Scan(&organization_id)
+func GetUsername(ctx context.Context, w http.ResponseWriter, user_id uuid.UUID) (string, bool) {
+	username, err := storage.Organizations.GetUsername(ctx, user_id)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find user"}, http.StatusInternalServerError)
+		return username, false
+	}
+	return username, true
+}
+
+func GetOrganizationId(ctx context.Context, w http.ResponseWriter, author_id uuid.UUID) (uuid.UUID, bool) {
+	organization_id, err := storage.Organizations.GetUserOrganization(ctx, author_id)
 	if err != nil {
 		log.Println(err.Error())
 		log.Println("Failed to find organization")
@@ -186,13 +251,8 @@ func GetOrganizationId(w http.ResponseWriter, author_id string) (uuid.UUID, bool
 	return organization_id, true
 }
 
-func CheckBidExists(w http.ResponseWriter, bidId string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM bid
-			  WHERE id = $1);`
-	err := db.QueryRow(context.Background(), query, bidId).Scan(&exists)
+func CheckBidExists(ctx context.Context, w http.ResponseWriter, bidId uuid.UUID) bool {
+	exists, err := storage.Bids.Exists(ctx, bidId)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find bid"}, http.StatusInternalServerError)
@@ -205,12 +265,8 @@ func CheckBidExists(w http.ResponseWriter, bidId string) bool {
 	return true
 }
 
-func GetBidInfo(w http.ResponseWriter, bidId string) (Bid, bool) {
-	var bid Bid
-	query := `SELECT id, name, description, status, tender_id, author_type, author_id, organization_id, version, decision, approved_count, created_at
-			  FROM bid b
-			  WHERE b.id = $1`
-	err := db.QueryRow(context.Background(), query, bidId).Scan(&bid.ID, &bid.Name, &bid.Description, &bid.Status, &bid.TenderID, &bid.AuthorType, &bid.AuthorID, &bid.OrganizationID, &bid.Version, &bid.Decision, &bid.ApprovedCount, &bid.CreatedAt)
+func GetBidInfo(ctx context.Context, w http.ResponseWriter, bidId uuid.UUID) (Bid, bool) {
+	bid, err := storage.Bids.Get(ctx, bidId)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find bid"}, http.StatusInternalServerError)
@@ -219,12 +275,18 @@ func GetBidInfo(w http.ResponseWriter, bidId string) (Bid, bool) {
 	return bid, true
 }
 
-func AddBidToVersionsList(w http.ResponseWriter, bid Bid) bool {
-	var id uuid.UUID
-	query := `INSERT INTO bid_version (bid_id, version, name, description, decision, approved_count, status)
-              VALUES ($1, $2, $3, $4, $5, $6, $7)
-              RETURNING id`
-	err := db.QueryRow(context.Background(), query, bid.ID, bid.Version, bid.Name, bid.Description, bid.Decision, bid.ApprovedCount, bid.Status).Scan(&id)
+func GetBidInfoForUpdate(ctx context.Context, w http.ResponseWriter, bidId uuid.UUID) (Bid, bool) {
+	bid, err := storage.Bids.GetForUpdate(ctx, bidId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bid"}, http.StatusInternalServerError)
+		return bid, false
+	}
+	return bid, true
+}
+
+func AddBidToVersionsList(ctx context.Context, w http.ResponseWriter, bid Bid) bool {
+	err := storage.Bids.AddVersion(ctx, bid)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to create reserve copy of bid"}, http.StatusInternalServerError)
@@ -233,32 +295,35 @@ func AddBidToVersionsList(w http.ResponseWriter, bid Bid) bool {
 	return true
 }
 
-func ClosingTender(w http.ResponseWriter, tenderId string) bool {
+func ClosingTender(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID, username string) bool {
+	if resolved, ok := LotsResolved(ctx, w, tenderId); !ok {
+		return false
+	} else if !resolved {
+		return true
+	}
 	var tender Tender
-	if tn, ok := GetTenderInfo(w, tenderId); ok {
+	if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
 		tender = tn
 	} else {
 		return false
 	}
-	query := `UPDATE tender
-			  SET status = 'Closed', version = $1, updated_at = NOW()
-			  WHERE id = $2
-			  RETURNING status, version`
-	err := db.QueryRow(context.Background(), query, tender.Version+1, tender.ID).Scan(&tender.Status, &tender.Version)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit tender status"}, http.StatusInternalServerError)
+	if tender.Status == "Closed" {
+		return true
+	}
+	if err := ApplyTenderTransition(ctx, &tender, "Closed", username, "closed"); err != nil {
+		SendTransitionError(w, err, "Failed to edit tender status")
 		return false
 	}
 	return true
 }
 
-func MakeDecision(w http.ResponseWriter, bid *Bid, decision string) bool {
-	query := `UPDATE bid
-			  SET decision = $1, approved_count = $2, status = 'Canceled', version = $3, updated_at = NOW()
-			  WHERE id = $4
-			  RETURNING decision, approved_count, version`
-	err := db.QueryRow(context.Background(), query, decision, bid.ApprovedCount+1, bid.Version+1, bid.ID).Scan(&bid.Decision, &bid.ApprovedCount, &bid.Version)
+func MakeDecision(ctx context.Context, w http.ResponseWriter, bid *Bid, decision, username string) bool {
+	bid.Decision = decision
+	bid.ApprovedCount++
+	bid.Status = "Canceled"
+	bid.ModifiedBy = username
+	bid.Version++
+	err := storage.Bids.Update(ctx, bid)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to edit bid decision"}, http.StatusInternalServerError)
@@ -267,82 +332,61 @@ func MakeDecision(w http.ResponseWriter, bid *Bid, decision string) bool {
 	return true
 }
 
-func CheckUserApproveExists(w http.ResponseWriter, bid Bid, username string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM bid_approve
-			  WHERE bid_id = $1 AND username = $2);`
-	err := db.QueryRow(context.Background(), query, bid.ID, username).Scan(&exists)
+func CheckBidVersionExists(ctx context.Context, w http.ResponseWriter, bidId uuid.UUID, vers int) bool {
+	exists, err := storage.Bids.VersionExists(ctx, bidId, vers)
 	if err != nil {
 		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to find organization"}, http.StatusInternalServerError)
+		SendErrorResponse(w, ErrorResponse{"Failed to find bid version"}, http.StatusInternalServerError)
 		return false
 	}
-	if exists {
-		SendErrorResponse(w, ErrorResponse{"Bid already approved by user"}, http.StatusNotFound)
+	if !exists {
+		SendErrorResponse(w, ErrorResponse{"No such bid"}, http.StatusNotFound)
 		return false
 	}
 	return true
 }
 
-func AddApproveBid(w http.ResponseWriter, bid *Bid) bool {
-	query := `UPDATE bid
-			  SET approved_count = $1, version = $2, updated_at = NOW()
-			  WHERE id = $3
-			  RETURNING approved_count, version`
-	err := db.QueryRow(context.Background(), query, bid.ApprovedCount+1, bid.Version+1, bid.ID).Scan(&bid.ApprovedCount, &bid.Version)
+func GetBidVersionInfo(ctx context.Context, w http.ResponseWriter, bidId uuid.UUID, vers int) (Bid, bool) {
+	bid, err := storage.Bids.GetVersion(ctx, bidId, vers)
 	if err != nil {
 		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit bid decision"}, http.StatusInternalServerError)
-		return false
+		SendErrorResponse(w, ErrorResponse{"Failed to find bid version info"}, http.StatusInternalServerError)
+		return bid, false
 	}
-	return true
+	return bid, true
 }
 
-func CountResponsibles(w http.ResponseWriter, bid Bid) (int, bool) {
-	resp := 0
-	query := `SELECT count(user_id)
-			  FROM organization_responsible or2 
-			  WHERE organization_id = $1`
-	err := db.QueryRow(context.Background(), query, bid.OrganizationID).Scan(&resp)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to count responsible"}, http.StatusInternalServerError)
-		return resp, false
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
-	return resp, true
+	return a.Equal(*b)
 }
 
-func CheckBidVersionExists(w http.ResponseWriter, bidId, vers string) bool {
-	var exists bool
-	query := `SELECT EXISTS (
-			  SELECT 1
-			  FROM bid_version
-			  WHERE bid_id = $1 AND version = $2);`
-	err := db.QueryRow(context.Background(), query, bidId, vers).Scan(&exists)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to find bid version"}, http.StatusInternalServerError)
+func CheckTenderSchedule(w http.ResponseWriter, tender Tender, current Tender) bool {
+	if tender.SubmissionDeadline != nil && tender.PublishAt != nil && !tender.PublishAt.Before(*tender.SubmissionDeadline) {
+		SendErrorResponse(w, ErrorResponse{"publishAt must be before submissionDeadline"}, http.StatusBadRequest)
 		return false
 	}
-	if !exists {
-		SendErrorResponse(w, ErrorResponse{"No such bid"}, http.StatusNotFound)
+	if tender.SubmissionDeadline != nil && !sameTime(tender.SubmissionDeadline, current.SubmissionDeadline) && !tender.SubmissionDeadline.After(time.Now()) {
+		SendErrorResponse(w, ErrorResponse{"submissionDeadline must be in the future"}, http.StatusBadRequest)
 		return false
 	}
 	return true
 }
 
-func GetBidVersionInfo(w http.ResponseWriter, bidId, vers string) (Bid, bool) {
-	var bid Bid
-	query := `SELECT name, description, status
-			  FROM bid_version 
-			  WHERE bid_id = $1 AND version = $2`
-	err := db.QueryRow(context.Background(), query, bidId, vers).Scan(&bid.Name, &bid.Description, &bid.Status)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to find bid version info"}, http.StatusInternalServerError)
-		return bid, false
+func CheckSubmissionOpen(w http.ResponseWriter, tender Tender) bool {
+	if tender.Status == "Cancelled" {
+		SendErrorResponse(w, ErrorResponse{"Tender has been cancelled"}, http.StatusForbidden)
+		return false
 	}
-	return bid, true
+	if tender.SubmissionDeadline != nil && !tender.SubmissionDeadline.After(time.Now()) {
+		SendErrorResponse(w, ErrorResponse{"Submission deadline has passed"}, http.StatusForbidden)
+		return false
+	}
+	if tender.UnsealedAt != nil {
+		SendErrorResponse(w, ErrorResponse{"Bids of this tender have been unsealed"}, http.StatusForbidden)
+		return false
+	}
+	return true
 }
@@ -0,0 +1,439 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ContractDraft      = "Draft"
+	ContractSigned     = "Signed"
+	ContractInProgress = "InProgress"
+	ContractCompleted  = "Completed"
+	ContractTerminated = "Terminated"
+
+	SideBuyer    = "buyer"
+	SideSupplier = "supplier"
+
+	EventContractDrafted = "contract_drafted"
+)
+
+var contractMachine = map[string][]string{
+	ContractSigned:     {ContractInProgress, ContractTerminated},
+	ContractDraft:      {ContractTerminated},
+	ContractInProgress: {ContractCompleted, ContractTerminated},
+}
+
+type Contract struct {
+	ID                uuid.UUID        `json:"id"`
+	TenderID          uuid.UUID        `json:"tenderId"`
+	BidID             uuid.UUID        `json:"bidId"`
+	LotID             *uuid.UUID       `json:"lotId,omitempty"`
+	BuyerID           uuid.UUID        `json:"buyerOrganizationId"`
+	SupplierType      string           `json:"supplierType"`
+	SupplierID        uuid.UUID        `json:"supplierId"`
+	Amount            *decimal.Decimal `json:"amount,omitempty"`
+	Currency          string           `json:"currency,omitempty"`
+	Status            string           `json:"status"`
+	StartDate         *time.Time       `json:"startDate,omitempty"`
+	EndDate           *time.Time       `json:"endDate,omitempty"`
+	BuyerSignedBy     string           `json:"buyerSignedBy,omitempty"`
+	BuyerSignedAt     *time.Time       `json:"buyerSignedAt,omitempty"`
+	SupplierSignedBy  string           `json:"supplierSignedBy,omitempty"`
+	SupplierSignedAt  *time.Time       `json:"supplierSignedAt,omitempty"`
+	TerminationReason string           `json:"terminationReason,omitempty"`
+	Version           int              `json:"version"`
+	CreatedAt         time.Time        `json:"createdAt"`
+	UpdatedAt         time.Time        `json:"updatedAt"`
+}
+
+type ContractTerms struct {
+	Amount    *decimal.Decimal `json:"amount"`
+	Currency  *string          `json:"currency"`
+	StartDate *time.Time       `json:"startDate"`
+	EndDate   *time.Time       `json:"endDate"`
+}
+
+func CreateContract(ctx context.Context, w http.ResponseWriter, tender Tender, bid Bid, lot *Lot) bool {
+	contract := Contract{
+		TenderID:     tender.ID,
+		BidID:        bid.ID,
+		BuyerID:      tender.OrganizationID,
+		SupplierType: bid.AuthorType,
+		SupplierID:   bid.AuthorID,
+		Amount:       bid.Amount,
+		Currency:     bid.Currency,
+		Status:       ContractDraft,
+	}
+	if contract.SupplierType != "Organization" {
+		contract.SupplierType = "User"
+	}
+	if lot != nil {
+		contract.LotID = &lot.ID
+	}
+	if err := storage.Contracts.Create(ctx, &contract); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to create contract"}, http.StatusInternalServerError)
+		return false
+	}
+	message := fmt.Sprintf("Bid %q won tender %q, contract %s is ready for signing", bid.Name, tender.Name, contract.ID)
+	if err := NotifyBidAuthor(ctx, bid, EventContractDrafted, message); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to notify supplier"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func IsContractParty(ctx context.Context, contract Contract, user Principal, side string, perm Permission) (bool, error) {
+	if side == SideBuyer {
+		return allows(ctx, contract.BuyerID, user.Username, perm)
+	}
+	if contract.SupplierType == "User" {
+		return contract.SupplierID == user.ID, nil
+	}
+	return allows(ctx, contract.SupplierID, user.Username, perm)
+}
+
+func CheckContractParty(ctx context.Context, w http.ResponseWriter, contract Contract, user Principal, side string, perm Permission) bool {
+	ok, err := IsContractParty(ctx, contract, user, side, perm)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to authorize"}, http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func GetContractInfo(ctx context.Context, w http.ResponseWriter, contractId uuid.UUID, forUpdate bool) (Contract, bool) {
+	get := storage.Contracts.Get
+	if forUpdate {
+		get = storage.Contracts.GetForUpdate
+	}
+	contract, err := get(ctx, contractId)
+	if errors.Is(err, ErrNotFound) {
+		SendErrorResponse(w, ErrorResponse{"No such contract"}, http.StatusNotFound)
+		return contract, false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find contract"}, http.StatusInternalServerError)
+		return contract, false
+	}
+	return contract, true
+}
+
+func GetPartyContract(ctx context.Context, w http.ResponseWriter, r *http.Request) (Contract, Principal, bool) {
+	var contract Contract
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return contract, user, false
+	}
+	contractId, ok := ParseID(w, mux.Vars(r)["contractId"], "contract")
+	if !ok {
+		return contract, user, false
+	}
+	contract, ok = GetContractInfo(ctx, w, contractId, false)
+	return contract, user, ok
+}
+
+func CheckContractTerms(w http.ResponseWriter, contract Contract) bool {
+	if contract.Amount != nil && contract.Amount.IsNegative() {
+		SendErrorResponse(w, ErrorResponse{"Amount must not be negative"}, http.StatusBadRequest)
+		return false
+	}
+	if (contract.Amount != nil || contract.Currency != "") && !currencyPattern.MatchString(contract.Currency) {
+		SendErrorResponse(w, ErrorResponse{"Invalid currency"}, http.StatusBadRequest)
+		return false
+	}
+	if contract.StartDate != nil && contract.EndDate != nil && !contract.EndDate.After(*contract.StartDate) {
+		SendErrorResponse(w, ErrorResponse{"endDate must be after startDate"}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func UpdateContract(ctx context.Context, w http.ResponseWriter, contract *Contract) bool {
+	contract.Version++
+	if err := storage.Contracts.Update(ctx, contract); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to edit contract"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func sendContracts(w http.ResponseWriter, contracts []Contract) {
+	if contracts == nil {
+		contracts = []Contract{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contracts)
+}
+
+func ShowContractHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowContractHandler started")
+	ctx := r.Context()
+	contract, user, ok := GetPartyContract(ctx, w, r)
+	if !ok {
+		return
+	}
+	party, err := IsContractParty(ctx, contract, user, SideBuyer, PermViewBid)
+	if err == nil && !party {
+		party, err = IsContractParty(ctx, contract, user, SideSupplier, PermViewBid)
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to authorize"}, http.StatusInternalServerError)
+		return
+	}
+	if !party {
+		SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
+		return
+	}
+	SetETag(w, contract.ID, contract.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contract)
+}
+
+func ShowMyContractsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowMyContractsHandler started")
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	page, ok := ParsePage(w, r.URL.Query(), feedSorts)
+	if !ok {
+		return
+	}
+	var organizationId uuid.UUID
+	if oi, ok := GetOrganizationId(ctx, w, user.ID); ok {
+		organizationId = oi
+	}
+	contracts, total, err := storage.Contracts.ListForParty(ctx, user.ID, organizationId, page.Lookahead())
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find contracts"}, http.StatusInternalServerError)
+		return
+	}
+	sendContracts(w, WritePage(w, r, page, contracts, total))
+}
+
+func ShowTenderContractsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderContractsHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermViewBid) {
+		return
+	}
+	contracts, err := storage.Contracts.ListForTender(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find contracts"}, http.StatusInternalServerError)
+		return
+	}
+	sendContracts(w, contracts)
+}
+
+func EditContractHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("EditContractHandler started")
+	ctx := r.Context()
+	contract, user, ok := GetPartyContract(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !CheckContractParty(ctx, w, contract, user, SideBuyer, PermManageContract) {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var terms ContractTerms
+	if err := json.Unmarshal(buf.Bytes(), &terms); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	expected, ok := GetExpectedVersion(w, r, buf.Bytes())
+	if !ok {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if ct, ok := GetContractInfo(ctx, w, contract.ID, true); ok {
+			contract = ct
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, contract.ID, contract.Version, expected) {
+			return false
+		}
+		if contract.Status != ContractDraft || contract.BuyerSignedAt != nil || contract.SupplierSignedAt != nil {
+			SendErrorResponse(w, ErrorResponse{"Only unsigned draft contracts can be edited"}, http.StatusConflict)
+			return false
+		}
+		if terms.Amount != nil {
+			contract.Amount = terms.Amount
+		}
+		if terms.Currency != nil {
+			contract.Currency = *terms.Currency
+		}
+		if terms.StartDate != nil {
+			contract.StartDate = terms.StartDate
+		}
+		if terms.EndDate != nil {
+			contract.EndDate = terms.EndDate
+		}
+		if !CheckContractTerms(w, contract) {
+			return false
+		}
+		return UpdateContract(ctx, w, &contract)
+	})
+	if !ok {
+		return
+	}
+	SetETag(w, contract.ID, contract.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contract)
+}
+
+func SignContractHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SignContractHandler started")
+	ctx := r.Context()
+	contract, user, ok := GetPartyContract(ctx, w, r)
+	if !ok {
+		return
+	}
+	side := r.URL.Query().Get("side")
+	switch side {
+	case SideBuyer:
+		ok = CheckContractParty(ctx, w, contract, user, side, PermManageContract)
+	case SideSupplier:
+		ok = CheckContractParty(ctx, w, contract, user, side, PermEditBid)
+	default:
+		SendErrorResponse(w, ErrorResponse{"side must be buyer or supplier"}, http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		return
+	}
+	expected, ok := GetExpectedVersion(w, r, nil)
+	if !ok {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if ct, ok := GetContractInfo(ctx, w, contract.ID, true); ok {
+			contract = ct
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, contract.ID, contract.Version, expected) {
+			return false
+		}
+		if contract.Status != ContractDraft {
+			SendErrorResponse(w, ErrorResponse{"Contract is already " + contract.Status}, http.StatusConflict)
+			return false
+		}
+		if contract.StartDate == nil || contract.EndDate == nil {
+			SendErrorResponse(w, ErrorResponse{"Contract dates must be set before signing"}, http.StatusConflict)
+			return false
+		}
+		now := time.Now()
+		signedBy, signedAt := &contract.BuyerSignedBy, &contract.BuyerSignedAt
+		if side == SideSupplier {
+			signedBy, signedAt = &contract.SupplierSignedBy, &contract.SupplierSignedAt
+		}
+		if *signedAt != nil {
+			SendErrorResponse(w, ErrorResponse{"Contract is already signed by the " + side}, http.StatusConflict)
+			return false
+		}
+		*signedBy, *signedAt = user.Username, &now
+		if contract.BuyerSignedAt != nil && contract.SupplierSignedAt != nil {
+			contract.Status = ContractSigned
+		}
+		return UpdateContract(ctx, w, &contract)
+	})
+	if !ok {
+		return
+	}
+	SetETag(w, contract.ID, contract.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contract)
+}
+
+func EditContractStatusHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("EditContractStatusHandler started")
+	ctx := r.Context()
+	contract, user, ok := GetPartyContract(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !CheckContractParty(ctx, w, contract, user, SideBuyer, PermManageContract) {
+		return
+	}
+	url := r.URL.Query()
+	status := url.Get("status")
+	if status != ContractInProgress && status != ContractCompleted && status != ContractTerminated {
+		SendErrorResponse(w, ErrorResponse{"Invalid status"}, http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(url.Get("reason"))
+	if status == ContractTerminated && reason == "" {
+		SendErrorResponse(w, ErrorResponse{"Termination reason is required"}, http.StatusBadRequest)
+		return
+	}
+	expected, ok := GetExpectedVersion(w, r, nil)
+	if !ok {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if ct, ok := GetContractInfo(ctx, w, contract.ID, true); ok {
+			contract = ct
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, contract.ID, contract.Version, expected) {
+			return false
+		}
+		if !hasStatus(contractMachine[contract.Status], status) {
+			SendErrorResponse(w, ErrorResponse{"Contract can't be moved from " + contract.Status + " to " + status}, http.StatusConflict)
+			return false
+		}
+		contract.Status = status
+		if status == ContractTerminated {
+			contract.TerminationReason = reason
+		}
+		return UpdateContract(ctx, w, &contract)
+	})
+	if !ok {
+		return
+	}
+	SetETag(w, contract.ID, contract.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(contract)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// awardBid submits a bid and approves it under a single-approval policy.
+func (env *testEnv) awardBid(tender Tender, extra map[string]interface{}) (Bid, Contract) {
+	env.t.Helper()
+	env.expect("PUT", "/api/tenders/"+tender.ID.String()+"/approval-policy", keyAlice,
+		map[string]interface{}{"mode": "fixed", "count": 1}, http.StatusOK, nil)
+	bid := env.submitBid(tender, extra)
+	env.decide(bid, keyDave, "Approved", "", http.StatusOK)
+	var contracts []Contract
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/contracts", keyAlice, nil, http.StatusOK, &contracts)
+	if len(contracts) != 1 {
+		env.t.Fatalf("got %d contracts after the award", len(contracts))
+	}
+	return bid, contracts[0]
+}
+
+func TestContractCreatedOnAward(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", map[string]interface{}{"currency": "RUB"})
+	bid, contract := env.awardBid(tender, map[string]interface{}{"amount": "100", "currency": "RUB"})
+	if contract.BidID != bid.ID || contract.BuyerID != testBuyerOrg || contract.SupplierID != testBob ||
+		contract.Status != ContractDraft || contract.Amount == nil || contract.Amount.String() != "100" {
+		t.Fatalf("got contract %+v", contract)
+	}
+	var mine []Contract
+	env.expect("GET", "/api/contracts/my", keyBob, nil, http.StatusOK, &mine)
+	if len(mine) != 1 || mine[0].ID != contract.ID {
+		t.Fatalf("supplier sees contracts %+v", mine)
+	}
+	env.expect("GET", "/api/contracts/"+contract.ID.String(), keyCarol, nil, http.StatusForbidden, nil)
+}
+
+func TestContractSigning(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	_, contract := env.awardBid(tender, nil)
+	path := "/api/contracts/" + contract.ID.String()
+
+	env.expect("PUT", path+"/sign?side=buyer", keyAlice, nil, http.StatusConflict, nil)
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	terms := map[string]interface{}{"startDate": start, "endDate": start.Add(30 * 24 * time.Hour)}
+	env.expect("PATCH", path, keyBob, terms, http.StatusForbidden, nil)
+	env.expect("PATCH", path, keyAlice, map[string]interface{}{"startDate": start, "endDate": start}, http.StatusBadRequest, nil)
+	env.expect("PATCH", path, keyAlice, terms, http.StatusOK, nil)
+
+	env.expect("PUT", path+"/sign?side=buyer", keyBob, nil, http.StatusForbidden, nil)
+	env.expect("PUT", path+"/sign?side=supplier", keyAlice, nil, http.StatusForbidden, nil)
+	var signed Contract
+	env.expect("PUT", path+"/sign?side=supplier", keyBob, nil, http.StatusOK, &signed)
+	if signed.Status != ContractDraft || signed.SupplierSignedBy != "bob" {
+		t.Fatalf("got %+v after the supplier signature", signed)
+	}
+	env.expect("PATCH", path, keyAlice, terms, http.StatusConflict, nil)
+	env.expect("PUT", path+"/sign?side=supplier", keyBob, nil, http.StatusConflict, nil)
+	env.expect("PUT", path+"/sign?side=buyer", keyAlice, nil, http.StatusOK, &signed)
+	if signed.Status != ContractSigned || signed.BuyerSignedBy != "alice" {
+		t.Fatalf("got %+v after both signatures", signed)
+	}
+
+	env.expect("PUT", path+"/status?status=InProgress", keyBob, nil, http.StatusForbidden, nil)
+	env.expect("PUT", path+"/status?status=Completed", keyAlice, nil, http.StatusConflict, nil)
+	env.expect("PUT", path+"/status?status=Terminated", keyAlice, nil, http.StatusBadRequest, nil)
+	env.expect("PUT", path+"/status?status=InProgress", keyAlice, nil, http.StatusOK, nil)
+	env.expect("PUT", path+"/status?status=Completed", keyAlice, nil, http.StatusOK, &signed)
+	if signed.Status != ContractCompleted {
+		t.Fatalf("got status %s", signed.Status)
+	}
+}
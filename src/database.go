@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+var db *pgxpool.Pool
+
+var dbAcquireTimeout = 5 * time.Second
+
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+type connKey struct{}
+
+type txKey struct{}
+
+func initDB() (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(os.Getenv("POSTGRES_CONN"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse database config: %v", err)
+	}
+	if n, ok, err := envInt("DB_MAX_CONNS"); err != nil {
+		return nil, err
+	} else if ok {
+		config.MaxConns = int32(n)
+	}
+	if n, ok, err := envInt("DB_MIN_CONNS"); err != nil {
+		return nil, err
+	} else if ok {
+		config.MinConns = int32(n)
+	}
+	if d, ok, err := envDuration("DB_STATEMENT_TIMEOUT"); err != nil {
+		return nil, err
+	} else if ok {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(d.Milliseconds(), 10)
+	}
+	if d, ok, err := envDuration("DB_ACQUIRE_TIMEOUT"); err != nil {
+		return nil, err
+	} else if ok {
+		dbAcquireTimeout = d
+	}
+	pool, err := pgxpool.ConnectConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to database: %v", err)
+	}
+	log.Printf("Successfully connected to the database! (max connections: %d)\n", config.MaxConns)
+	return pool, nil
+}
+
+func envInt(name string) (int, bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("invalid %s value: %q", name, value)
+	}
+	return n, true, nil
+}
+
+func envDuration(name string) (time.Duration, bool, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, false, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, false, fmt.Errorf("invalid %s value: %q", name, value)
+	}
+	return d, true, nil
+}
+
+func WithDBConnection(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), dbAcquireTimeout)
+		conn, err := db.Acquire(ctx)
+		cancel()
+		if err != nil {
+			if r.Context().Err() != nil {
+				log.Println("Request cancelled before acquiring connection")
+				return
+			}
+			log.Println(err.Error())
+			w.Header().Set("Retry-After", "1")
+			SendErrorResponse(w, ErrorResponse{"Database is busy, try again later"}, http.StatusServiceUnavailable)
+			return
+		}
+		defer conn.Release()
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), connKey{}, conn)))
+	})
+}
+
+func dbConn(ctx context.Context) querier {
+	if tx, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return tx
+	}
+	if conn, ok := ctx.Value(connKey{}).(*pgxpool.Conn); ok {
+		return conn
+	}
+	return db
+}
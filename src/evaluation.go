@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+const defaultMaxScore = 10
+
+type Criterion struct {
+	ID          uuid.UUID       `json:"id"`
+	TenderID    uuid.UUID       `json:"tenderId"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Weight      decimal.Decimal `json:"weight"`
+	MaxScore    int             `json:"maxScore"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+type BidScore struct {
+	BidID       uuid.UUID       `json:"bidId"`
+	CriterionID uuid.UUID       `json:"criterionId"`
+	Username    string          `json:"username"`
+	Score       decimal.Decimal `json:"score"`
+	Comment     string          `json:"comment,omitempty"`
+	UpdatedAt   time.Time       `json:"updatedAt"`
+}
+
+type CriterionResult struct {
+	CriterionID uuid.UUID        `json:"criterionId"`
+	Name        string           `json:"name"`
+	Weight      decimal.Decimal  `json:"weight"`
+	MaxScore    int              `json:"maxScore"`
+	Average     *decimal.Decimal `json:"average,omitempty"`
+	Scores      int              `json:"scores"`
+}
+
+type BidScoring struct {
+	Position int               `json:"position"`
+	BidID    uuid.UUID         `json:"bidId"`
+	BidName  string            `json:"bidName"`
+	Total    decimal.Decimal   `json:"total"`
+	Complete bool              `json:"complete"`
+	Scorers  int               `json:"scorers"`
+	Criteria []CriterionResult `json:"criteria"`
+}
+
+type TenderScoring struct {
+	TenderID       uuid.UUID    `json:"tenderId"`
+	RequireScoring bool         `json:"requireScoring"`
+	Criteria       []Criterion  `json:"criteria"`
+	Bids           []BidScoring `json:"bids"`
+}
+
+func GetTenderCriteria(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID) ([]Criterion, bool) {
+	criteria, err := storage.Evaluations.ListCriteria(ctx, tenderId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find criteria"}, http.StatusInternalServerError)
+		return nil, false
+	}
+	return criteria, true
+}
+
+func CheckCriterion(w http.ResponseWriter, criterion *Criterion) bool {
+	if strings.TrimSpace(criterion.Name) == "" {
+		SendErrorResponse(w, ErrorResponse{"Criterion name is required"}, http.StatusBadRequest)
+		return false
+	}
+	if !criterion.Weight.IsPositive() {
+		SendErrorResponse(w, ErrorResponse{"Criterion weight must be positive"}, http.StatusBadRequest)
+		return false
+	}
+	if criterion.MaxScore == 0 {
+		criterion.MaxScore = defaultMaxScore
+	}
+	if criterion.MaxScore < 0 {
+		SendErrorResponse(w, ErrorResponse{"maxScore must be positive"}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func CheckCriteriaEditable(ctx context.Context, w http.ResponseWriter, tender Tender) bool {
+	if tender.Status == "Closed" {
+		SendErrorResponse(w, ErrorResponse{"Tender is closed"}, http.StatusForbidden)
+		return false
+	}
+	scored, err := storage.Evaluations.TenderHasScores(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find scores"}, http.StatusInternalServerError)
+		return false
+	}
+	if scored {
+		SendErrorResponse(w, ErrorResponse{"Criteria can't be changed after scoring started"}, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func CheckScoringComplete(ctx context.Context, w http.ResponseWriter, tender Tender, bid Bid, username string) bool {
+	if !tender.RequireScoring {
+		return true
+	}
+	criteria, ok := GetTenderCriteria(ctx, w, tender.ID)
+	if !ok {
+		return false
+	}
+	if len(criteria) == 0 {
+		SendErrorResponse(w, ErrorResponse{"Tender has no evaluation criteria"}, http.StatusConflict)
+		return false
+	}
+	scores, err := storage.Evaluations.ListBidScores(ctx, bid.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find scores"}, http.StatusInternalServerError)
+		return false
+	}
+	scored := map[uuid.UUID]bool{}
+	for _, score := range scores {
+		if score.Username == username {
+			scored[score.CriterionID] = true
+		}
+	}
+	for _, criterion := range criteria {
+		if !scored[criterion.ID] {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Score the bid on criterion %q before submitting a decision", criterion.Name)}, http.StatusConflict)
+			return false
+		}
+	}
+	return true
+}
+
+func ScoreBids(criteria []Criterion, bids []Bid, scores []BidScore) []BidScoring {
+	type sum struct {
+		total decimal.Decimal
+		count int
+	}
+	sums := map[uuid.UUID]map[uuid.UUID]*sum{}
+	scorers := map[uuid.UUID]map[string]bool{}
+	for _, score := range scores {
+		if sums[score.BidID] == nil {
+			sums[score.BidID] = map[uuid.UUID]*sum{}
+			scorers[score.BidID] = map[string]bool{}
+		}
+		s := sums[score.BidID][score.CriterionID]
+		if s == nil {
+			s = &sum{}
+			sums[score.BidID][score.CriterionID] = s
+		}
+		s.total = s.total.Add(score.Score)
+		s.count++
+		scorers[score.BidID][score.Username] = true
+	}
+	weights := decimal.Zero
+	for _, criterion := range criteria {
+		weights = weights.Add(criterion.Weight)
+	}
+	result := []BidScoring{}
+	for _, bid := range bids {
+		scoring := BidScoring{BidID: bid.ID, BidName: bid.Name, Complete: true, Scorers: len(scorers[bid.ID]), Criteria: []CriterionResult{}}
+		weighted := decimal.Zero
+		for _, criterion := range criteria {
+			res := CriterionResult{CriterionID: criterion.ID, Name: criterion.Name, Weight: criterion.Weight, MaxScore: criterion.MaxScore}
+			if s := sums[bid.ID][criterion.ID]; s != nil {
+				avg := s.total.Div(decimal.NewFromInt(int64(s.count))).Round(2)
+				res.Average, res.Scores = &avg, s.count
+				weighted = weighted.Add(criterion.Weight.Mul(avg).Div(decimal.NewFromInt(int64(criterion.MaxScore))))
+			} else {
+				scoring.Complete = false
+			}
+			scoring.Criteria = append(scoring.Criteria, res)
+		}
+		if weights.IsPositive() {
+			scoring.Total = weighted.Div(weights).Mul(decimal.NewFromInt(100)).Round(2)
+		}
+		result = append(result, scoring)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Total.GreaterThan(result[j].Total) })
+	for i := range result {
+		result[i].Position = i + 1
+	}
+	return result
+}
+
+func CreateCriterionHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("CreateCriterionHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	tenderId, ok := ParseID(w, mux.Vars(r)["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var criterion Criterion
+	if err := json.Unmarshal(buf.Bytes(), &criterion); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	criterion.TenderID = tender.ID
+	if !CheckCriterion(w, &criterion) {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckCriteriaEditable(ctx, w, tender) {
+			return false
+		}
+		if err := storage.Evaluations.CreateCriterion(ctx, &criterion); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to create criterion"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(criterion)
+}
+
+func ShowCriteriaHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowCriteriaHandler started")
+	ctx := r.Context()
+	tender, _, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	criteria, ok := GetTenderCriteria(ctx, w, tender.ID)
+	if !ok {
+		return
+	}
+	if criteria == nil {
+		criteria = []Criterion{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(criteria)
+}
+
+func DeleteCriterionHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("DeleteCriterionHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	criterionId, ok := ParseID(w, vars["criterionId"], "criterion")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckCriteriaEditable(ctx, w, tender) {
+			return false
+		}
+		criterion, err := storage.Evaluations.GetCriterion(ctx, criterionId)
+		if errors.Is(err, ErrNotFound) || err == nil && criterion.TenderID != tenderId {
+			SendErrorResponse(w, ErrorResponse{"No such criterion"}, http.StatusNotFound)
+			return false
+		}
+		if err == nil {
+			err = storage.Evaluations.DeleteCriterion(ctx, criterionId)
+		}
+		if err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to delete criterion"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func SubmitScoresHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SubmitScoresHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	bidId, ok := ParseID(w, mux.Vars(r)["bidId"], "bid")
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
+		return
+	}
+	var bid Bid
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
+		bid = bd
+	} else {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, bid.TenderID); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermScoreBid) {
+		return
+	}
+	if !CheckBidNotSealed(w, bid) {
+		return
+	}
+	if tender.Status == "Closed" {
+		SendErrorResponse(w, ErrorResponse{"Tender is closed"}, http.StatusForbidden)
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var scores []BidScore
+	if err := json.Unmarshal(buf.Bytes(), &scores); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	if len(scores) == 0 {
+		SendErrorResponse(w, ErrorResponse{"No scores provided"}, http.StatusBadRequest)
+		return
+	}
+	criteria, ok := GetTenderCriteria(ctx, w, tender.ID)
+	if !ok {
+		return
+	}
+	maxScores := map[uuid.UUID]int{}
+	for _, criterion := range criteria {
+		maxScores[criterion.ID] = criterion.MaxScore
+	}
+	for _, score := range scores {
+		max, ok := maxScores[score.CriterionID]
+		if !ok {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Criterion %s does not belong to the tender", score.CriterionID)}, http.StatusBadRequest)
+			return
+		}
+		if score.Score.IsNegative() || score.Score.GreaterThan(decimal.NewFromInt(int64(max))) {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Score must be between 0 and %d", max)}, http.StatusBadRequest)
+			return
+		}
+	}
+	var own []BidScore
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if _, ok := GetBidInfoForUpdate(ctx, w, bidId); !ok {
+			return false
+		}
+		for i := range scores {
+			scores[i].BidID, scores[i].Username = bid.ID, username
+			if err := storage.Evaluations.SetScore(ctx, &scores[i]); err != nil {
+				log.Println(err.Error())
+				SendErrorResponse(w, ErrorResponse{"Failed to save score"}, http.StatusInternalServerError)
+				return false
+			}
+		}
+		all, err := storage.Evaluations.ListBidScores(ctx, bid.ID)
+		if err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to find scores"}, http.StatusInternalServerError)
+			return false
+		}
+		own = []BidScore{}
+		for _, score := range all {
+			if score.Username == username {
+				own = append(own, score)
+			}
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(own)
+}
+
+func ShowTenderScoringHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderScoringHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermViewBid) {
+		return
+	}
+	filter, ok := ParseBidFilter(w, r.URL.Query())
+	if !ok {
+		return
+	}
+	page, ok := ParsePage(w, r.URL.Query(), bidSorts)
+	if !ok {
+		return
+	}
+	if !UnsealIfDue(ctx, w, &tender) {
+		return
+	}
+	if tender.BidsSealed() {
+		SendErrorResponse(w, ErrorResponse{"Bids are sealed until the submission deadline"}, http.StatusForbidden)
+		return
+	}
+	criteria, ok := GetTenderCriteria(ctx, w, tender.ID)
+	if !ok {
+		return
+	}
+	bids, _, err := storage.Bids.ListForTender(ctx, tender.ID, tender.OrganizationID, filter, Page{Sort: page.Sort, Desc: page.Desc})
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
+		return
+	}
+	scores, err := storage.Evaluations.ListScores(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find scores"}, http.StatusInternalServerError)
+		return
+	}
+	if criteria == nil {
+		criteria = []Criterion{}
+	}
+	scoring := TenderScoring{tender.ID, tender.RequireScoring, criteria, ScoreBids(criteria, bids, scores)}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(scoring)
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	InvitationPending  = "Pending"
+	InvitationAccepted = "Accepted"
+	InvitationDeclined = "Declined"
+
+	EventTenderInvitation = "tender_invitation"
+)
+
+type Invitation struct {
+	ID          uuid.UUID  `json:"id"`
+	TenderID    uuid.UUID  `json:"tenderId"`
+	InviteeType string     `json:"inviteeType"`
+	InviteeID   uuid.UUID  `json:"inviteeId"`
+	Status      string     `json:"status"`
+	InvitedBy   string     `json:"invitedBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RespondedAt *time.Time `json:"respondedAt,omitempty"`
+}
+
+func (i Invitation) invites(userId, organizationId uuid.UUID) bool {
+	if i.InviteeType == "User" {
+		return i.InviteeID == userId
+	}
+	return organizationId != uuid.Nil && i.InviteeID == organizationId
+}
+
+func partyInvited(ctx context.Context, tenderId, userId, organizationId uuid.UUID, accepted bool) (bool, error) {
+	parties := []struct {
+		Type string
+		ID   uuid.UUID
+	}{{"User", userId}, {"Organization", organizationId}}
+	for _, party := range parties {
+		if party.ID == uuid.Nil {
+			continue
+		}
+		invitation, err := storage.Invitations.Find(ctx, tenderId, party.Type, party.ID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		if invitation.Status == InvitationAccepted || !accepted && invitation.Status == InvitationPending {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func IsInvited(ctx context.Context, tenderId uuid.UUID, username string) (bool, error) {
+	userId, err := storage.Organizations.GetUserID(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	organizationId, err := storage.Organizations.GetUserOrganization(ctx, userId)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return false, err
+	}
+	return partyInvited(ctx, tenderId, userId, organizationId, false)
+}
+
+func CheckTenderVisible(ctx context.Context, w http.ResponseWriter, tender Tender, username string, perm Permission) bool {
+	if tender.Status == "Published" && !tender.Private {
+		return true
+	}
+	if tender.Status == "Published" {
+		invited, err := IsInvited(ctx, tender.ID, username)
+		if err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to check invitation"}, http.StatusInternalServerError)
+			return false
+		}
+		if invited {
+			return true
+		}
+	}
+	return Authorize(ctx, w, tender.OrganizationID, username, perm)
+}
+
+func CheckBidVisible(ctx context.Context, w http.ResponseWriter, bid Bid, user Principal) bool {
+	if IsBidAuthor(ctx, bid, user) {
+		return true
+	}
+	if bid.Status != "Published" {
+		return Authorize(ctx, w, bid.OrganizationID, user.Username, PermViewBid)
+	}
+	tender, ok := GetTenderInfo(ctx, w, bid.TenderID)
+	return ok && CheckTenderVisible(ctx, w, tender, user.Username, PermViewBid)
+}
+
+func CheckBidderInvited(ctx context.Context, w http.ResponseWriter, tender Tender, bid Bid) bool {
+	if !tender.Private || tender.OrganizationID == bid.OrganizationID {
+		return true
+	}
+	var userId uuid.UUID
+	if bid.AuthorType != "Organization" {
+		userId = bid.AuthorID
+	}
+	invited, err := partyInvited(ctx, tender.ID, userId, bid.OrganizationID, true)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to check invitation"}, http.StatusInternalServerError)
+		return false
+	}
+	if !invited {
+		SendErrorResponse(w, ErrorResponse{"Tender is invitation-only, accept an invitation first"}, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func GetInvitationInfo(ctx context.Context, w http.ResponseWriter, invitationId uuid.UUID) (Invitation, bool) {
+	invitation, err := storage.Invitations.Get(ctx, invitationId)
+	if errors.Is(err, ErrNotFound) {
+		SendErrorResponse(w, ErrorResponse{"No such invitation"}, http.StatusNotFound)
+		return invitation, false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find invitation"}, http.StatusInternalServerError)
+		return invitation, false
+	}
+	return invitation, true
+}
+
+func CheckInvitee(ctx context.Context, w http.ResponseWriter, tender Tender, invitation Invitation) bool {
+	switch invitation.InviteeType {
+	case "Organization":
+		if !CheckOrganizationExists(ctx, w, invitation.InviteeID) {
+			return false
+		}
+		if invitation.InviteeID == tender.OrganizationID {
+			SendErrorResponse(w, ErrorResponse{"Tender organization can't be invited"}, http.StatusBadRequest)
+			return false
+		}
+	case "User":
+		if _, ok := GetUsername(ctx, w, invitation.InviteeID); !ok {
+			return false
+		}
+	default:
+		SendErrorResponse(w, ErrorResponse{"inviteeType must be Organization or User"}, http.StatusBadRequest)
+		return false
+	}
+	_, err := storage.Invitations.Find(ctx, tender.ID, invitation.InviteeType, invitation.InviteeID)
+	if err == nil {
+		SendErrorResponse(w, ErrorResponse{"Already invited"}, http.StatusConflict)
+		return false
+	}
+	if !errors.Is(err, ErrNotFound) {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find invitation"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func ShowInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowInvitationsHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermViewTender) {
+		return
+	}
+	invitations, err := storage.Invitations.ListForTender(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find invitations"}, http.StatusInternalServerError)
+		return
+	}
+	if invitations == nil {
+		invitations = []Invitation{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func CreateInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("CreateInvitationHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var invitation Invitation
+	if err := json.Unmarshal(buf.Bytes(), &invitation); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	invitation.TenderID, invitation.InvitedBy = tender.ID, username
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if !CheckInvitee(ctx, w, tender, invitation) {
+			return false
+		}
+		if err := storage.Invitations.Create(ctx, &invitation); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to create invitation"}, http.StatusInternalServerError)
+			return false
+		}
+		tenderId := tender.ID
+		message := fmt.Sprintf("You are invited to tender %q", tender.Name)
+		notification := Notification{Event: EventTenderInvitation, RecipientType: invitation.InviteeType, RecipientID: invitation.InviteeID, TenderID: &tenderId, Message: message}
+		if err := notify(ctx, notification); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to notify invitee"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+func DeleteInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("DeleteInvitationHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	invitationId, ok := ParseID(w, mux.Vars(r)["invitationId"], "invitation")
+	if !ok {
+		return
+	}
+	invitation, ok := GetInvitationInfo(ctx, w, invitationId)
+	if !ok {
+		return
+	}
+	if invitation.TenderID != tender.ID {
+		SendErrorResponse(w, ErrorResponse{"No such invitation"}, http.StatusNotFound)
+		return
+	}
+	if err := storage.Invitations.Delete(ctx, invitation.ID); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to delete invitation"}, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ShowMyInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowMyInvitationsHandler started")
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	page, ok := ParsePage(w, r.URL.Query(), feedSorts)
+	if !ok {
+		return
+	}
+	var organizationId uuid.UUID
+	if oi, ok := GetOrganizationId(ctx, w, user.ID); ok {
+		organizationId = oi
+	}
+	invitations, total, err := storage.Invitations.ListForInvitee(ctx, user.ID, organizationId, page.Lookahead())
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find invitations"}, http.StatusInternalServerError)
+		return
+	}
+	invitations = WritePage(w, r, page, invitations, total)
+	if invitations == nil {
+		invitations = []Invitation{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitations)
+}
+
+func RespondInvitation(w http.ResponseWriter, r *http.Request, status string) {
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	invitationId, ok := ParseID(w, mux.Vars(r)["invitationId"], "invitation")
+	if !ok {
+		return
+	}
+	invitation, ok := GetInvitationInfo(ctx, w, invitationId)
+	if !ok {
+		return
+	}
+	if invitation.InviteeType == "User" && invitation.InviteeID != user.ID {
+		SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
+		return
+	}
+	if invitation.InviteeType == "Organization" && !Authorize(ctx, w, invitation.InviteeID, user.Username, PermCreateBid) {
+		return
+	}
+	if invitation.Status != InvitationPending {
+		SendErrorResponse(w, ErrorResponse{"Invitation is already " + invitation.Status}, http.StatusConflict)
+		return
+	}
+	now := time.Now()
+	invitation.Status, invitation.RespondedAt = status, &now
+	if err := storage.Invitations.Update(ctx, &invitation); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to update invitation"}, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(invitation)
+}
+
+func AcceptInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("AcceptInvitationHandler started")
+	RespondInvitation(w, r, InvitationAccepted)
+}
+
+func DeclineInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("DeclineInvitationHandler started")
+	RespondInvitation(w, r, InvitationDeclined)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPrivateTenderVisibleOnlyToInvited(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Secret", map[string]interface{}{"private": true})
+	status := "/api/tenders/" + tender.ID.String() + "/status"
+	var tenders []Tender
+	env.expect("GET", "/api/tenders", keyBob, nil, http.StatusOK, &tenders)
+	if len(tenders) != 0 {
+		t.Fatalf("private tender listed for an outsider: %+v", tenders)
+	}
+	env.expect("GET", status, keyBob, nil, http.StatusForbidden, nil)
+
+	invitations := "/api/tenders/" + tender.ID.String() + "/invitations"
+	env.expect("POST", invitations, keyBob, map[string]interface{}{"inviteeType": "User", "inviteeId": testBob}, http.StatusForbidden, nil)
+	var invitation Invitation
+	env.expect("POST", invitations, keyAlice, map[string]interface{}{"inviteeType": "User", "inviteeId": testBob}, http.StatusOK, &invitation)
+	env.expect("GET", status, keyBob, nil, http.StatusOK, nil)
+	env.expect("GET", status, keyCarol, nil, http.StatusForbidden, nil)
+
+	body := map[string]interface{}{"name": "b", "description": "d", "tenderId": tender.ID, "authorType": "User", "authorId": testBob}
+	env.expect("POST", "/api/bids/new", keyBob, body, http.StatusForbidden, nil)
+	var mine []Invitation
+	env.expect("GET", "/api/invitations/my", keyBob, nil, http.StatusOK, &mine)
+	if len(mine) != 1 || mine[0].ID != invitation.ID {
+		t.Fatalf("got invitations %+v", mine)
+	}
+	env.expect("PUT", "/api/invitations/"+invitation.ID.String()+"/accept", keyCarol, nil, http.StatusForbidden, nil)
+	env.expect("PUT", "/api/invitations/"+invitation.ID.String()+"/accept", keyBob, nil, http.StatusOK, nil)
+	env.expect("POST", "/api/bids/new", keyBob, body, http.StatusOK, nil)
+	env.expect("GET", "/api/tenders", keyBob, nil, http.StatusOK, &tenders)
+	if len(tenders) != 1 {
+		t.Fatalf("invited user sees %d tenders", len(tenders))
+	}
+}
+
+func TestPrivateTenderHidesBids(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Secret", map[string]interface{}{"private": true})
+	var invitation Invitation
+	env.expect("POST", "/api/tenders/"+tender.ID.String()+"/invitations", keyAlice,
+		map[string]interface{}{"inviteeType": "User", "inviteeId": testBob}, http.StatusOK, &invitation)
+	env.expect("PUT", "/api/invitations/"+invitation.ID.String()+"/accept", keyBob, nil, http.StatusOK, nil)
+	bid := env.submitBid(tender, nil)
+
+	env.expect("GET", "/api/bids/"+bid.ID.String()+"/status", keyCarol, nil, http.StatusForbidden, nil)
+	env.expect("GET", "/api/bids/"+bid.ID.String()+"/versions", keyCarol, nil, http.StatusForbidden, nil)
+	env.expect("GET", "/api/bids/"+bid.ID.String()+"/status", keyDave, nil, http.StatusOK, nil)
+}
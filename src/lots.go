@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	LotOpen     = "Open"
+	LotAwarded  = "Awarded"
+	LotCanceled = "Canceled"
+)
+
+type Lot struct {
+	ID           uuid.UUID        `json:"id"`
+	TenderID     uuid.UUID        `json:"tenderId"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	Budget       *decimal.Decimal `json:"budget,omitempty"`
+	Status       string           `json:"status"`
+	AwardedBidID *uuid.UUID       `json:"awardedBidId,omitempty"`
+	CreatedAt    time.Time        `json:"createdAt"`
+}
+
+func GetTenderLots(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID) ([]Lot, bool) {
+	lots, err := storage.Lots.ListForTender(ctx, tenderId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find lots"}, http.StatusInternalServerError)
+		return nil, false
+	}
+	return lots, true
+}
+
+func GetLotInfo(ctx context.Context, w http.ResponseWriter, tenderId, lotId uuid.UUID, forUpdate bool) (Lot, bool) {
+	get := storage.Lots.Get
+	if forUpdate {
+		get = storage.Lots.GetForUpdate
+	}
+	lot, err := get(ctx, lotId)
+	if errors.Is(err, ErrNotFound) || err == nil && lot.TenderID != tenderId {
+		SendErrorResponse(w, ErrorResponse{"No such lot"}, http.StatusNotFound)
+		return lot, false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find lot"}, http.StatusInternalServerError)
+		return lot, false
+	}
+	return lot, true
+}
+
+func CheckLot(w http.ResponseWriter, tender Tender, lot Lot) bool {
+	if strings.TrimSpace(lot.Name) == "" {
+		SendErrorResponse(w, ErrorResponse{"Lot name is required"}, http.StatusBadRequest)
+		return false
+	}
+	if lot.Budget == nil {
+		return true
+	}
+	if lot.Budget.IsNegative() {
+		SendErrorResponse(w, ErrorResponse{"Amounts must not be negative"}, http.StatusBadRequest)
+		return false
+	}
+	if tender.Currency == "" {
+		SendErrorResponse(w, ErrorResponse{"Lot budget requires tender currency"}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func CheckLotsEditable(w http.ResponseWriter, tender Tender) bool {
+	if tender.Status != "Created" {
+		SendErrorResponse(w, ErrorResponse{"Lots can only be changed before the tender is published"}, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func CheckBidLots(ctx context.Context, w http.ResponseWriter, tender Tender, bid *Bid) bool {
+	lots, ok := GetTenderLots(ctx, w, tender.ID)
+	if !ok {
+		return false
+	}
+	if len(lots) == 0 {
+		if len(bid.LotIDs) > 0 {
+			SendErrorResponse(w, ErrorResponse{"Tender has no lots"}, http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+	if len(bid.LotIDs) == 0 {
+		SendErrorResponse(w, ErrorResponse{"lotIds is required for tenders with lots"}, http.StatusBadRequest)
+		return false
+	}
+	status := map[uuid.UUID]string{}
+	for _, lot := range lots {
+		status[lot.ID] = lot.Status
+	}
+	seen := map[uuid.UUID]bool{}
+	var ids []uuid.UUID
+	for _, id := range bid.LotIDs {
+		st, ok := status[id]
+		if !ok {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Lot %s does not belong to the tender", id)}, http.StatusBadRequest)
+			return false
+		}
+		if st != LotOpen {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Lot %s is not open", id)}, http.StatusBadRequest)
+			return false
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+	bid.LotIDs = ids
+	return true
+}
+
+func bidTargetsLot(bid Bid, lotId uuid.UUID) bool {
+	for _, id := range bid.LotIDs {
+		if id == lotId {
+			return true
+		}
+	}
+	return false
+}
+
+func GetDecisionLot(ctx context.Context, w http.ResponseWriter, bid Bid, value string) (*Lot, bool) {
+	if len(bid.LotIDs) == 0 {
+		if value != "" {
+			SendErrorResponse(w, ErrorResponse{"Bid does not target any lot"}, http.StatusBadRequest)
+			return nil, false
+		}
+		return nil, true
+	}
+	lotId := bid.LotIDs[0]
+	if value != "" {
+		id, ok := ParseID(w, value, "lot")
+		if !ok {
+			return nil, false
+		}
+		lotId = id
+	} else if len(bid.LotIDs) > 1 {
+		SendErrorResponse(w, ErrorResponse{"No lot provided"}, http.StatusBadRequest)
+		return nil, false
+	}
+	if !bidTargetsLot(bid, lotId) {
+		SendErrorResponse(w, ErrorResponse{"Bid does not target this lot"}, http.StatusBadRequest)
+		return nil, false
+	}
+	lot, ok := GetLotInfo(ctx, w, bid.TenderID, lotId, false)
+	if !ok {
+		return nil, false
+	}
+	return &lot, true
+}
+
+func CheckDecisionOpen(ctx context.Context, w http.ResponseWriter, tender Tender, bid Bid, lot *Lot) bool {
+	if bid.Status == BidWithdrawn {
+		SendErrorResponse(w, ErrorResponse{"Bid has been withdrawn"}, http.StatusConflict)
+		return false
+	}
+	if bid.Decision != "None" {
+		SendErrorResponse(w, ErrorResponse{"Decision already made"}, http.StatusBadRequest)
+		return false
+	}
+	if bid.Status != BidSubmitted {
+		SendErrorResponse(w, ErrorResponse{"Only submitted bids can be decided"}, http.StatusConflict)
+		return false
+	}
+	if tender.Status != "Published" && tender.Status != "Evaluation" {
+		SendErrorResponse(w, ErrorResponse{"Tender is " + strings.ToLower(tender.Status)}, http.StatusConflict)
+		return false
+	}
+	if lot == nil {
+		return true
+	}
+	state, ok := EvaluateApproval(ctx, w, tender, bid, lot.ID)
+	if !ok {
+		return false
+	}
+	if state.Status != "Pending" {
+		SendErrorResponse(w, ErrorResponse{"Decision already made for this lot"}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func CheckLotOpen(w http.ResponseWriter, lot Lot) bool {
+	if lot.Status != LotOpen {
+		SendErrorResponse(w, ErrorResponse{"Lot is already " + strings.ToLower(lot.Status)}, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func AwardLot(ctx context.Context, w http.ResponseWriter, lot *Lot, bid Bid) bool {
+	lot.Status = LotAwarded
+	lot.AwardedBidID = &bid.ID
+	if err := storage.Lots.Update(ctx, lot); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to award lot"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func LotsResolved(ctx context.Context, w http.ResponseWriter, tenderId uuid.UUID) (bool, bool) {
+	lots, ok := GetTenderLots(ctx, w, tenderId)
+	if !ok {
+		return false, false
+	}
+	for _, lot := range lots {
+		if lot.Status == LotOpen {
+			return false, true
+		}
+	}
+	return true, true
+}
+
+func BidLotsDecision(ctx context.Context, w http.ResponseWriter, tender Tender, bid Bid) (string, bool) {
+	decision := "Rejected"
+	for _, lotId := range bid.LotIDs {
+		lot, ok := GetLotInfo(ctx, w, bid.TenderID, lotId, false)
+		if !ok {
+			return "", false
+		}
+		if lot.AwardedBidID != nil && *lot.AwardedBidID == bid.ID {
+			decision = "Approved"
+			continue
+		}
+		if lot.Status != LotOpen {
+			continue
+		}
+		state, ok := EvaluateApproval(ctx, w, tender, bid, lotId)
+		if !ok {
+			return "", false
+		}
+		if state.Status != "Rejected" {
+			return "", true
+		}
+	}
+	return decision, true
+}
+
+func DecideLot(ctx context.Context, w http.ResponseWriter, tender Tender, bid *Bid, decision, username string) bool {
+	final, ok := BidLotsDecision(ctx, w, tender, *bid)
+	if !ok {
+		return false
+	}
+	if final == "" {
+		return RecordVote(ctx, w, bid, decision, username)
+	}
+	if decision != "Approved" {
+		bid.ApprovedCount--
+	}
+	return MakeDecision(ctx, w, bid, final, username)
+}
+
+func CreateLotHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("CreateLotHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	tenderId, ok := ParseID(w, mux.Vars(r)["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var lot Lot
+	if err := json.Unmarshal(buf.Bytes(), &lot); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	lot.TenderID = tender.ID
+	if !CheckLot(w, tender, lot) {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckLotsEditable(w, tender) {
+			return false
+		}
+		if err := storage.Lots.Create(ctx, &lot); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to create lot"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lot)
+}
+
+func ShowTenderLotsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderLotsHandler started")
+	ctx := r.Context()
+	tender, _, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	lots, ok := GetTenderLots(ctx, w, tender.ID)
+	if !ok {
+		return
+	}
+	if lots == nil {
+		lots = []Lot{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lots)
+}
+
+func EditLotHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("EditLotHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	lotId, ok := ParseID(w, vars["lotId"], "lot")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var lot Lot
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckLotsEditable(w, tender) {
+			return false
+		}
+		if lt, ok := GetLotInfo(ctx, w, tenderId, lotId, true); ok {
+			lot = lt
+		} else {
+			return false
+		}
+		patch := lot
+		if err := json.Unmarshal(buf.Bytes(), &patch); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+			return false
+		}
+		lot.Name, lot.Description, lot.Budget = patch.Name, patch.Description, patch.Budget
+		if !CheckLot(w, tender, lot) {
+			return false
+		}
+		if err := storage.Lots.Update(ctx, &lot); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to edit lot"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lot)
+}
+
+func EditLotStatusHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("EditLotStatusHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	lotId, ok := ParseID(w, vars["lotId"], "lot")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	switch st := r.URL.Query().Get("status"); st {
+	case "":
+		SendErrorResponse(w, ErrorResponse{"No status provided"}, http.StatusBadRequest)
+		return
+	case LotCanceled:
+	default:
+		SendErrorResponse(w, ErrorResponse{"Undefined status provided"}, http.StatusBadRequest)
+		return
+	}
+	var lot Lot
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if lt, ok := GetLotInfo(ctx, w, tenderId, lotId, true); ok {
+			lot = lt
+		} else {
+			return false
+		}
+		if !CheckLotOpen(w, lot) {
+			return false
+		}
+		lot.Status = LotCanceled
+		if err := storage.Lots.Update(ctx, &lot); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to edit lot status"}, http.StatusInternalServerError)
+			return false
+		}
+		if tender.Status == "Published" || tender.Status == "Evaluation" {
+			return ClosingTender(ctx, w, tender.ID, username)
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(lot)
+}
@@ -2,59 +2,141 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 
 	"github.com/gorilla/mux"
-	"github.com/jackc/pgx/v4"
 )
 
-var db *pgx.Conn
-
-func initDB() (*pgx.Conn, error) {
-	conn, err := pgx.Connect(context.Background(), os.Getenv("POSTGRES_CONN"))
-	if err != nil {
-		return nil, fmt.Errorf("unable to connect to database: %v", err)
-	}
-	log.Println("Successfully connected to the database!")
-	return conn, nil
-}
-
 func PingHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("PingHandler started")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("ok"))
 }
 
+func NewRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/ping", PingHandler).Methods("GET")
+	api := router.NewRoute().Subrouter()
+	if db != nil {
+		api.Use(WithDBConnection)
+	}
+	api.Use(Authenticate)
+	api.HandleFunc("/api/auth/token", IssueTokenHandler).Methods("POST")
+	api.HandleFunc("/api/tenders", ShowTendersHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/new", CreateTenderHandler).Methods("POST")
+	api.HandleFunc("/api/tenders/search", SearchTendersHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/my", ShowUsersTendersHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/status", ShowTenderStatusHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/status", EditTenderStatusHandler).Methods("PUT")
+	api.HandleFunc("/api/tenders/{tenderId}/cancel", CancelTenderHandler).Methods("PUT")
+	api.HandleFunc("/api/tenders/{tenderId}/transitions", ShowTenderTransitionsHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/edit", EditTenderHandler).Methods("PATCH")
+	api.HandleFunc("/api/tenders/{tenderId}/rollback/{version}", TenderRollbackHandler).Methods("PUT")
+	api.HandleFunc("/api/tenders/{tenderId}/seal", ShowTenderSealHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/lots", ShowTenderLotsHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/lots", CreateLotHandler).Methods("POST")
+	api.HandleFunc("/api/tenders/{tenderId}/lots/{lotId}", EditLotHandler).Methods("PATCH")
+	api.HandleFunc("/api/tenders/{tenderId}/lots/{lotId}/status", EditLotStatusHandler).Methods("PUT")
+	api.HandleFunc("/api/tenders/{tenderId}/criteria", ShowCriteriaHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/criteria", CreateCriterionHandler).Methods("POST")
+	api.HandleFunc("/api/tenders/{tenderId}/criteria/{criterionId}", DeleteCriterionHandler).Methods("DELETE")
+	api.HandleFunc("/api/tenders/{tenderId}/scoring", ShowTenderScoringHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/approval-policy", ShowTenderPolicyHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/approval-policy", SetTenderPolicyHandler).Methods("PUT")
+	api.HandleFunc("/api/tenders/{tenderId}/approval-policy", DeleteTenderPolicyHandler).Methods("DELETE")
+	api.HandleFunc("/api/tenders/{tenderId}/questions", ShowQuestionsHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/questions", CreateQuestionHandler).Methods("POST")
+	api.HandleFunc("/api/tenders/{tenderId}/questions/{questionId}/answer", AnswerQuestionHandler).Methods("PUT")
+	api.HandleFunc("/api/tenders/{tenderId}/invitations", ShowInvitationsHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/invitations", CreateInvitationHandler).Methods("POST")
+	api.HandleFunc("/api/tenders/{tenderId}/invitations/{invitationId}", DeleteInvitationHandler).Methods("DELETE")
+	api.HandleFunc("/api/tenders/{tenderId}/attachments", ShowTenderAttachmentsHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/attachments", UploadTenderAttachmentHandler).Methods("POST")
+	api.HandleFunc("/api/tenders/{tenderId}/contracts", ShowTenderContractsHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/ranking", ShowAuctionRankingHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/versions", ShowTenderVersionsHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/versions/diff", TenderVersionsDiffHandler).Methods("GET")
+	api.HandleFunc("/api/tenders/{tenderId}/versions/{version}", ShowTenderVersionHandler).Methods("GET")
+	api.HandleFunc("/api/bids/new", CreateBidHandler).Methods("POST")
+	api.HandleFunc("/api/bids/search", SearchBidsHandler).Methods("GET")
+	api.HandleFunc("/api/bids/my", ShowUsersBidsHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{tenderId}/list", ShowTenderBidsHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/status", ShowBidStatusHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/status", EditBidStatusHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{bidId}/transitions", ShowBidTransitionsHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/submit", SubmitBidHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{bidId}/withdraw", WithdrawBidHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{bidId}/edit", EditBidHandler).Methods("PATCH")
+	api.HandleFunc("/api/bids/{bidId}/offer", SubmitOfferHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{bidId}/scores", SubmitScoresHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{bidId}/submit_decision", SubmitDecisionHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{bidId}/approvals", ShowBidApprovalsHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/rollback/{version}", BidRollbackHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{bidId}/attachments", ShowBidAttachmentsHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/attachments", UploadBidAttachmentHandler).Methods("POST")
+	api.HandleFunc("/api/reviews/{reviewId}/attachments", ShowReviewAttachmentsHandler).Methods("GET")
+	api.HandleFunc("/api/reviews/{reviewId}/attachments", UploadReviewAttachmentHandler).Methods("POST")
+	api.HandleFunc("/api/attachments/{attachmentId}", DownloadAttachmentHandler).Methods("GET")
+	api.HandleFunc("/api/attachments/{attachmentId}", DeleteAttachmentHandler).Methods("DELETE")
+	api.HandleFunc("/api/bids/{bidId}/versions", ShowBidVersionsHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/versions/diff", BidVersionsDiffHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/versions/{version}", ShowBidVersionHandler).Methods("GET")
+	api.HandleFunc("/api/bids/{bidId}/feedback", BidReviewHandler).Methods("PUT")
+	api.HandleFunc("/api/bids/{tenderId}/reviews", ShowBidReviewsHandler).Methods("GET")
+	api.HandleFunc("/api/organizations/{organizationId}/members", ShowMembersHandler).Methods("GET")
+	api.HandleFunc("/api/notifications/my", ShowMyNotificationsHandler).Methods("GET")
+	api.HandleFunc("/api/invitations/my", ShowMyInvitationsHandler).Methods("GET")
+	api.HandleFunc("/api/invitations/{invitationId}/accept", AcceptInvitationHandler).Methods("PUT")
+	api.HandleFunc("/api/invitations/{invitationId}/decline", DeclineInvitationHandler).Methods("PUT")
+	api.HandleFunc("/api/contracts/my", ShowMyContractsHandler).Methods("GET")
+	api.HandleFunc("/api/contracts/{contractId}", ShowContractHandler).Methods("GET")
+	api.HandleFunc("/api/contracts/{contractId}", EditContractHandler).Methods("PATCH")
+	api.HandleFunc("/api/contracts/{contractId}/sign", SignContractHandler).Methods("PUT")
+	api.HandleFunc("/api/contracts/{contractId}/status", EditContractStatusHandler).Methods("PUT")
+	api.HandleFunc("/api/organizations/{organizationId}/approval-policy", ShowOrganizationPolicyHandler).Methods("GET")
+	api.HandleFunc("/api/organizations/{organizationId}/approval-policy", SetOrganizationPolicyHandler).Methods("PUT")
+	api.HandleFunc("/api/organizations/{organizationId}/members/audit", ShowRoleAuditHandler).Methods("GET")
+	api.HandleFunc("/api/organizations/{organizationId}/members/{username}", GrantRoleHandler).Methods("PUT")
+	api.HandleFunc("/api/organizations/{organizationId}/members/{username}", RevokeRoleHandler).Methods("DELETE")
+	return router
+}
+
 func main() {
-	var err error
 	log.Println("Program started")
-	db, err = initDB()
-	if err != nil {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := RunMigrateCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "apikey" {
+		if err := RunAPIKeyCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := initStorage(os.Getenv("STORAGE")); err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close(context.Background())
-	router := mux.NewRouter()
-	router.HandleFunc("/api/ping", PingHandler).Methods("GET")
-	router.HandleFunc("/api/tenders", ShowTendersHandler).Methods("GET")
-	router.HandleFunc("/api/tenders/new", CreateTenderHandler).Methods("POST")
-	router.HandleFunc("/api/tenders/my", ShowUsersTendersHandler).Methods("GET")
-	router.HandleFunc("/api/tenders/{tenderId}/status", ShowTenderStatusHandler).Methods("GET")
-	router.HandleFunc("/api/tenders/{tenderId}/status", EditTenderStatusHandler).Methods("PUT")
-	router.HandleFunc("/api/tenders/{tenderId}/edit", EditTenderHandler).Methods("PATCH")
-	router.HandleFunc("/api/tenders/{tenderId}/rollback/{version}", TenderRollbackHandler).Methods("PUT")
-	router.HandleFunc("/api/bids/new", CreateBidHandler).Methods("POST")
-	router.HandleFunc("/api/bids/my", ShowUsersBidsHandler).Methods("GET")
-	router.HandleFunc("/api/bids/{tenderId}/list", ShowTenderBidsHandler).Methods("GET")
-	router.HandleFunc("/api/bids/{bidId}/status", ShowBidStatusHandler).Methods("GET")
-	router.HandleFunc("/api/bids/{bidId}/status", EditBidStatusHandler).Methods("PUT")
-	router.HandleFunc("/api/bids/{bidId}/edit", EditBidHandler).Methods("PATCH")
-	router.HandleFunc("/api/bids/{bidId}/submit_decision", SubmitDecisionHandler).Methods("PUT")
-	router.HandleFunc("/api/bids/{bidId}/rollback/{version}", BidRollbackHandler).Methods("PUT")
-	router.HandleFunc("/api/bids/{bidId}/feedback", BidReviewHandler).Methods("PUT")
-	router.HandleFunc("/api/bids/{tenderId}/reviews", ShowBidReviewsHandler).Methods("GET")
+	if err := initSealing(); err != nil {
+		log.Fatal(err)
+	}
+	if err := initAuth(); err != nil {
+		log.Fatal(err)
+	}
+	if err := initBlobStore(); err != nil {
+		log.Fatal(err)
+	}
+	if err := initScheduler(); err != nil {
+		log.Fatal(err)
+	}
+	go RunScheduler(context.Background())
+	if db != nil {
+		defer db.Close()
+	}
+	router := NewRouter()
 
 	server_address := os.Getenv("SERVER_ADDRESS")
 	log.Printf("Starting server at %s\n", server_address)
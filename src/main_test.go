@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	testBuyerOrg    = uuid.MustParse("aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa")
+	testSupplierOrg = uuid.MustParse("bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb")
+	testAlice       = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+	testBob         = uuid.MustParse("22222222-2222-2222-2222-222222222222")
+	testCarol       = uuid.MustParse("33333333-3333-3333-3333-333333333333")
+	testDave        = uuid.MustParse("44444444-4444-4444-4444-444444444444")
+)
+
+const (
+	keyAlice = "key-alice"
+	keyBob   = "key-bob"
+	keyCarol = "key-carol"
+	keyDave  = "key-dave"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+type testEnv struct {
+	t      *testing.T
+	store  *MemoryStore
+	router http.Handler
+}
+
+// newTestEnv seeds a memory store with a buyer organization (alice is admin,
+// dave is approver) and a supplier organization (bob is admin, carol is editor).
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	store := NewMemoryStore()
+	for id, name := range map[uuid.UUID]string{testAlice: "alice", testBob: "bob", testCarol: "carol", testDave: "dave"} {
+		store.AddEmployee(id, name)
+	}
+	store.AddOrganization(testBuyerOrg, "Buyer")
+	store.AddOrganization(testSupplierOrg, "Supplier")
+	store.AddMember(testBuyerOrg, testAlice, RoleAdmin)
+	store.AddMember(testBuyerOrg, testDave, RoleApprover)
+	store.AddMember(testSupplierOrg, testBob, RoleAdmin)
+	store.AddMember(testSupplierOrg, testCarol, RoleEditor)
+	store.AddAPIKey(testAlice, keyAlice)
+	store.AddAPIKey(testBob, keyBob)
+	store.AddAPIKey(testCarol, keyCarol)
+	store.AddAPIKey(testDave, keyDave)
+	storage, legacyAuth, jwtSecret = store.Storage(), false, []byte("test-secret")
+	return &testEnv{t, store, NewRouter()}
+}
+
+func (env *testEnv) do(method, path, key string, body interface{}) *httptest.ResponseRecorder {
+	env.t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			env.t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	r := httptest.NewRequest(method, path, reader)
+	if key != "" {
+		r.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, r)
+	return w
+}
+
+func (env *testEnv) expect(method, path, key string, body interface{}, status int, out interface{}) *httptest.ResponseRecorder {
+	env.t.Helper()
+	w := env.do(method, path, key, body)
+	if w.Code != status {
+		env.t.Fatalf("%s %s: got %d, want %d: %s", method, path, w.Code, status, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			env.t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return w
+}
+
+func testDeadline() string {
+	return time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+}
+
+func (env *testEnv) createTender(name string, extra map[string]interface{}) Tender {
+	env.t.Helper()
+	body := map[string]interface{}{
+		"name":               name,
+		"description":        "test tender",
+		"serviceType":        "Construction",
+		"organizationId":     testBuyerOrg,
+		"submissionDeadline": testDeadline(),
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	var tender Tender
+	env.expect("POST", "/api/tenders/new", keyAlice, body, http.StatusOK, &tender)
+	return tender
+}
+
+func (env *testEnv) publishTender(name string, extra map[string]interface{}) Tender {
+	env.t.Helper()
+	tender := env.createTender(name, extra)
+	env.expect("PUT", "/api/tenders/"+tender.ID.String()+"/status?status=Published", keyAlice, nil, http.StatusOK, &tender)
+	return tender
+}
+
+func (env *testEnv) createBid(tender Tender, extra map[string]interface{}) Bid {
+	env.t.Helper()
+	body := map[string]interface{}{
+		"name":        "bid",
+		"description": "test bid",
+		"tenderId":    tender.ID,
+		"authorType":  "User",
+		"authorId":    testBob,
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	var bid Bid
+	env.expect("POST", "/api/bids/new", keyBob, body, http.StatusOK, &bid)
+	return bid
+}
+
+func (env *testEnv) submitBid(tender Tender, extra map[string]interface{}) Bid {
+	env.t.Helper()
+	bid := env.createBid(tender, extra)
+	env.expect("PUT", "/api/bids/"+bid.ID.String()+"/submit", keyBob, nil, http.StatusOK, &bid)
+	return bid
+}
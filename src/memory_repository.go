@@ -0,0 +1,1569 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type versionKey struct {
+	ID      uuid.UUID
+	Version int
+}
+
+type approvalKey struct {
+	BidID    uuid.UUID
+	LotID    uuid.UUID
+	Username string
+}
+
+type scoreKey struct {
+	BidID       uuid.UUID
+	CriterionID uuid.UUID
+	Username    string
+}
+
+type memoryReview struct {
+	BidReview
+	BidID    uuid.UUID
+	Username string
+}
+
+type membershipKey struct {
+	OrganizationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+type memoryTxKey struct{}
+
+type memoryTx struct {
+	undo []func()
+}
+
+type MemoryStore struct {
+	txMu           sync.Mutex
+	mu             sync.RWMutex
+	employees      map[uuid.UUID]string
+	organizations  map[uuid.UUID]string
+	responsibles   map[membershipKey]string
+	roleChanges    []RoleChange
+	tenders        map[uuid.UUID]Tender
+	tenderVersions map[versionKey]Tender
+	unsealEvents   []UnsealEvent
+	bids           map[uuid.UUID]Bid
+	bidVersions    map[versionKey]Bid
+	lots           map[uuid.UUID]Lot
+	criteria       map[uuid.UUID]Criterion
+	scores         map[scoreKey]BidScore
+	approvals      map[approvalKey]ApprovalVote
+	policies       map[uuid.UUID]ApprovalPolicy
+	reviews        []memoryReview
+	notifications  []Notification
+	contracts      map[uuid.UUID]Contract
+	questions      map[uuid.UUID]Question
+	invitations    map[uuid.UUID]Invitation
+	attachments    map[uuid.UUID]Attachment
+	apiKeys        map[string]APIKey
+}
+
+type memoryTransactor struct{ *MemoryStore }
+
+type memoryTenders struct{ *MemoryStore }
+
+type memoryBids struct{ *MemoryStore }
+
+type memoryLots struct{ *MemoryStore }
+
+type memoryEvaluations struct{ *MemoryStore }
+
+type memoryPolicies struct{ *MemoryStore }
+
+type memoryReviews struct{ *MemoryStore }
+
+type memoryNotifications struct{ *MemoryStore }
+
+type memoryContracts struct{ *MemoryStore }
+
+type memoryQuestions struct{ *MemoryStore }
+
+type memoryInvitations struct{ *MemoryStore }
+
+type memoryAttachments struct{ *MemoryStore }
+
+type memoryOrganizations struct{ *MemoryStore }
+
+type memoryAPIKeys struct{ *MemoryStore }
+
+type MemorySeed struct {
+	Employees []struct {
+		ID       uuid.UUID `json:"id"`
+		Username string    `json:"username"`
+	} `json:"employees"`
+	Organizations []struct {
+		ID   uuid.UUID `json:"id"`
+		Name string    `json:"name"`
+	} `json:"organizations"`
+	Responsibles []struct {
+		OrganizationID uuid.UUID `json:"organizationId"`
+		UserID         uuid.UUID `json:"userId"`
+		Role           string    `json:"role"`
+	} `json:"responsibles"`
+	APIKeys []struct {
+		UserID uuid.UUID `json:"userId"`
+		Key    string    `json:"key"`
+	} `json:"apiKeys"`
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		employees:      map[uuid.UUID]string{},
+		organizations:  map[uuid.UUID]string{},
+		responsibles:   map[membershipKey]string{},
+		tenders:        map[uuid.UUID]Tender{},
+		tenderVersions: map[versionKey]Tender{},
+		bids:           map[uuid.UUID]Bid{},
+		bidVersions:    map[versionKey]Bid{},
+		lots:           map[uuid.UUID]Lot{},
+		criteria:       map[uuid.UUID]Criterion{},
+		scores:         map[scoreKey]BidScore{},
+		approvals:      map[approvalKey]ApprovalVote{},
+		policies:       map[uuid.UUID]ApprovalPolicy{},
+		contracts:      map[uuid.UUID]Contract{},
+		questions:      map[uuid.UUID]Question{},
+		invitations:    map[uuid.UUID]Invitation{},
+		attachments:    map[uuid.UUID]Attachment{},
+		apiKeys:        map[string]APIKey{},
+	}
+}
+
+func (m *MemoryStore) Storage() Storage {
+	return Storage{
+		Tx:            memoryTransactor{m},
+		Tenders:       memoryTenders{m},
+		Bids:          memoryBids{m},
+		Lots:          memoryLots{m},
+		Evaluations:   memoryEvaluations{m},
+		Policies:      memoryPolicies{m},
+		Reviews:       memoryReviews{m},
+		Notifications: memoryNotifications{m},
+		Contracts:     memoryContracts{m},
+		Questions:     memoryQuestions{m},
+		Invitations:   memoryInvitations{m},
+		Attachments:   memoryAttachments{m},
+		Organizations: memoryOrganizations{m},
+		APIKeys:       memoryAPIKeys{m},
+	}
+}
+
+func (m *MemoryStore) AddEmployee(id uuid.UUID, username string) uuid.UUID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	m.employees[id] = username
+	return id
+}
+
+func (m *MemoryStore) AddOrganization(id uuid.UUID, name string) uuid.UUID {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if id == uuid.Nil {
+		id = uuid.New()
+	}
+	m.organizations[id] = name
+	return id
+}
+
+func (m *MemoryStore) AddResponsible(organizationID, userID uuid.UUID) {
+	m.AddMember(organizationID, userID, RoleAdmin)
+}
+
+func (m *MemoryStore) AddMember(organizationID, userID uuid.UUID, role string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.responsibles[membershipKey{organizationID, userID}] = role
+}
+
+func (m *MemoryStore) AddAPIKey(userID uuid.UUID, key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	hash := HashAPIKey(key)
+	m.apiKeys[hash] = APIKey{ID: uuid.New(), EmployeeID: userID, Username: m.employees[userID], Name: "seed", CreatedAt: time.Now()}
+}
+
+func (m *MemoryStore) LoadSeed(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read memory seed: %v", err)
+	}
+	var seed MemorySeed
+	if err := json.Unmarshal(data, &seed); err != nil {
+		return fmt.Errorf("unable to parse memory seed: %v", err)
+	}
+	for _, e := range seed.Employees {
+		m.AddEmployee(e.ID, e.Username)
+	}
+	for _, o := range seed.Organizations {
+		m.AddOrganization(o.ID, o.Name)
+	}
+	for _, r := range seed.Responsibles {
+		if r.Role == "" {
+			r.Role = RoleAdmin
+		}
+		if !IsValidRole(r.Role) {
+			return fmt.Errorf("unknown role %q in memory seed", r.Role)
+		}
+		m.AddMember(r.OrganizationID, r.UserID, r.Role)
+	}
+	for _, k := range seed.APIKeys {
+		m.AddAPIKey(k.UserID, k.Key)
+	}
+	return nil
+}
+
+func (m *MemoryStore) userID(username string) (uuid.UUID, bool) {
+	for id, name := range m.employees {
+		if name == username {
+			return id, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+func (m memoryTransactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		return fn(ctx)
+	}
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+	tx := &memoryTx{}
+	err := fn(context.WithValue(ctx, memoryTxKey{}, tx))
+	if err != nil {
+		m.mu.Lock()
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+		m.mu.Unlock()
+	}
+	return err
+}
+
+func (m *MemoryStore) onRollback(ctx context.Context, undo func()) {
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		tx.undo = append(tx.undo, undo)
+	}
+}
+
+func restore[K comparable, V any](items map[K]V, key K) func() {
+	prev, ok := items[key]
+	return func() {
+		if ok {
+			items[key] = prev
+		} else {
+			delete(items, key)
+		}
+	}
+}
+
+func paginate[T any](items []T, page Page) []T {
+	if page.Offset >= len(items) {
+		return nil
+	}
+	items = items[page.Offset:]
+	if page.Limit > 0 && page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+func compareSortKeys(kind string, a, b *string) int {
+	switch {
+	case a == nil || b == nil:
+		if a == nil && b == nil {
+			return 0
+		}
+		if a == nil {
+			return 1
+		}
+		return -1
+	case kind == sortTime:
+		x, _ := time.Parse(time.RFC3339Nano, *a)
+		y, _ := time.Parse(time.RFC3339Nano, *b)
+		return x.Compare(y)
+	case kind == sortAmount:
+		x, _ := decimal.NewFromString(*a)
+		y, _ := decimal.NewFromString(*b)
+		return x.Cmp(y)
+	}
+	return strings.Compare(*a, *b)
+}
+
+func paginateSorted[T Sortable](items []T, page Page) ([]T, int) {
+	kind := sortKinds[page.Sort]
+	compare := func(key *string, id uuid.UUID, other *string, otherID uuid.UUID) int {
+		c := compareSortKeys(kind, key, other)
+		if page.Desc && key != nil && other != nil {
+			c = -c
+		}
+		if c == 0 {
+			c = strings.Compare(id.String(), otherID.String())
+		}
+		return c
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		key, id := items[i].SortKey(page.Sort)
+		other, otherID := items[j].SortKey(page.Sort)
+		return compare(key, id, other, otherID) < 0
+	})
+	total := len(items)
+	if cursor := page.After; cursor != nil {
+		start := sort.Search(len(items), func(i int) bool {
+			key, id := items[i].SortKey(page.Sort)
+			return compare(key, id, cursor.Key, cursor.ID) > 0
+		})
+		items = items[start:]
+	}
+	return paginate(items, page), total
+}
+
+func cloneTender(tender Tender) Tender {
+	tender.SubmissionDeadline = clonePtr(tender.SubmissionDeadline)
+	tender.PublishAt = clonePtr(tender.PublishAt)
+	tender.Budget = clonePtr(tender.Budget)
+	tender.ReservePrice = clonePtr(tender.ReservePrice)
+	tender.MaxBidAmount = clonePtr(tender.MaxBidAmount)
+	tender.UnsealedAt = clonePtr(tender.UnsealedAt)
+	tender.AuctionStep = clonePtr(tender.AuctionStep)
+	tender.CancelledAt = clonePtr(tender.CancelledAt)
+	return tender
+}
+
+func clonePtr[T any](v *T) *T {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
+
+func cloneBid(bid Bid) Bid {
+	bid.Amount = clonePtr(bid.Amount)
+	bid.LineItems = append([]BidLineItem(nil), bid.LineItems...)
+	bid.LotIDs = append([]uuid.UUID(nil), bid.LotIDs...)
+	bid.WithdrawnAt = clonePtr(bid.WithdrawnAt)
+	return bid
+}
+
+func cloneLot(lot Lot) Lot {
+	lot.Budget = clonePtr(lot.Budget)
+	lot.AwardedBidID = clonePtr(lot.AwardedBidID)
+	return lot
+}
+
+func cloneContract(contract Contract) Contract {
+	contract.LotID = clonePtr(contract.LotID)
+	contract.Amount = clonePtr(contract.Amount)
+	contract.StartDate = clonePtr(contract.StartDate)
+	contract.EndDate = clonePtr(contract.EndDate)
+	contract.BuyerSignedAt = clonePtr(contract.BuyerSignedAt)
+	contract.SupplierSignedAt = clonePtr(contract.SupplierSignedAt)
+	return contract
+}
+
+func cloneQuestion(question Question) Question {
+	question.AnsweredAt = clonePtr(question.AnsweredAt)
+	question.AmendedVersion = clonePtr(question.AmendedVersion)
+	return question
+}
+
+func (m memoryTenders) Create(ctx context.Context, tender *Tender) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tender.ID = uuid.New()
+	tender.Status = "Created"
+	tender.Version = 1
+	tender.CreatedAt = time.Now()
+	tender.ModifiedBy = tender.CreatorUsername
+	tender.ModifiedAt = tender.CreatedAt
+	m.onRollback(ctx, restore(m.tenders, tender.ID))
+	m.tenders[tender.ID] = cloneTender(*tender)
+	return nil
+}
+
+func (m memoryTenders) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.tenders[id]
+	return ok, nil
+}
+
+func (m memoryTenders) Get(ctx context.Context, id uuid.UUID) (Tender, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tender, ok := m.tenders[id]
+	if !ok {
+		return tender, ErrNotFound
+	}
+	return cloneTender(tender), nil
+}
+
+func (m memoryTenders) GetForUpdate(ctx context.Context, id uuid.UUID) (Tender, error) {
+	return m.Get(ctx, id)
+}
+
+func (m memoryTenders) ListPublished(ctx context.Context, serviceType string, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]Tender, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tenders []Tender
+	for _, tender := range m.tenders {
+		if tender.Private && !m.invited(tender, viewerID, viewerOrganizationID) {
+			continue
+		}
+		if tender.Status == "Published" && (serviceType == "" || tender.ServiceType == serviceType) {
+			tenders = append(tenders, tender)
+		}
+	}
+	tenders, total := paginateSorted(tenders, page)
+	return tenders, total, nil
+}
+
+func (m memoryTenders) Search(ctx context.Context, filter SearchFilter, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]TenderHit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var hits []TenderHit
+	for _, tender := range m.tenders {
+		if !m.tenderVisible(tender, viewerID, viewerOrganizationID) {
+			continue
+		}
+		if !filter.Matches(tender.Status, tender.ServiceType, tender.OrganizationID, tender.CreatedAt, tender.Budget) {
+			continue
+		}
+		if rank, snippet, ok := filter.MatchText(tender.Name, tender.Description); ok {
+			hits = append(hits, TenderHit{cloneTender(tender), snippet, rank})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Name < hits[j].Name
+	})
+	return paginate(hits, page), nil
+}
+
+func (m *MemoryStore) tenderVisible(tender Tender, userID, organizationID uuid.UUID) bool {
+	if tender.Status == "Published" && (!tender.Private || m.invited(tender, userID, organizationID)) {
+		return true
+	}
+	return organizationID != uuid.Nil && tender.OrganizationID == organizationID
+}
+
+func (m *MemoryStore) invited(tender Tender, userID, organizationID uuid.UUID) bool {
+	if organizationID != uuid.Nil && tender.OrganizationID == organizationID {
+		return true
+	}
+	for _, invitation := range m.invitations {
+		if invitation.TenderID == tender.ID && invitation.Status != InvitationDeclined && invitation.invites(userID, organizationID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m memoryTenders) ListByCreator(ctx context.Context, username string, page Page) ([]Tender, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var tenders []Tender
+	for _, tender := range m.tenders {
+		if tender.CreatorUsername == username {
+			tenders = append(tenders, tender)
+		}
+	}
+	tenders, total := paginateSorted(tenders, page)
+	return tenders, total, nil
+}
+
+func (m memoryTenders) ListDueForPublish(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var ids []uuid.UUID
+	for _, tender := range m.tenders {
+		if tender.Status == "Created" && tender.PublishAt != nil && !tender.PublishAt.After(now) {
+			ids = append(ids, tender.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m memoryTenders) ListDueForClose(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var ids []uuid.UUID
+	for _, tender := range m.tenders {
+		if tender.Status == "Published" && tender.SubmissionDeadline != nil && !tender.SubmissionDeadline.After(now) {
+			ids = append(ids, tender.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m memoryTenders) Update(ctx context.Context, tender *Tender) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.tenders[tender.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Name = tender.Name
+	stored.Description = tender.Description
+	stored.ServiceType = tender.ServiceType
+	stored.Status = tender.Status
+	stored.SubmissionDeadline = clonePtr(tender.SubmissionDeadline)
+	stored.PublishAt = clonePtr(tender.PublishAt)
+	stored.Budget = clonePtr(tender.Budget)
+	stored.ReservePrice = clonePtr(tender.ReservePrice)
+	stored.MaxBidAmount = clonePtr(tender.MaxBidAmount)
+	stored.Currency = tender.Currency
+	stored.RejectAboveMax = tender.RejectAboveMax
+	stored.Sealed = tender.Sealed
+	stored.UnsealedAt = clonePtr(tender.UnsealedAt)
+	stored.Mode = tender.Mode
+	stored.AuctionStep = clonePtr(tender.AuctionStep)
+	stored.AuctionExtension = tender.AuctionExtension
+	stored.RequireScoring = tender.RequireScoring
+	stored.Private = tender.Private
+	stored.CancellationReason = tender.CancellationReason
+	stored.CancelledAt = clonePtr(tender.CancelledAt)
+	stored.Version = tender.Version
+	stored.ModifiedBy = tender.ModifiedBy
+	stored.ModifiedAt = time.Now()
+	tender.ModifiedAt = stored.ModifiedAt
+	m.onRollback(ctx, restore(m.tenders, tender.ID))
+	m.tenders[tender.ID] = stored
+	return nil
+}
+
+func (m memoryTenders) AddVersion(ctx context.Context, tender Tender) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := versionKey{tender.ID, tender.Version}
+	m.onRollback(ctx, restore(m.tenderVersions, key))
+	m.tenderVersions[key] = cloneTender(tender)
+	return nil
+}
+
+func (m memoryTenders) VersionExists(ctx context.Context, id uuid.UUID, version int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.tenderVersions[versionKey{id, version}]
+	return ok, nil
+}
+
+func (m memoryTenders) GetVersion(ctx context.Context, id uuid.UUID, version int) (Tender, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tender, ok := m.tenderVersions[versionKey{id, version}]
+	if !ok {
+		return tender, ErrNotFound
+	}
+	return cloneTender(tender), nil
+}
+
+func (m memoryTenders) ListVersions(ctx context.Context, id uuid.UUID) ([]Tender, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var versions []Tender
+	for key, tender := range m.tenderVersions {
+		if key.ID == id {
+			versions = append(versions, tender)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+func (m memoryTenders) AddUnsealEvent(ctx context.Context, event *UnsealEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	event.ID = uuid.New()
+	event.CreatedAt = time.Now()
+	n := len(m.unsealEvents)
+	m.onRollback(ctx, func() { m.unsealEvents = m.unsealEvents[:n] })
+	m.unsealEvents = append(m.unsealEvents, *event)
+	return nil
+}
+
+func (m memoryTenders) GetUnsealEvent(ctx context.Context, tenderID uuid.UUID) (UnsealEvent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.unsealEvents) - 1; i >= 0; i-- {
+		if m.unsealEvents[i].TenderID == tenderID {
+			return m.unsealEvents[i], nil
+		}
+	}
+	return UnsealEvent{}, ErrNotFound
+}
+
+func (m memoryBids) Create(ctx context.Context, bid *Bid) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bid.ID = uuid.New()
+	bid.Status = "Created"
+	bid.Version = 1
+	bid.Decision = "None"
+	bid.ApprovedCount = 0
+	bid.CreatedAt = time.Now()
+	bid.ModifiedAt = bid.CreatedAt
+	m.onRollback(ctx, restore(m.bids, bid.ID))
+	m.bids[bid.ID] = cloneBid(*bid)
+	return nil
+}
+
+func (m memoryBids) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.bids[id]
+	return ok, nil
+}
+
+func (m memoryBids) Get(ctx context.Context, id uuid.UUID) (Bid, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	bid, ok := m.bids[id]
+	if !ok {
+		return bid, ErrNotFound
+	}
+	return cloneBid(bid), nil
+}
+
+func (m memoryBids) GetForUpdate(ctx context.Context, id uuid.UUID) (Bid, error) {
+	return m.Get(ctx, id)
+}
+
+func (m memoryBids) ListByAuthor(ctx context.Context, authorID uuid.UUID, page Page) ([]Bid, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var bids []Bid
+	for _, bid := range m.bids {
+		if bid.AuthorID == authorID {
+			bids = append(bids, bid)
+		}
+	}
+	bids, total := paginateSorted(bids, page)
+	return bids, total, nil
+}
+
+func (m memoryBids) ListForTender(ctx context.Context, tenderID, organizationID uuid.UUID, filter BidFilter, page Page) ([]Bid, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var bids []Bid
+	for _, bid := range m.bids {
+		if bid.TenderID == tenderID && (bid.Status == "Published" || bid.OrganizationID == organizationID) && filter.Matches(bid) {
+			bids = append(bids, bid)
+		}
+	}
+	bids, total := paginateSorted(bids, page)
+	return bids, total, nil
+}
+
+func (m memoryBids) Search(ctx context.Context, filter SearchFilter, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]BidHit, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var hits []BidHit
+	for _, bid := range m.bids {
+		if bid.Sealed {
+			continue
+		}
+		tender := m.tenders[bid.TenderID]
+		own := bid.AuthorID == viewerID || viewerOrganizationID != uuid.Nil && (bid.AuthorID == viewerOrganizationID || bid.OrganizationID == viewerOrganizationID)
+		if !own && (bid.Status != "Published" || !m.tenderVisible(tender, viewerID, viewerOrganizationID)) {
+			continue
+		}
+		if !filter.Matches(bid.Status, tender.ServiceType, bid.OrganizationID, bid.CreatedAt, bid.Amount) {
+			continue
+		}
+		if rank, snippet, ok := filter.MatchText(bid.Name, bid.Description); ok {
+			hits = append(hits, BidHit{cloneBid(bid), snippet, rank})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].Name < hits[j].Name
+	})
+	return paginate(hits, page), nil
+}
+
+func (m memoryBids) ListSealed(ctx context.Context, tenderID uuid.UUID) ([]Bid, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var bids []Bid
+	for _, bid := range m.bids {
+		if bid.TenderID == tenderID && bid.Sealed {
+			bids = append(bids, cloneBid(bid))
+		}
+	}
+	return bids, nil
+}
+
+func (m memoryBids) ListRanked(ctx context.Context, tenderID uuid.UUID) ([]Bid, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var bids []Bid
+	for _, bid := range m.bids {
+		if bid.TenderID == tenderID && bid.Amount != nil && bid.Status == BidSubmitted && bid.Decision == "None" {
+			bids = append(bids, cloneBid(bid))
+		}
+	}
+	sort.SliceStable(bids, func(i, j int) bool {
+		if !bids[i].Amount.Equal(*bids[j].Amount) {
+			return bids[i].Amount.LessThan(*bids[j].Amount)
+		}
+		return bids[i].ModifiedAt.Before(bids[j].ModifiedAt)
+	})
+	return bids, nil
+}
+
+func (m memoryBids) CountForTender(ctx context.Context, tenderID uuid.UUID) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, bid := range m.bids {
+		if bid.TenderID == tenderID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m memoryBids) Update(ctx context.Context, bid *Bid) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.bids[bid.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Name = bid.Name
+	stored.Description = bid.Description
+	stored.Status = bid.Status
+	stored.Decision = bid.Decision
+	stored.ApprovedCount = bid.ApprovedCount
+	priced := cloneBid(*bid)
+	stored.Amount = priced.Amount
+	stored.Currency = priced.Currency
+	stored.LineItems = priced.LineItems
+	stored.Sealed = bid.Sealed
+	stored.SealedData = bid.SealedData
+	stored.WithdrawalReason = bid.WithdrawalReason
+	stored.WithdrawnAt = priced.WithdrawnAt
+	stored.Version = bid.Version
+	stored.ModifiedBy = bid.ModifiedBy
+	stored.ModifiedAt = time.Now()
+	bid.ModifiedAt = stored.ModifiedAt
+	m.onRollback(ctx, restore(m.bids, bid.ID))
+	m.bids[bid.ID] = stored
+	return nil
+}
+
+func (m memoryBids) AddVersion(ctx context.Context, bid Bid) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := versionKey{bid.ID, bid.Version}
+	m.onRollback(ctx, restore(m.bidVersions, key))
+	m.bidVersions[key] = cloneBid(bid)
+	return nil
+}
+
+func (m memoryBids) VersionExists(ctx context.Context, id uuid.UUID, version int) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.bidVersions[versionKey{id, version}]
+	return ok, nil
+}
+
+func (m memoryBids) GetVersion(ctx context.Context, id uuid.UUID, version int) (Bid, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	bid, ok := m.bidVersions[versionKey{id, version}]
+	if !ok {
+		return bid, ErrNotFound
+	}
+	return cloneBid(bid), nil
+}
+
+func (m memoryBids) ListVersions(ctx context.Context, id uuid.UUID) ([]Bid, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var versions []Bid
+	for key, bid := range m.bidVersions {
+		if key.ID == id {
+			versions = append(versions, bid)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+func (m memoryBids) VoteExists(ctx context.Context, bidID, lotID uuid.UUID, username string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.approvals[approvalKey{bidID, lotID, username}]
+	return ok, nil
+}
+
+func (m memoryBids) AddVote(ctx context.Context, vote *ApprovalVote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vote.CreatedAt = time.Now()
+	key := approvalKey{vote.BidID, vote.LotID, vote.Username}
+	m.onRollback(ctx, restore(m.approvals, key))
+	m.approvals[key] = *vote
+	return nil
+}
+
+func (m memoryBids) ListVotes(ctx context.Context, bidID, lotID uuid.UUID) ([]ApprovalVote, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var votes []ApprovalVote
+	for key, vote := range m.approvals {
+		if key.BidID == bidID && key.LotID == lotID {
+			votes = append(votes, vote)
+		}
+	}
+	sort.Slice(votes, func(i, j int) bool { return votes[i].CreatedAt.Before(votes[j].CreatedAt) })
+	return votes, nil
+}
+
+func (m memoryLots) Create(ctx context.Context, lot *Lot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lot.ID = uuid.New()
+	lot.Status = LotOpen
+	lot.AwardedBidID = nil
+	lot.CreatedAt = time.Now()
+	m.onRollback(ctx, restore(m.lots, lot.ID))
+	m.lots[lot.ID] = cloneLot(*lot)
+	return nil
+}
+
+func (m memoryLots) Get(ctx context.Context, id uuid.UUID) (Lot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lot, ok := m.lots[id]
+	if !ok {
+		return lot, ErrNotFound
+	}
+	return cloneLot(lot), nil
+}
+
+func (m memoryLots) GetForUpdate(ctx context.Context, id uuid.UUID) (Lot, error) {
+	return m.Get(ctx, id)
+}
+
+func (m memoryLots) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Lot, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var lots []Lot
+	for _, lot := range m.lots {
+		if lot.TenderID == tenderID {
+			lots = append(lots, cloneLot(lot))
+		}
+	}
+	sort.Slice(lots, func(i, j int) bool { return lots[i].CreatedAt.Before(lots[j].CreatedAt) })
+	return lots, nil
+}
+
+func (m memoryLots) Update(ctx context.Context, lot *Lot) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.lots[lot.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := cloneLot(*lot)
+	stored.Name = updated.Name
+	stored.Description = updated.Description
+	stored.Budget = updated.Budget
+	stored.Status = updated.Status
+	stored.AwardedBidID = updated.AwardedBidID
+	m.onRollback(ctx, restore(m.lots, lot.ID))
+	m.lots[lot.ID] = stored
+	return nil
+}
+
+func (m memoryEvaluations) CreateCriterion(ctx context.Context, criterion *Criterion) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	criterion.ID = uuid.New()
+	criterion.CreatedAt = time.Now()
+	m.onRollback(ctx, restore(m.criteria, criterion.ID))
+	m.criteria[criterion.ID] = *criterion
+	return nil
+}
+
+func (m memoryEvaluations) GetCriterion(ctx context.Context, id uuid.UUID) (Criterion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	criterion, ok := m.criteria[id]
+	if !ok {
+		return criterion, ErrNotFound
+	}
+	return criterion, nil
+}
+
+func (m memoryEvaluations) ListCriteria(ctx context.Context, tenderID uuid.UUID) ([]Criterion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var criteria []Criterion
+	for _, criterion := range m.criteria {
+		if criterion.TenderID == tenderID {
+			criteria = append(criteria, criterion)
+		}
+	}
+	sort.Slice(criteria, func(i, j int) bool { return criteria[i].CreatedAt.Before(criteria[j].CreatedAt) })
+	return criteria, nil
+}
+
+func (m memoryEvaluations) DeleteCriterion(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.criteria[id]; !ok {
+		return ErrNotFound
+	}
+	for key := range m.scores {
+		if key.CriterionID == id {
+			m.onRollback(ctx, restore(m.scores, key))
+			delete(m.scores, key)
+		}
+	}
+	m.onRollback(ctx, restore(m.criteria, id))
+	delete(m.criteria, id)
+	return nil
+}
+
+func (m memoryEvaluations) TenderHasScores(ctx context.Context, tenderID uuid.UUID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for key := range m.scores {
+		if m.criteria[key.CriterionID].TenderID == tenderID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m memoryEvaluations) SetScore(ctx context.Context, score *BidScore) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	score.UpdatedAt = time.Now()
+	key := scoreKey{score.BidID, score.CriterionID, score.Username}
+	m.onRollback(ctx, restore(m.scores, key))
+	m.scores[key] = *score
+	return nil
+}
+
+func (m *MemoryStore) listScores(match func(score BidScore) bool) []BidScore {
+	var scores []BidScore
+	for _, score := range m.scores {
+		if match(score) {
+			scores = append(scores, score)
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if a.BidID != b.BidID {
+			return a.BidID.String() < b.BidID.String()
+		}
+		if ca, cb := m.criteria[a.CriterionID].CreatedAt, m.criteria[b.CriterionID].CreatedAt; !ca.Equal(cb) {
+			return ca.Before(cb)
+		}
+		return a.Username < b.Username
+	})
+	return scores
+}
+
+func (m memoryEvaluations) ListScores(ctx context.Context, tenderID uuid.UUID) ([]BidScore, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.listScores(func(score BidScore) bool { return m.criteria[score.CriterionID].TenderID == tenderID }), nil
+}
+
+func (m memoryEvaluations) ListBidScores(ctx context.Context, bidID uuid.UUID) ([]BidScore, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.listScores(func(score BidScore) bool { return score.BidID == bidID }), nil
+}
+
+func (m *MemoryStore) getPolicy(id uuid.UUID) (ApprovalPolicy, error) {
+	policy, ok := m.policies[id]
+	if !ok {
+		return policy, ErrNotFound
+	}
+	policy.MandatoryApprovers = append([]string(nil), policy.MandatoryApprovers...)
+	return policy, nil
+}
+
+func (m *MemoryStore) setPolicy(ctx context.Context, id uuid.UUID, policy *ApprovalPolicy) {
+	now := time.Now()
+	policy.UpdatedAt = &now
+	stored := *policy
+	stored.MandatoryApprovers = append([]string(nil), policy.MandatoryApprovers...)
+	m.onRollback(ctx, restore(m.policies, id))
+	m.policies[id] = stored
+}
+
+func (m memoryPolicies) GetOrganizationPolicy(ctx context.Context, organizationID uuid.UUID) (ApprovalPolicy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.getPolicy(organizationID)
+}
+
+func (m memoryPolicies) SetOrganizationPolicy(ctx context.Context, organizationID uuid.UUID, policy *ApprovalPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setPolicy(ctx, organizationID, policy)
+	return nil
+}
+
+func (m memoryPolicies) GetTenderPolicy(ctx context.Context, tenderID uuid.UUID) (ApprovalPolicy, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.getPolicy(tenderID)
+}
+
+func (m memoryPolicies) SetTenderPolicy(ctx context.Context, tenderID uuid.UUID, policy *ApprovalPolicy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setPolicy(ctx, tenderID, policy)
+	return nil
+}
+
+func (m memoryPolicies) DeleteTenderPolicy(ctx context.Context, tenderID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.policies[tenderID]; !ok {
+		return ErrNotFound
+	}
+	m.onRollback(ctx, restore(m.policies, tenderID))
+	delete(m.policies, tenderID)
+	return nil
+}
+
+func (m memoryReviews) Create(ctx context.Context, bidID uuid.UUID, username, text string) (BidReview, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	review := BidReview{ID: uuid.New(), Description: text, CreatedAt: time.Now()}
+	n := len(m.reviews)
+	m.onRollback(ctx, func() { m.reviews = m.reviews[:n] })
+	m.reviews = append(m.reviews, memoryReview{review, bidID, username})
+	return review, nil
+}
+
+func (m memoryReviews) Get(ctx context.Context, id uuid.UUID) (BidReview, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, review := range m.reviews {
+		if review.ID == id {
+			review.BidReview.BidID, review.BidReview.Username = review.BidID, review.Username
+			return review.BidReview, nil
+		}
+	}
+	return BidReview{}, ErrNotFound
+}
+
+func (m memoryReviews) ListByAuthor(ctx context.Context, authorID, excludeTenderID uuid.UUID, page Page) ([]BidReview, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var reviews []BidReview
+	for _, review := range m.reviews {
+		bid, ok := m.bids[review.BidID]
+		if ok && bid.AuthorID == authorID && bid.AuthorType == "User" && bid.TenderID != excludeTenderID {
+			reviews = append(reviews, review.BidReview)
+		}
+	}
+	reviews, total := paginateSorted(reviews, page)
+	return reviews, total, nil
+}
+
+func (m memoryNotifications) Create(ctx context.Context, notification *Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	notification.ID = uuid.New()
+	notification.CreatedAt = time.Now()
+	n := len(m.notifications)
+	m.onRollback(ctx, func() { m.notifications = m.notifications[:n] })
+	m.notifications = append(m.notifications, *notification)
+	return nil
+}
+
+func (m memoryNotifications) ListForRecipient(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Notification, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var notifications []Notification
+	for i := len(m.notifications) - 1; i >= 0; i-- {
+		notification := m.notifications[i]
+		if notification.RecipientType == "User" && notification.RecipientID == userID ||
+			notification.RecipientType == "Organization" && organizationID != uuid.Nil && notification.RecipientID == organizationID {
+			notifications = append(notifications, notification)
+		}
+	}
+	notifications, total := paginateSorted(notifications, page)
+	return notifications, total, nil
+}
+
+func (m memoryContracts) Create(ctx context.Context, contract *Contract) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	contract.ID = uuid.New()
+	contract.Version = 1
+	contract.CreatedAt = time.Now()
+	contract.UpdatedAt = contract.CreatedAt
+	m.onRollback(ctx, restore(m.contracts, contract.ID))
+	m.contracts[contract.ID] = cloneContract(*contract)
+	return nil
+}
+
+func (m memoryContracts) Get(ctx context.Context, id uuid.UUID) (Contract, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	contract, ok := m.contracts[id]
+	if !ok {
+		return contract, ErrNotFound
+	}
+	return cloneContract(contract), nil
+}
+
+func (m memoryContracts) GetForUpdate(ctx context.Context, id uuid.UUID) (Contract, error) {
+	return m.Get(ctx, id)
+}
+
+func (m memoryContracts) list(match func(Contract) bool) []Contract {
+	var contracts []Contract
+	for _, contract := range m.contracts {
+		if match(contract) {
+			contracts = append(contracts, cloneContract(contract))
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool { return contracts[i].CreatedAt.After(contracts[j].CreatedAt) })
+	return contracts
+}
+
+func (m memoryContracts) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Contract, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.list(func(contract Contract) bool { return contract.TenderID == tenderID }), nil
+}
+
+func (m memoryContracts) ListForParty(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Contract, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	contracts := m.list(func(contract Contract) bool {
+		if contract.SupplierType == "User" && contract.SupplierID == userID {
+			return true
+		}
+		return organizationID != uuid.Nil && (contract.BuyerID == organizationID ||
+			contract.SupplierType == "Organization" && contract.SupplierID == organizationID)
+	})
+	contracts, total := paginateSorted(contracts, page)
+	return contracts, total, nil
+}
+
+func (m memoryContracts) Update(ctx context.Context, contract *Contract) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.contracts[contract.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	contract.UpdatedAt = time.Now()
+	updated := cloneContract(*contract)
+	updated.ID, updated.TenderID, updated.BidID, updated.LotID = stored.ID, stored.TenderID, stored.BidID, stored.LotID
+	updated.BuyerID, updated.SupplierType, updated.SupplierID = stored.BuyerID, stored.SupplierType, stored.SupplierID
+	updated.CreatedAt = stored.CreatedAt
+	m.onRollback(ctx, restore(m.contracts, contract.ID))
+	m.contracts[contract.ID] = updated
+	return nil
+}
+
+func (m memoryQuestions) Create(ctx context.Context, question *Question) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	question.ID = uuid.New()
+	question.Answer, question.AnsweredBy, question.AnsweredAt, question.AmendedVersion = "", "", nil, nil
+	question.CreatedAt = time.Now()
+	m.onRollback(ctx, restore(m.questions, question.ID))
+	m.questions[question.ID] = cloneQuestion(*question)
+	return nil
+}
+
+func (m memoryQuestions) Get(ctx context.Context, id uuid.UUID) (Question, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	question, ok := m.questions[id]
+	if !ok {
+		return question, ErrNotFound
+	}
+	return cloneQuestion(question), nil
+}
+
+func (m memoryQuestions) GetForUpdate(ctx context.Context, id uuid.UUID) (Question, error) {
+	return m.Get(ctx, id)
+}
+
+func (m memoryQuestions) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Question, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var questions []Question
+	for _, question := range m.questions {
+		if question.TenderID == tenderID {
+			questions = append(questions, cloneQuestion(question))
+		}
+	}
+	sort.Slice(questions, func(i, j int) bool { return questions[i].CreatedAt.Before(questions[j].CreatedAt) })
+	return questions, nil
+}
+
+func (m memoryQuestions) Update(ctx context.Context, question *Question) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.questions[question.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := cloneQuestion(*question)
+	stored.Anonymous = updated.Anonymous
+	stored.Answer, stored.AnsweredBy, stored.AnsweredAt = updated.Answer, updated.AnsweredBy, updated.AnsweredAt
+	stored.AmendedVersion = updated.AmendedVersion
+	m.onRollback(ctx, restore(m.questions, question.ID))
+	m.questions[question.ID] = stored
+	return nil
+}
+
+func (m memoryInvitations) Create(ctx context.Context, invitation *Invitation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	invitation.ID = uuid.New()
+	invitation.Status = InvitationPending
+	invitation.RespondedAt = nil
+	invitation.CreatedAt = time.Now()
+	m.onRollback(ctx, restore(m.invitations, invitation.ID))
+	m.invitations[invitation.ID] = *invitation
+	return nil
+}
+
+func (m memoryInvitations) Get(ctx context.Context, id uuid.UUID) (Invitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	invitation, ok := m.invitations[id]
+	if !ok {
+		return invitation, ErrNotFound
+	}
+	invitation.RespondedAt = clonePtr(invitation.RespondedAt)
+	return invitation, nil
+}
+
+func (m memoryInvitations) Find(ctx context.Context, tenderID uuid.UUID, inviteeType string, inviteeID uuid.UUID) (Invitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, invitation := range m.invitations {
+		if invitation.TenderID == tenderID && invitation.InviteeType == inviteeType && invitation.InviteeID == inviteeID {
+			invitation.RespondedAt = clonePtr(invitation.RespondedAt)
+			return invitation, nil
+		}
+	}
+	return Invitation{}, ErrNotFound
+}
+
+func (m memoryInvitations) list(match func(Invitation) bool) []Invitation {
+	var invitations []Invitation
+	for _, invitation := range m.invitations {
+		if match(invitation) {
+			invitation.RespondedAt = clonePtr(invitation.RespondedAt)
+			invitations = append(invitations, invitation)
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].CreatedAt.After(invitations[j].CreatedAt) })
+	return invitations
+}
+
+func (m memoryInvitations) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Invitation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.list(func(invitation Invitation) bool { return invitation.TenderID == tenderID }), nil
+}
+
+func (m memoryInvitations) ListForInvitee(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Invitation, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	invitations := m.list(func(invitation Invitation) bool { return invitation.invites(userID, organizationID) })
+	invitations, total := paginateSorted(invitations, page)
+	return invitations, total, nil
+}
+
+func (m memoryInvitations) Update(ctx context.Context, invitation *Invitation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.invitations[invitation.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Status = invitation.Status
+	stored.RespondedAt = clonePtr(invitation.RespondedAt)
+	m.onRollback(ctx, restore(m.invitations, invitation.ID))
+	m.invitations[invitation.ID] = stored
+	return nil
+}
+
+func (m memoryInvitations) Delete(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invitations[id]; !ok {
+		return ErrNotFound
+	}
+	m.onRollback(ctx, restore(m.invitations, id))
+	delete(m.invitations, id)
+	return nil
+}
+
+func (m memoryAttachments) Create(ctx context.Context, attachment *Attachment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attachment.ID = uuid.New()
+	attachment.CreatedAt = time.Now()
+	m.onRollback(ctx, restore(m.attachments, attachment.ID))
+	m.attachments[attachment.ID] = *attachment
+	return nil
+}
+
+func (m memoryAttachments) Get(ctx context.Context, id uuid.UUID) (Attachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	attachment, ok := m.attachments[id]
+	if !ok {
+		return attachment, ErrNotFound
+	}
+	attachment.RemovedIn = clonePtr(attachment.RemovedIn)
+	return attachment, nil
+}
+
+func (m memoryAttachments) ListActive(ctx context.Context, parentType string, parentID uuid.UUID, version int) ([]Attachment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var attachments []Attachment
+	for _, attachment := range m.attachments {
+		if attachment.ParentType != parentType || attachment.ParentID != parentID {
+			continue
+		}
+		if attachment.AddedIn <= version && (attachment.RemovedIn == nil || *attachment.RemovedIn > version) {
+			attachment.RemovedIn = clonePtr(attachment.RemovedIn)
+			attachments = append(attachments, attachment)
+		}
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].CreatedAt.Before(attachments[j].CreatedAt) })
+	return attachments, nil
+}
+
+func (m memoryAttachments) Remove(ctx context.Context, id uuid.UUID, version int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attachment, ok := m.attachments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	m.onRollback(ctx, restore(m.attachments, id))
+	attachment.RemovedIn = &version
+	m.attachments[id] = attachment
+	return nil
+}
+
+func (m memoryOrganizations) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.organizations[id]
+	return ok, nil
+}
+
+func (m memoryOrganizations) UserExists(ctx context.Context, username string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.userID(username)
+	return ok, nil
+}
+
+func (m memoryOrganizations) GetUserID(ctx context.Context, username string) (uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	id, ok := m.userID(username)
+	if !ok {
+		return id, ErrNotFound
+	}
+	return id, nil
+}
+
+func (m memoryOrganizations) GetUsername(ctx context.Context, userID uuid.UUID) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	username, ok := m.employees[userID]
+	if !ok {
+		return username, ErrNotFound
+	}
+	return username, nil
+}
+
+func (m memoryOrganizations) GetUserOrganization(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for key := range m.responsibles {
+		if key.UserID == userID {
+			return key.OrganizationID, nil
+		}
+	}
+	return uuid.Nil, ErrNotFound
+}
+
+func (m memoryOrganizations) Lock(ctx context.Context, id uuid.UUID) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if _, ok := m.organizations[id]; !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (m memoryOrganizations) GetRole(ctx context.Context, organizationID uuid.UUID, username string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	userID, ok := m.userID(username)
+	if !ok {
+		return "", ErrNotFound
+	}
+	role, ok := m.responsibles[membershipKey{organizationID, userID}]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return role, nil
+}
+
+func (m *MemoryStore) countMembers(organizationID uuid.UUID, roles ...string) int {
+	count := 0
+	for key, role := range m.responsibles {
+		if key.OrganizationID != organizationID {
+			continue
+		}
+		for _, r := range roles {
+			if role == r {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (m memoryOrganizations) CountAdmins(ctx context.Context, organizationID uuid.UUID) (int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.countMembers(organizationID, RoleAdmin), nil
+}
+
+func (m memoryOrganizations) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]Membership, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var members []Membership
+	for key, role := range m.responsibles {
+		if key.OrganizationID == organizationID {
+			members = append(members, Membership{key.OrganizationID, key.UserID, m.employees[key.UserID], role})
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Username < members[j].Username })
+	return members, nil
+}
+
+func (m memoryOrganizations) SetRole(ctx context.Context, organizationID, userID uuid.UUID, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := membershipKey{organizationID, userID}
+	m.onRollback(ctx, restore(m.responsibles, key))
+	m.responsibles[key] = role
+	return nil
+}
+
+func (m memoryOrganizations) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := membershipKey{organizationID, userID}
+	if _, ok := m.responsibles[key]; !ok {
+		return ErrNotFound
+	}
+	m.onRollback(ctx, restore(m.responsibles, key))
+	delete(m.responsibles, key)
+	return nil
+}
+
+func (m memoryOrganizations) AddRoleChange(ctx context.Context, change *RoleChange) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	change.ID = uuid.New()
+	change.CreatedAt = time.Now()
+	n := len(m.roleChanges)
+	m.onRollback(ctx, func() { m.roleChanges = m.roleChanges[:n] })
+	m.roleChanges = append(m.roleChanges, *change)
+	return nil
+}
+
+func (m memoryOrganizations) ListRoleChanges(ctx context.Context, organizationID uuid.UUID, page Page) ([]RoleChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var changes []RoleChange
+	for i := len(m.roleChanges) - 1; i >= 0; i-- {
+		if m.roleChanges[i].OrganizationID == organizationID {
+			changes = append(changes, m.roleChanges[i])
+		}
+	}
+	return paginate(changes, page), nil
+}
+
+func (m memoryAPIKeys) Create(ctx context.Context, employeeID uuid.UUID, name, hash string) (APIKey, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	username, ok := m.employees[employeeID]
+	if !ok {
+		return APIKey{}, ErrNotFound
+	}
+	key := APIKey{ID: uuid.New(), EmployeeID: employeeID, Username: username, Name: name, CreatedAt: time.Now()}
+	m.onRollback(ctx, restore(m.apiKeys, hash))
+	m.apiKeys[hash] = key
+	return key, nil
+}
+
+func (m memoryAPIKeys) FindByHash(ctx context.Context, hash string) (APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key, ok := m.apiKeys[hash]
+	if !ok {
+		return key, ErrNotFound
+	}
+	return key, nil
+}
+
+func (m memoryAPIKeys) Revoke(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for hash, key := range m.apiKeys {
+		if key.ID == id {
+			m.onRollback(ctx, restore(m.apiKeys, hash))
+			delete(m.apiKeys, hash)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var errTestAbort = errors.New("abort")
+
+func TestMemoryTenderCreateAndUpdate(t *testing.T) {
+	store := NewMemoryStore().Storage()
+	ctx := context.Background()
+	tender := Tender{Name: "Road", Description: "d", ServiceType: "Construction", OrganizationID: uuid.New(), CreatorUsername: "alice"}
+	if err := store.Tenders.Create(ctx, &tender); err != nil {
+		t.Fatal(err)
+	}
+	if tender.Status != "Created" || tender.Version != 1 {
+		t.Fatalf("got status %s version %d", tender.Status, tender.Version)
+	}
+	if err := store.Tenders.AddVersion(ctx, tender); err != nil {
+		t.Fatal(err)
+	}
+	tender.Name = "Bridge"
+	tender.Version++
+	if err := store.Tenders.Update(ctx, &tender); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Tenders.Get(ctx, tender.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Bridge" || got.Version != 2 {
+		t.Fatalf("got %s version %d", got.Name, got.Version)
+	}
+	old, err := store.Tenders.GetVersion(ctx, tender.ID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.Name != "Road" {
+		t.Fatalf("version 1 has name %s", old.Name)
+	}
+	if _, err := store.Tenders.Get(ctx, uuid.New()); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryListPublishedSortsAndPaginates(t *testing.T) {
+	store := NewMemoryStore().Storage()
+	ctx := context.Background()
+	for _, name := range []string{"c", "a", "d", "b"} {
+		tender := Tender{Name: name, ServiceType: "Construction", CreatorUsername: "alice"}
+		if err := store.Tenders.Create(ctx, &tender); err != nil {
+			t.Fatal(err)
+		}
+		if name != "d" {
+			tender.Status = "Published"
+			if err := store.Tenders.Update(ctx, &tender); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tenders, total, err := store.Tenders.ListPublished(ctx, "Construction", uuid.Nil, uuid.Nil, Page{Limit: 2, Offset: 1, Sort: "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenders) != 2 || tenders[0].Name != "b" || tenders[1].Name != "c" || total != 3 {
+		t.Fatalf("got %+v of %d", tenders, total)
+	}
+	if tenders, _, _ := store.Tenders.ListPublished(ctx, "Delivery", uuid.Nil, uuid.Nil, Page{}); len(tenders) != 0 {
+		t.Fatalf("service type filter ignored: %+v", tenders)
+	}
+}
+
+func TestMemoryBidVotes(t *testing.T) {
+	store := NewMemoryStore().Storage()
+	ctx := context.Background()
+	bid := Bid{Name: "b", TenderID: uuid.New(), AuthorType: "User", AuthorID: uuid.New()}
+	if err := store.Bids.Create(ctx, &bid); err != nil {
+		t.Fatal(err)
+	}
+	if bid.Status != "Created" || bid.Decision != "None" {
+		t.Fatalf("got status %s decision %s", bid.Status, bid.Decision)
+	}
+	lotID := uuid.New()
+	for _, vote := range []ApprovalVote{
+		{BidID: bid.ID, Username: "alice", Decision: "Approved"},
+		{BidID: bid.ID, Username: "dave", Decision: "Rejected"},
+		{BidID: bid.ID, LotID: lotID, Username: "alice", Decision: "Rejected"},
+	} {
+		if err := store.Bids.AddVote(ctx, &vote); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exists, _ := store.Bids.VoteExists(ctx, bid.ID, uuid.Nil, "alice"); !exists {
+		t.Fatal("vote was not recorded")
+	}
+	if exists, _ := store.Bids.VoteExists(ctx, bid.ID, uuid.Nil, "bob"); exists {
+		t.Fatal("vote recorded for the wrong user")
+	}
+	votes, err := store.Bids.ListVotes(ctx, bid.ID, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 2 || votes[0].Username != "alice" || votes[1].Decision != "Rejected" {
+		t.Fatalf("got votes %+v", votes)
+	}
+	if votes, _ := store.Bids.ListVotes(ctx, bid.ID, lotID); len(votes) != 1 || votes[0].Decision != "Rejected" {
+		t.Fatalf("got lot votes %+v", votes)
+	}
+}
+
+func TestMemoryTxRollback(t *testing.T) {
+	store := NewMemoryStore().Storage()
+	ctx := context.Background()
+	existing := Tender{Name: "Road", CreatorUsername: "alice"}
+	if err := store.Tenders.Create(ctx, &existing); err != nil {
+		t.Fatal(err)
+	}
+	var created Tender
+	var bid Bid
+	err := store.Tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := store.Tenders.AddVersion(ctx, existing); err != nil {
+			return err
+		}
+		changed := existing
+		changed.Name, changed.Status, changed.Version = "Bridge", "Published", 2
+		if err := store.Tenders.Update(ctx, &changed); err != nil {
+			return err
+		}
+		created = Tender{Name: "Tunnel", CreatorUsername: "alice"}
+		if err := store.Tenders.Create(ctx, &created); err != nil {
+			return err
+		}
+		bid = Bid{Name: "b", TenderID: existing.ID, AuthorType: "User", AuthorID: uuid.New()}
+		if err := store.Bids.Create(ctx, &bid); err != nil {
+			return err
+		}
+		if err := store.Bids.AddVote(ctx, &ApprovalVote{BidID: bid.ID, Username: "alice", Decision: "Approved"}); err != nil {
+			return err
+		}
+		return errTestAbort
+	})
+	if !errors.Is(err, errTestAbort) {
+		t.Fatalf("got %v, want the callback error", err)
+	}
+	got, err := store.Tenders.Get(ctx, existing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Road" || got.Status != "Created" || got.Version != 1 {
+		t.Fatalf("update was not rolled back: %+v", got)
+	}
+	if exists, _ := store.Tenders.VersionExists(ctx, existing.ID, 1); exists {
+		t.Fatal("version snapshot was not rolled back")
+	}
+	if _, err := store.Tenders.Get(ctx, created.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("created tender survived rollback: %v", err)
+	}
+	if exists, _ := store.Bids.Exists(ctx, bid.ID); exists {
+		t.Fatal("created bid survived rollback")
+	}
+	if voted, _ := store.Bids.VoteExists(ctx, bid.ID, uuid.Nil, "alice"); voted {
+		t.Fatal("vote survived rollback")
+	}
+}
+
+func TestMemoryTxCommit(t *testing.T) {
+	store := NewMemoryStore().Storage()
+	ctx := context.Background()
+	var tender Tender
+	err := store.Tx.WithTx(ctx, func(ctx context.Context) error {
+		tender = Tender{Name: "Road", CreatorUsername: "alice"}
+		if err := store.Tenders.Create(ctx, &tender); err != nil {
+			return err
+		}
+		return store.Tx.WithTx(ctx, func(ctx context.Context) error {
+			tender.Name = "Bridge"
+			return store.Tenders.Update(ctx, &tender)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.Tenders.Get(ctx, tender.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Bridge" {
+		t.Fatalf("got name %s", got.Name)
+	}
+}
+
+func TestMemoryNestedTxRollsBackOuter(t *testing.T) {
+	store := NewMemoryStore().Storage()
+	ctx := context.Background()
+	var tender Tender
+	err := store.Tx.WithTx(ctx, func(ctx context.Context) error {
+		tender = Tender{Name: "Road", CreatorUsername: "alice"}
+		if err := store.Tenders.Create(ctx, &tender); err != nil {
+			return err
+		}
+		return store.Tx.WithTx(ctx, func(ctx context.Context) error {
+			return errTestAbort
+		})
+	})
+	if !errors.Is(err, errTestAbort) {
+		t.Fatalf("got %v, want the callback error", err)
+	}
+	if exists, _ := store.Tenders.Exists(ctx, tender.ID); exists {
+		t.Fatal("tender created in the outer transaction survived rollback")
+	}
+}
+
+func TestMemoryListRanked(t *testing.T) {
+	store := NewMemoryStore().Storage()
+	ctx := context.Background()
+	tenderID := uuid.New()
+	amounts := []struct {
+		amount   string
+		status   string
+		decision string
+	}{
+		{"90", BidSubmitted, "None"},
+		{"80", BidDraft, "None"},
+		{"70", BidWithdrawn, "None"},
+		{"60", BidCanceled, "Rejected"},
+		{"100", BidSubmitted, "None"},
+	}
+	for _, a := range amounts {
+		amount := decimal.RequireFromString(a.amount)
+		bid := Bid{Name: a.amount, TenderID: tenderID, AuthorType: "User", AuthorID: testBob}
+		if err := store.Bids.Create(ctx, &bid); err != nil {
+			t.Fatal(err)
+		}
+		bid.Amount, bid.Status, bid.Decision = &amount, a.status, a.decision
+		if err := store.Bids.Update(ctx, &bid); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ranked, err := store.Bids.ListRanked(ctx, tenderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ranked) != 2 || ranked[0].Name != "90" || ranked[1].Name != "100" {
+		names := []string{}
+		for _, bid := range ranked {
+			names = append(names, bid.Name)
+		}
+		t.Fatalf("got ranking %v, want [90 100]", names)
+	}
+}
+
+func TestPaginateSorted(t *testing.T) {
+	var tenders []Tender
+	for _, name := range []string{"d", "a", "c", "e", "b"} {
+		tenders = append(tenders, Tender{ID: uuid.New(), Name: name})
+	}
+	names := func(items []Tender) string {
+		s := ""
+		for _, item := range items {
+			s += item.Name
+		}
+		return s
+	}
+	page, total := paginateSorted(tenders, Page{Limit: 2, Sort: "name"})
+	if names(page) != "ab" || total != 5 {
+		t.Fatalf("got %q of %d", names(page), total)
+	}
+	key, id := page[1].SortKey("name")
+	page, _ = paginateSorted(tenders, Page{Limit: 2, Sort: "name", After: &Cursor{Sort: "name", Key: key, ID: id}})
+	if names(page) != "cd" {
+		t.Fatalf("got %q after cursor, want \"cd\"", names(page))
+	}
+	page, _ = paginateSorted(tenders, Page{Limit: 3, Sort: "name", Desc: true})
+	if names(page) != "edc" {
+		t.Fatalf("got %q in descending order, want \"edc\"", names(page))
+	}
+}
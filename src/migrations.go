@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const migrationLockID = 6105
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type AppliedMigration struct {
+	Version  int
+	Name     string
+	Checksum string
+}
+
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		parts := migrationName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("unexpected migration file name: %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(parts[1])
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+	var migrations []Migration
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration versions must be sequential, found %d at position %d", m.Version, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+func ensureMigrationsTable(ctx context.Context, conn querier) error {
+	query := `CREATE TABLE IF NOT EXISTS schema_migrations (
+			  version INT PRIMARY KEY,
+			  name VARCHAR(100) NOT NULL,
+			  checksum VARCHAR(64) NOT NULL,
+			  applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)`
+	_, err := conn.Exec(ctx, query)
+	return err
+}
+
+func appliedMigrations(ctx context.Context, conn querier) ([]AppliedMigration, error) {
+	rows, err := conn.Query(ctx, `SELECT version, name, checksum FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var applied []AppliedMigration
+	for rows.Next() {
+		var am AppliedMigration
+		if err := rows.Scan(&am.Version, &am.Name, &am.Checksum); err != nil {
+			return nil, err
+		}
+		applied = append(applied, am)
+	}
+	return applied, rows.Err()
+}
+
+func verifyMigrations(migrations []Migration, applied []AppliedMigration) error {
+	for i, am := range applied {
+		if am.Version != i+1 {
+			return fmt.Errorf("schema_migrations is inconsistent: expected version %d, found %d", i+1, am.Version)
+		}
+		if am.Version > len(migrations) {
+			return fmt.Errorf("database schema version %d is ahead of this binary (knows up to %d), refusing to start", am.Version, len(migrations))
+		}
+		m := migrations[am.Version-1]
+		if m.Checksum != am.Checksum {
+			return fmt.Errorf("checksum mismatch for migration %d_%s: it was changed after being applied", m.Version, m.Name)
+		}
+	}
+	return nil
+}
+
+func withMigrationLock(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	conn, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	if err := ensureMigrationsTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, script string, record func(tx pgx.Tx) error) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, script); err != nil {
+		return err
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func MigrateUp(ctx context.Context, target int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	if target <= 0 || target > len(migrations) {
+		target = len(migrations)
+	}
+	return withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyMigrations(migrations, applied); err != nil {
+			return err
+		}
+		if target < len(applied) {
+			return fmt.Errorf("database is already at version %d, use down to revert", len(applied))
+		}
+		for _, m := range migrations[len(applied):target] {
+			log.Printf("Applying migration %04d_%s\n", m.Version, m.Name)
+			err := applyMigration(ctx, conn, m.Up, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`, m.Version, m.Name, m.Checksum)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %v", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func MigrateDown(ctx context.Context, steps int) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	return withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyMigrations(migrations, applied); err != nil {
+			return err
+		}
+		for i := len(applied) - 1; i >= 0 && steps > 0; i, steps = i-1, steps-1 {
+			m := migrations[applied[i].Version-1]
+			if m.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+			}
+			log.Printf("Reverting migration %04d_%s\n", m.Version, m.Name)
+			err := applyMigration(ctx, conn, m.Down, func(tx pgx.Tx) error {
+				_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("revert of %04d_%s failed: %v", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+func MigrationStatus(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	return withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			state := "pending"
+			for _, am := range applied {
+				if am.Version == m.Version {
+					state = "applied"
+					if am.Checksum != m.Checksum {
+						state = "modified"
+					}
+				}
+			}
+			fmt.Printf("%04d_%s\t%s\n", m.Version, m.Name, state)
+		}
+		for _, am := range applied {
+			if am.Version > len(migrations) {
+				fmt.Printf("%04d_%s\tunknown\n", am.Version, am.Name)
+			}
+		}
+		return nil
+	})
+}
+
+func CheckMigrations(ctx context.Context, autoMigrate bool) error {
+	if autoMigrate {
+		return MigrateUp(ctx, 0)
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+	return withMigrationLock(ctx, func(conn *pgxpool.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := verifyMigrations(migrations, applied); err != nil {
+			return err
+		}
+		if len(applied) < len(migrations) {
+			return fmt.Errorf("database schema is at version %d, binary expects %d: run the migrate command", len(applied), len(migrations))
+		}
+		return nil
+	})
+}
+
+func RunMigrateCommand(args []string) error {
+	pool, err := initDB()
+	if err != nil {
+		return err
+	}
+	db = pool
+	defer db.Close()
+	ctx := context.Background()
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	n := 0
+	if len(args) > 1 {
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			return fmt.Errorf("invalid migration count: %q", args[1])
+		}
+	}
+	switch command {
+	case "up":
+		return MigrateUp(ctx, n)
+	case "down":
+		if n == 0 {
+			n = 1
+		}
+		return MigrateDown(ctx, n)
+	case "status":
+		return MigrationStatus(ctx)
+	default:
+		fmt.Fprintln(os.Stderr, "usage: server migrate [up [version] | down [steps] | status]")
+		return fmt.Errorf("unknown migrate command: %q", command)
+	}
+}
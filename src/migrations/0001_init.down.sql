@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS bid_review;
+DROP TABLE IF EXISTS bid_approve;
+DROP TABLE IF EXISTS bid_version;
+DROP TABLE IF EXISTS bid;
+DROP TABLE IF EXISTS tender_version;
+DROP TABLE IF EXISTS tender;
@@ -1,4 +1,6 @@
-CREATE TABLE tender (
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS tender (
     id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(100) NOT NULL,
     description TEXT NOT NULL,
@@ -11,7 +13,7 @@ CREATE TABLE tender (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE tender_version (
+CREATE TABLE IF NOT EXISTS tender_version (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     tender_id UUID REFERENCES tender(id) ON DELETE CASCADE,
     version INT NOT NULL,
@@ -22,7 +24,7 @@ CREATE TABLE tender_version (
 );
 
 
-CREATE TABLE bid (
+CREATE TABLE IF NOT EXISTS bid (
     id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     name VARCHAR(100) NOT NULL,
     description TEXT NOT NULL,
@@ -33,12 +35,12 @@ CREATE TABLE bid (
     organization_id uuid REFERENCES organization(id) ON DELETE CASCADE,
     version INT DEFAULT 1,
     decision VARCHAR(50) CHECK (decision IN ('Approved', 'Rejected', 'None')) default 'None',
-    approved_count int DEFAULT 0, 
+    approved_count int DEFAULT 0,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE TABLE bid_version (
+CREATE TABLE IF NOT EXISTS bid_version (
     id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     bid_id uuid REFERENCES bid(id) ON DELETE CASCADE,
     version INT NOT NULL,
@@ -49,13 +51,13 @@ CREATE TABLE bid_version (
     status VARCHAR(50)
 );
 
-CREATE TABLE bid_approve (
+CREATE TABLE IF NOT EXISTS bid_approve (
     id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     bid_id uuid REFERENCES bid(id) ON DELETE CASCADE,
     username VARCHAR(100) REFERENCES employee(username) ON DELETE CASCADE
 );
 
-CREATE TABLE bid_review (
+CREATE TABLE IF NOT EXISTS bid_review (
     id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
     bid_id uuid REFERENCES bid(id) ON DELETE CASCADE,
     username VARCHAR(100) REFERENCES employee(username) ON DELETE cascade,
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS bid_version_bid_id_idx;
+ALTER TABLE bid_version DROP COLUMN modified_at;
+ALTER TABLE bid_version DROP COLUMN modified_by;
+ALTER TABLE bid DROP COLUMN modified_by;
+
+DROP INDEX IF EXISTS tender_version_tender_id_idx;
+ALTER TABLE tender_version DROP COLUMN modified_at;
+ALTER TABLE tender_version DROP COLUMN modified_by;
+ALTER TABLE tender DROP COLUMN modified_by;
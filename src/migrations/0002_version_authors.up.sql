@@ -0,0 +1,15 @@
+ALTER TABLE tender ADD COLUMN modified_by VARCHAR(50) NOT NULL DEFAULT '';
+UPDATE tender SET modified_by = creator_username;
+
+ALTER TABLE tender_version ADD COLUMN modified_by VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE tender_version ADD COLUMN modified_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX tender_version_tender_id_idx ON tender_version (tender_id, version);
+
+ALTER TABLE bid ADD COLUMN modified_by VARCHAR(50) NOT NULL DEFAULT '';
+UPDATE bid b SET modified_by = e.username
+FROM employee e
+WHERE b.author_type = 'User' AND b.author_id = e.id;
+
+ALTER TABLE bid_version ADD COLUMN modified_by VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE bid_version ADD COLUMN modified_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX bid_version_bid_id_idx ON bid_version (bid_id, version);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS organization_role_audit;
+ALTER TABLE organization_responsible DROP COLUMN IF EXISTS role;
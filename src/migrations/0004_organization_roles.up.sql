@@ -0,0 +1,14 @@
+ALTER TABLE organization_responsible ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'admin'
+    CHECK (role IN ('viewer', 'editor', 'approver', 'admin'));
+
+CREATE TABLE IF NOT EXISTS organization_role_audit (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID REFERENCES organization(id) ON DELETE CASCADE,
+    username VARCHAR(50) NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('grant', 'revoke')),
+    old_role VARCHAR(20) NOT NULL DEFAULT '',
+    new_role VARCHAR(20) NOT NULL DEFAULT '',
+    changed_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX organization_role_audit_organization_id_idx ON organization_role_audit (organization_id, created_at);
@@ -0,0 +1,7 @@
+ALTER TABLE tender_version DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE tender_version DROP COLUMN IF EXISTS submission_deadline;
+
+DROP INDEX IF EXISTS tender_submission_deadline_idx;
+DROP INDEX IF EXISTS tender_publish_at_idx;
+ALTER TABLE tender DROP COLUMN IF EXISTS publish_at;
+ALTER TABLE tender DROP COLUMN IF EXISTS submission_deadline;
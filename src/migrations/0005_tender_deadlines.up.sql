@@ -0,0 +1,7 @@
+ALTER TABLE tender ADD COLUMN submission_deadline TIMESTAMPTZ;
+ALTER TABLE tender ADD COLUMN publish_at TIMESTAMPTZ;
+CREATE INDEX tender_publish_at_idx ON tender (publish_at) WHERE status = 'Created';
+CREATE INDEX tender_submission_deadline_idx ON tender (submission_deadline) WHERE status = 'Published';
+
+ALTER TABLE tender_version ADD COLUMN submission_deadline TIMESTAMPTZ;
+ALTER TABLE tender_version ADD COLUMN publish_at TIMESTAMPTZ;
@@ -0,0 +1,9 @@
+ALTER TABLE bid_version DROP COLUMN IF EXISTS line_items;
+ALTER TABLE bid_version DROP COLUMN IF EXISTS currency;
+ALTER TABLE bid_version DROP COLUMN IF EXISTS amount;
+
+DROP INDEX IF EXISTS bid_tender_amount_idx;
+ALTER TABLE bid DROP CONSTRAINT IF EXISTS bid_amount_check;
+ALTER TABLE bid DROP COLUMN IF EXISTS line_items;
+ALTER TABLE bid DROP COLUMN IF EXISTS currency;
+ALTER TABLE bid DROP COLUMN IF EXISTS amount;
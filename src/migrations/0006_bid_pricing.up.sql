@@ -0,0 +1,9 @@
+ALTER TABLE bid ADD COLUMN amount NUMERIC;
+ALTER TABLE bid ADD COLUMN currency VARCHAR(3);
+ALTER TABLE bid ADD COLUMN line_items JSONB NOT NULL DEFAULT '[]';
+ALTER TABLE bid ADD CONSTRAINT bid_amount_check CHECK (amount >= 0);
+CREATE INDEX bid_tender_amount_idx ON bid (tender_id, amount);
+
+ALTER TABLE bid_version ADD COLUMN amount NUMERIC;
+ALTER TABLE bid_version ADD COLUMN currency VARCHAR(3);
+ALTER TABLE bid_version ADD COLUMN line_items JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,12 @@
+ALTER TABLE tender_version DROP COLUMN IF EXISTS reject_above_max;
+ALTER TABLE tender_version DROP COLUMN IF EXISTS currency;
+ALTER TABLE tender_version DROP COLUMN IF EXISTS max_bid_amount;
+ALTER TABLE tender_version DROP COLUMN IF EXISTS reserve_price;
+ALTER TABLE tender_version DROP COLUMN IF EXISTS budget;
+
+ALTER TABLE tender DROP CONSTRAINT IF EXISTS tender_amounts_check;
+ALTER TABLE tender DROP COLUMN IF EXISTS reject_above_max;
+ALTER TABLE tender DROP COLUMN IF EXISTS currency;
+ALTER TABLE tender DROP COLUMN IF EXISTS max_bid_amount;
+ALTER TABLE tender DROP COLUMN IF EXISTS reserve_price;
+ALTER TABLE tender DROP COLUMN IF EXISTS budget;
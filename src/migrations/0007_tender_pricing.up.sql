@@ -0,0 +1,12 @@
+ALTER TABLE tender ADD COLUMN budget NUMERIC;
+ALTER TABLE tender ADD COLUMN reserve_price NUMERIC;
+ALTER TABLE tender ADD COLUMN max_bid_amount NUMERIC;
+ALTER TABLE tender ADD COLUMN currency VARCHAR(3);
+ALTER TABLE tender ADD COLUMN reject_above_max BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tender ADD CONSTRAINT tender_amounts_check CHECK (budget >= 0 AND reserve_price >= 0 AND max_bid_amount >= 0);
+
+ALTER TABLE tender_version ADD COLUMN budget NUMERIC;
+ALTER TABLE tender_version ADD COLUMN reserve_price NUMERIC;
+ALTER TABLE tender_version ADD COLUMN max_bid_amount NUMERIC;
+ALTER TABLE tender_version ADD COLUMN currency VARCHAR(3);
+ALTER TABLE tender_version ADD COLUMN reject_above_max BOOLEAN NOT NULL DEFAULT FALSE;
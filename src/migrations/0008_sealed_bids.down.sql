@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS tender_unseal_event;
+
+ALTER TABLE bid_version DROP COLUMN IF EXISTS sealed_data;
+
+DROP INDEX IF EXISTS bid_sealed_idx;
+ALTER TABLE bid DROP COLUMN IF EXISTS sealed_data;
+ALTER TABLE bid DROP COLUMN IF EXISTS sealed;
+
+ALTER TABLE tender DROP COLUMN IF EXISTS unsealed_at;
+ALTER TABLE tender DROP COLUMN IF EXISTS sealed;
+UPDATE tender SET status = 'Closed' WHERE status = 'Evaluation';
+ALTER TABLE tender DROP CONSTRAINT IF EXISTS tender_status_check;
+ALTER TABLE tender ADD CONSTRAINT tender_status_check CHECK (status IN ('Created', 'Published', 'Closed'));
@@ -0,0 +1,19 @@
+ALTER TABLE tender DROP CONSTRAINT IF EXISTS tender_status_check;
+ALTER TABLE tender ADD CONSTRAINT tender_status_check CHECK (status IN ('Created', 'Published', 'Evaluation', 'Closed'));
+ALTER TABLE tender ADD COLUMN sealed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tender ADD COLUMN unsealed_at TIMESTAMPTZ;
+
+ALTER TABLE bid ADD COLUMN sealed BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE bid ADD COLUMN sealed_data BYTEA;
+CREATE INDEX bid_sealed_idx ON bid (tender_id) WHERE sealed;
+
+ALTER TABLE bid_version ADD COLUMN sealed_data BYTEA;
+
+CREATE TABLE IF NOT EXISTS tender_unseal_event (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tender_id UUID NOT NULL REFERENCES tender(id) ON DELETE CASCADE,
+    bid_count INT NOT NULL,
+    reason VARCHAR(50) NOT NULL,
+    unsealed_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS bid_tender_ranking_idx;
+
+ALTER TABLE tender DROP COLUMN IF EXISTS auction_extension_seconds;
+ALTER TABLE tender DROP COLUMN IF EXISTS auction_step;
+ALTER TABLE tender DROP COLUMN IF EXISTS mode;
@@ -0,0 +1,5 @@
+ALTER TABLE tender ADD COLUMN mode VARCHAR(20) NOT NULL DEFAULT 'standard' CHECK (mode IN ('standard', 'auction'));
+ALTER TABLE tender ADD COLUMN auction_step NUMERIC CHECK (auction_step > 0);
+ALTER TABLE tender ADD COLUMN auction_extension_seconds INT NOT NULL DEFAULT 0 CHECK (auction_extension_seconds >= 0);
+
+CREATE INDEX bid_tender_ranking_idx ON bid (tender_id, amount, updated_at) WHERE amount IS NOT NULL;
@@ -0,0 +1,6 @@
+ALTER TABLE bid_approve DROP COLUMN IF EXISTS lot_id;
+
+DROP INDEX IF EXISTS bid_lot_ids_idx;
+ALTER TABLE bid DROP COLUMN IF EXISTS lot_ids;
+
+DROP TABLE IF EXISTS tender_lot;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS tender_lot (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tender_id UUID NOT NULL REFERENCES tender(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    budget NUMERIC CHECK (budget >= 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'Open' CHECK (status IN ('Open', 'Awarded', 'Canceled')),
+    awarded_bid_id UUID REFERENCES bid(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ
+);
+CREATE INDEX tender_lot_tender_idx ON tender_lot (tender_id, created_at);
+
+ALTER TABLE bid ADD COLUMN lot_ids JSONB NOT NULL DEFAULT '[]';
+CREATE INDEX bid_lot_ids_idx ON bid USING GIN (lot_ids);
+
+ALTER TABLE bid_approve ADD COLUMN lot_id UUID REFERENCES tender_lot(id) ON DELETE CASCADE;
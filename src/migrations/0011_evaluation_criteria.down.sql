@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS bid_score;
+DROP TABLE IF EXISTS tender_criterion;
+
+ALTER TABLE tender DROP COLUMN IF EXISTS require_scoring;
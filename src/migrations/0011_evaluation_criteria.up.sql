@@ -0,0 +1,23 @@
+ALTER TABLE tender ADD COLUMN require_scoring BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS tender_criterion (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tender_id UUID NOT NULL REFERENCES tender(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    weight NUMERIC NOT NULL CHECK (weight > 0),
+    max_score INT NOT NULL DEFAULT 10 CHECK (max_score > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX tender_criterion_tender_idx ON tender_criterion (tender_id, created_at);
+
+CREATE TABLE IF NOT EXISTS bid_score (
+    bid_id UUID NOT NULL REFERENCES bid(id) ON DELETE CASCADE,
+    criterion_id UUID NOT NULL REFERENCES tender_criterion(id) ON DELETE CASCADE,
+    username VARCHAR(50) NOT NULL,
+    score NUMERIC NOT NULL CHECK (score >= 0),
+    comment TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (bid_id, criterion_id, username)
+);
+CREATE INDEX bid_score_criterion_idx ON bid_score (criterion_id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS approval_policy;
+
+ALTER TABLE bid_approve DROP COLUMN IF EXISTS created_at;
+ALTER TABLE bid_approve DROP COLUMN IF EXISTS decision;
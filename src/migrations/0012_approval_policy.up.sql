@@ -0,0 +1,12 @@
+ALTER TABLE bid_approve ADD COLUMN decision VARCHAR(50) NOT NULL DEFAULT 'Approved' CHECK (decision IN ('Approved', 'Rejected'));
+ALTER TABLE bid_approve ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS approval_policy (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID UNIQUE REFERENCES organization(id) ON DELETE CASCADE,
+    tender_id UUID UNIQUE REFERENCES tender(id) ON DELETE CASCADE,
+    policy JSONB NOT NULL,
+    updated_by VARCHAR(50) NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK ((organization_id IS NULL) <> (tender_id IS NULL))
+);
@@ -0,0 +1,6 @@
+ALTER TABLE bid DROP COLUMN IF EXISTS withdrawn_at;
+ALTER TABLE bid DROP COLUMN IF EXISTS withdrawal_reason;
+
+UPDATE bid SET status = 'Canceled' WHERE status = 'Withdrawn';
+ALTER TABLE bid DROP CONSTRAINT IF EXISTS bid_status_check;
+ALTER TABLE bid ADD CONSTRAINT bid_status_check CHECK (status IN ('Created', 'Published', 'Canceled'));
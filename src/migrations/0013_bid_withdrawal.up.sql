@@ -0,0 +1,5 @@
+ALTER TABLE bid DROP CONSTRAINT IF EXISTS bid_status_check;
+ALTER TABLE bid ADD CONSTRAINT bid_status_check CHECK (status IN ('Created', 'Published', 'Withdrawn', 'Canceled'));
+
+ALTER TABLE bid ADD COLUMN withdrawal_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE bid ADD COLUMN withdrawn_at TIMESTAMPTZ;
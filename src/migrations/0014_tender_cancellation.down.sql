@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS notification;
+
+ALTER TABLE tender DROP COLUMN IF EXISTS cancelled_at;
+ALTER TABLE tender DROP COLUMN IF EXISTS cancellation_reason;
+
+UPDATE tender SET status = 'Closed' WHERE status = 'Cancelled';
+ALTER TABLE tender DROP CONSTRAINT IF EXISTS tender_status_check;
+ALTER TABLE tender ADD CONSTRAINT tender_status_check CHECK (status IN ('Created', 'Published', 'Evaluation', 'Closed'));
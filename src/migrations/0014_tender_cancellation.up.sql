@@ -0,0 +1,17 @@
+ALTER TABLE tender DROP CONSTRAINT IF EXISTS tender_status_check;
+ALTER TABLE tender ADD CONSTRAINT tender_status_check CHECK (status IN ('Created', 'Published', 'Evaluation', 'Closed', 'Cancelled'));
+
+ALTER TABLE tender ADD COLUMN cancellation_reason TEXT NOT NULL DEFAULT '';
+ALTER TABLE tender ADD COLUMN cancelled_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS notification (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    event VARCHAR(50) NOT NULL,
+    recipient_type VARCHAR(50) NOT NULL CHECK (recipient_type IN ('Organization', 'User')),
+    recipient_id UUID NOT NULL,
+    tender_id UUID REFERENCES tender(id) ON DELETE CASCADE,
+    bid_id UUID REFERENCES bid(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX notification_recipient_idx ON notification (recipient_type, recipient_id, created_at DESC);
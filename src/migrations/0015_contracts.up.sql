@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS contract (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tender_id UUID NOT NULL REFERENCES tender(id) ON DELETE CASCADE,
+    bid_id UUID NOT NULL REFERENCES bid(id) ON DELETE CASCADE,
+    lot_id UUID REFERENCES tender_lot(id) ON DELETE CASCADE,
+    buyer_organization_id UUID NOT NULL REFERENCES organization(id) ON DELETE CASCADE,
+    supplier_type VARCHAR(50) NOT NULL CHECK (supplier_type IN ('Organization', 'User')),
+    supplier_id UUID NOT NULL,
+    amount NUMERIC,
+    currency VARCHAR(3),
+    status VARCHAR(50) NOT NULL DEFAULT 'Draft' CHECK (status IN ('Draft', 'Signed', 'InProgress', 'Completed', 'Terminated')),
+    start_date TIMESTAMPTZ,
+    end_date TIMESTAMPTZ,
+    buyer_signed_by VARCHAR(50),
+    buyer_signed_at TIMESTAMPTZ,
+    supplier_signed_by VARCHAR(50),
+    supplier_signed_at TIMESTAMPTZ,
+    termination_reason TEXT,
+    version INT NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE UNIQUE INDEX contract_bid_lot_idx ON contract (bid_id, COALESCE(lot_id, '00000000-0000-0000-0000-000000000000'));
+CREATE INDEX contract_tender_idx ON contract (tender_id);
+CREATE INDEX contract_buyer_idx ON contract (buyer_organization_id);
+CREATE INDEX contract_supplier_idx ON contract (supplier_type, supplier_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS tender_question (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tender_id UUID NOT NULL REFERENCES tender(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL REFERENCES employee(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    anonymous BOOLEAN NOT NULL DEFAULT FALSE,
+    answer TEXT,
+    answered_by VARCHAR(50),
+    answered_at TIMESTAMPTZ,
+    amended_version INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX tender_question_tender_idx ON tender_question (tender_id, created_at);
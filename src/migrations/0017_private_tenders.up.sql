@@ -0,0 +1,14 @@
+ALTER TABLE tender ADD COLUMN private BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS tender_invitation (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tender_id UUID NOT NULL REFERENCES tender(id) ON DELETE CASCADE,
+    invitee_type VARCHAR(50) NOT NULL CHECK (invitee_type IN ('Organization', 'User')),
+    invitee_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'Pending' CHECK (status IN ('Pending', 'Accepted', 'Declined')),
+    invited_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMPTZ,
+    UNIQUE (tender_id, invitee_type, invitee_id)
+);
+CREATE INDEX tender_invitation_invitee_idx ON tender_invitation (invitee_type, invitee_id);
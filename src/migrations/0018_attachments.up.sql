@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS attachment (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    parent_type VARCHAR(50) NOT NULL CHECK (parent_type IN ('tender', 'bid', 'review')),
+    parent_id UUID NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(255) NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    sha256 CHAR(64) NOT NULL,
+    uploaded_by VARCHAR(50) NOT NULL,
+    added_in INT NOT NULL,
+    removed_in INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX attachment_parent_idx ON attachment (parent_type, parent_id);
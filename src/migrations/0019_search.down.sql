@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS bid_search_idx;
+ALTER TABLE bid DROP COLUMN IF EXISTS search_vector;
+DROP INDEX IF EXISTS tender_search_idx;
+ALTER TABLE tender DROP COLUMN IF EXISTS search_vector;
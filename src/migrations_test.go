@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no embedded migrations")
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration at position %d has version %d", i+1, m.Version)
+		}
+		if m.Up == "" || len(m.Checksum) != 64 {
+			t.Fatalf("migration %d_%s has no up script or checksum", m.Version, m.Name)
+		}
+	}
+}
+
+func TestVerifyMigrations(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "init", Checksum: "aaa"},
+		{Version: 2, Name: "more", Checksum: "bbb"},
+	}
+	tests := []struct {
+		name    string
+		applied []AppliedMigration
+		err     string
+	}{
+		{"none applied", nil, ""},
+		{"partially applied", []AppliedMigration{{1, "init", "aaa"}}, ""},
+		{"fully applied", []AppliedMigration{{1, "init", "aaa"}, {2, "more", "bbb"}}, ""},
+		{"changed after apply", []AppliedMigration{{1, "init", "aaa"}, {2, "more", "ccc"}}, "checksum mismatch for migration 2_more"},
+		{"ahead of binary", []AppliedMigration{{1, "init", "aaa"}, {2, "more", "bbb"}, {3, "newer", "ddd"}}, "is ahead of this binary"},
+		{"gap in versions", []AppliedMigration{{1, "init", "aaa"}, {3, "newer", "ddd"}}, "expected version 2, found 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyMigrations(migrations, tt.applied)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.err)
+			}
+		})
+	}
+}
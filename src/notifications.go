@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventTenderCancelled  = "tender_cancelled"
+	EventTenderAmended    = "tender_amended"
+	EventQuestionAnswered = "question_answered"
+)
+
+type Notification struct {
+	ID            uuid.UUID  `json:"id"`
+	Event         string     `json:"event"`
+	RecipientType string     `json:"recipientType"`
+	RecipientID   uuid.UUID  `json:"recipientId"`
+	TenderID      *uuid.UUID `json:"tenderId,omitempty"`
+	BidID         *uuid.UUID `json:"bidId,omitempty"`
+	Message       string     `json:"message"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+func NotifyBidAuthor(ctx context.Context, bid Bid, event, message string) error {
+	tenderId, bidId := bid.TenderID, bid.ID
+	notification := Notification{Event: event, RecipientType: bid.AuthorType, RecipientID: bid.AuthorID, TenderID: &tenderId, BidID: &bidId, Message: message}
+	if notification.RecipientType != "Organization" {
+		notification.RecipientType = "User"
+	}
+	return notify(ctx, notification)
+}
+
+func NotifyUser(ctx context.Context, userId, tenderId uuid.UUID, event, message string) error {
+	return notify(ctx, Notification{Event: event, RecipientType: "User", RecipientID: userId, TenderID: &tenderId, Message: message})
+}
+
+func notify(ctx context.Context, notification Notification) error {
+	if err := storage.Notifications.Create(ctx, &notification); err != nil {
+		return err
+	}
+	log.Printf("Notification %s for %s %s: %s\n", notification.Event, notification.RecipientType, notification.RecipientID, notification.Message)
+	return nil
+}
+
+func ShowMyNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowMyNotificationsHandler started")
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	page, ok := ParsePage(w, r.URL.Query(), feedSorts)
+	if !ok {
+		return
+	}
+	var organizationId uuid.UUID
+	if oi, ok := GetOrganizationId(ctx, w, user.ID); ok {
+		organizationId = oi
+	}
+	notifications, total, err := storage.Notifications.ListForRecipient(ctx, user.ID, organizationId, page.Lookahead())
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find notifications"}, http.StatusInternalServerError)
+		return
+	}
+	notifications = WritePage(w, r, page, notifications, total)
+	if notifications == nil {
+		notifications = []Notification{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(notifications)
+}
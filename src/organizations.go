@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type RoleChange struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organizationId"`
+	Username       string    `json:"username"`
+	Action         string    `json:"action"`
+	OldRole        string    `json:"oldRole"`
+	NewRole        string    `json:"newRole"`
+	ChangedBy      string    `json:"changedBy"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+func GetMemberTarget(ctx context.Context, w http.ResponseWriter, r *http.Request, perm Permission) (uuid.UUID, string, bool) {
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return uuid.Nil, username, false
+	}
+	organizationId, ok := ParseID(w, mux.Vars(r)["organizationId"], "organization")
+	if !ok {
+		return organizationId, username, false
+	}
+	if !CheckOrganizationExists(ctx, w, organizationId) {
+		return organizationId, username, false
+	}
+	if !Authorize(ctx, w, organizationId, username, perm) {
+		return organizationId, username, false
+	}
+	return organizationId, username, true
+}
+
+func GetMemberUserId(ctx context.Context, w http.ResponseWriter, username string) (uuid.UUID, bool) {
+	user_id, err := storage.Organizations.GetUserID(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		SendErrorResponse(w, ErrorResponse{"No such user"}, http.StatusNotFound)
+		return user_id, false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find user"}, http.StatusInternalServerError)
+		return user_id, false
+	}
+	return user_id, true
+}
+
+func GetMemberRole(ctx context.Context, w http.ResponseWriter, organizationId uuid.UUID, username string) (string, bool) {
+	role, err := storage.Organizations.GetRole(ctx, organizationId, username)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find member"}, http.StatusInternalServerError)
+		return role, false
+	}
+	return role, true
+}
+
+func CheckKeepsAdmin(ctx context.Context, w http.ResponseWriter, organizationId uuid.UUID, oldRole, newRole string) bool {
+	if oldRole != RoleAdmin || newRole == RoleAdmin {
+		return true
+	}
+	admins, err := storage.Organizations.CountAdmins(ctx, organizationId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find members"}, http.StatusInternalServerError)
+		return false
+	}
+	if admins <= 1 {
+		SendErrorResponse(w, ErrorResponse{"Organization must keep at least one admin"}, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func AddRoleChange(ctx context.Context, w http.ResponseWriter, change *RoleChange) bool {
+	err := storage.Organizations.AddRoleChange(ctx, change)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to write role audit"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func ShowMembersHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowMembersHandler started")
+	ctx := r.Context()
+	organizationId, _, ok := GetMemberTarget(ctx, w, r, PermViewMembers)
+	if !ok {
+		return
+	}
+	members, err := storage.Organizations.ListMembers(ctx, organizationId)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find members"}, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(members)
+}
+
+func GrantRoleHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("GrantRoleHandler started")
+	ctx := r.Context()
+	organizationId, username, ok := GetMemberTarget(ctx, w, r, PermManageMembers)
+	if !ok {
+		return
+	}
+	role := r.URL.Query().Get("role")
+	if !IsValidRole(role) {
+		SendErrorResponse(w, ErrorResponse{"Invalid role"}, http.StatusBadRequest)
+		return
+	}
+	member := Membership{OrganizationID: organizationId, Username: mux.Vars(r)["username"], Role: role}
+	if ui, ok := GetMemberUserId(ctx, w, member.Username); ok {
+		member.UserID = ui
+	} else {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if err := storage.Organizations.Lock(ctx, organizationId); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to find organization"}, http.StatusInternalServerError)
+			return false
+		}
+		oldRole, ok := GetMemberRole(ctx, w, organizationId, member.Username)
+		if !ok {
+			return false
+		}
+		if oldRole == role {
+			return true
+		}
+		if !CheckKeepsAdmin(ctx, w, organizationId, oldRole, role) {
+			return false
+		}
+		if err := storage.Organizations.SetRole(ctx, organizationId, member.UserID, role); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to grant role"}, http.StatusInternalServerError)
+			return false
+		}
+		return AddRoleChange(ctx, w, &RoleChange{OrganizationID: organizationId, Username: member.Username, Action: "grant", OldRole: oldRole, NewRole: role, ChangedBy: username})
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(member)
+}
+
+func RevokeRoleHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("RevokeRoleHandler started")
+	ctx := r.Context()
+	organizationId, username, ok := GetMemberTarget(ctx, w, r, PermManageMembers)
+	if !ok {
+		return
+	}
+	target := mux.Vars(r)["username"]
+	var user_id uuid.UUID
+	if ui, ok := GetMemberUserId(ctx, w, target); ok {
+		user_id = ui
+	} else {
+		return
+	}
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if err := storage.Organizations.Lock(ctx, organizationId); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to find organization"}, http.StatusInternalServerError)
+			return false
+		}
+		oldRole, ok := GetMemberRole(ctx, w, organizationId, target)
+		if !ok {
+			return false
+		}
+		if oldRole == "" {
+			SendErrorResponse(w, ErrorResponse{"User is not a member of the organization"}, http.StatusNotFound)
+			return false
+		}
+		if !CheckKeepsAdmin(ctx, w, organizationId, oldRole, "") {
+			return false
+		}
+		if err := storage.Organizations.RemoveMember(ctx, organizationId, user_id); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to revoke role"}, http.StatusInternalServerError)
+			return false
+		}
+		return AddRoleChange(ctx, w, &RoleChange{OrganizationID: organizationId, Username: target, Action: "revoke", OldRole: oldRole, ChangedBy: username})
+	})
+	if !ok {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func ShowRoleAuditHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowRoleAuditHandler started")
+	ctx := r.Context()
+	var err error
+	organizationId, _, ok := GetMemberTarget(ctx, w, r, PermManageMembers)
+	if !ok {
+		return
+	}
+	url := r.URL.Query()
+	var page Page
+	if lim := url.Get("limit"); lim != "" {
+		if page.Limit, err = strconv.Atoi(lim); err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if off := url.Get("offset"); off != "" {
+		if page.Offset, err = strconv.Atoi(off); err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
+			return
+		}
+	}
+	changes, err := storage.Organizations.ListRoleChanges(ctx, organizationId, page)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find role audit"}, http.StatusInternalServerError)
+		return
+	}
+	if changes == nil {
+		changes = []RoleChange{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(changes)
+}
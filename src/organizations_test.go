@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRolePermissions(t *testing.T) {
+	env := newTestEnv(t)
+	body := map[string]interface{}{"name": "Road", "description": "d", "serviceType": "Construction", "organizationId": testBuyerOrg}
+	env.expect("POST", "/api/tenders/new", keyDave, body, http.StatusForbidden, nil)
+	env.expect("PUT", "/api/organizations/"+testSupplierOrg.String()+"/members/bob?role=viewer", keyCarol, nil, http.StatusForbidden, nil)
+	env.expect("GET", "/api/organizations/"+testSupplierOrg.String()+"/members", keyCarol, nil, http.StatusOK, nil)
+}
+
+func TestGrantAndRevokeRoleAreAudited(t *testing.T) {
+	env := newTestEnv(t)
+	org := "/api/organizations/" + testBuyerOrg.String()
+	env.expect("PUT", org+"/members/dave?role=editor", keyAlice, nil, http.StatusOK, nil)
+	env.expect("PUT", org+"/members/dave?role=owner", keyAlice, nil, http.StatusBadRequest, nil)
+	env.expect("DELETE", org+"/members/alice", keyAlice, nil, http.StatusConflict, nil)
+	env.expect("DELETE", org+"/members/dave", keyAlice, nil, http.StatusNoContent, nil)
+	var changes []RoleChange
+	env.expect("GET", org+"/members/audit", keyAlice, nil, http.StatusOK, &changes)
+	if len(changes) != 2 {
+		t.Fatalf("got %d audit entries, want 2: %+v", len(changes), changes)
+	}
+	for _, change := range changes {
+		if change.Username != "dave" || change.ChangedBy != "alice" || change.OldRole == "" {
+			t.Fatalf("unexpected audit entry %+v", change)
+		}
+	}
+}
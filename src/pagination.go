@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 5
+	maxPageLimit     = 50
+)
+
+const (
+	sortText   = "text"
+	sortTime   = "timestamptz"
+	sortAmount = "numeric"
+)
+
+var sortKinds = map[string]string{"name": sortText, "createdAt": sortTime, "amount": sortAmount}
+
+var sortColumns = map[string]string{"name": "name", "createdAt": "created_at", "amount": "amount"}
+
+var (
+	tenderSorts = []string{"name", "createdAt"}
+	bidSorts    = []string{"name", "createdAt", "amount"}
+	reviewSorts = []string{"createdAt"}
+	feedSorts   = []string{"-createdAt"}
+)
+
+type Cursor struct {
+	Sort string    `json:"s"`
+	Desc bool      `json:"d,omitempty"`
+	Key  *string   `json:"k,omitempty"`
+	ID   uuid.UUID `json:"i"`
+}
+
+type Sortable interface {
+	SortKey(sort string) (*string, uuid.UUID)
+}
+
+func timeKey(t time.Time) *string {
+	key := t.UTC().Format(time.RFC3339Nano)
+	return &key
+}
+
+func (tender Tender) SortKey(sort string) (*string, uuid.UUID) {
+	if sort == "createdAt" {
+		return timeKey(tender.CreatedAt), tender.ID
+	}
+	return &tender.Name, tender.ID
+}
+
+func (bid Bid) SortKey(sort string) (*string, uuid.UUID) {
+	switch sort {
+	case "createdAt":
+		return timeKey(bid.CreatedAt), bid.ID
+	case "amount":
+		if bid.Amount == nil {
+			return nil, bid.ID
+		}
+		key := bid.Amount.String()
+		return &key, bid.ID
+	}
+	return &bid.Name, bid.ID
+}
+
+func (review BidReview) SortKey(sort string) (*string, uuid.UUID) {
+	return timeKey(review.CreatedAt), review.ID
+}
+
+func (contract Contract) SortKey(sort string) (*string, uuid.UUID) {
+	return timeKey(contract.CreatedAt), contract.ID
+}
+
+func (invitation Invitation) SortKey(sort string) (*string, uuid.UUID) {
+	return timeKey(invitation.CreatedAt), invitation.ID
+}
+
+func (notification Notification) SortKey(sort string) (*string, uuid.UUID) {
+	return timeKey(notification.CreatedAt), notification.ID
+}
+
+func EncodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(raw string) (Cursor, error) {
+	var cursor Cursor
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	return cursor, err
+}
+
+func ParsePage(w http.ResponseWriter, url url.Values, sorts []string) (Page, bool) {
+	var err error
+	page := Page{Limit: defaultPageLimit, Sort: strings.TrimPrefix(sorts[0], "-"), Desc: strings.HasPrefix(sorts[0], "-")}
+	if lim := url.Get("limit"); lim != "" {
+		if page.Limit, err = strconv.Atoi(lim); err != nil || page.Limit < 0 || page.Limit > maxPageLimit {
+			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
+			return page, false
+		}
+		if page.Limit == 0 {
+			page.Limit = defaultPageLimit
+		}
+	}
+	if off := url.Get("offset"); off != "" {
+		if page.Offset, err = strconv.Atoi(off); err != nil || page.Offset < 0 {
+			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
+			return page, false
+		}
+	}
+	if sort := url.Get("sort"); sort != "" {
+		page.Sort, page.Desc = strings.TrimPrefix(sort, "-"), strings.HasPrefix(sort, "-")
+		if !hasStatus(sorts, page.Sort) && !hasStatus(sorts, "-"+page.Sort) {
+			SendErrorResponse(w, ErrorResponse{"Invalid sort parameter"}, http.StatusBadRequest)
+			return page, false
+		}
+	}
+	if raw := url.Get("cursor"); raw != "" {
+		cursor, err := DecodeCursor(raw)
+		if err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid cursor parameter"}, http.StatusBadRequest)
+			return page, false
+		}
+		if page.Offset > 0 {
+			SendErrorResponse(w, ErrorResponse{"Cursor can't be combined with offset"}, http.StatusBadRequest)
+			return page, false
+		}
+		if cursor.Sort != page.Sort || cursor.Desc != page.Desc {
+			SendErrorResponse(w, ErrorResponse{"Cursor doesn't match sort parameter"}, http.StatusBadRequest)
+			return page, false
+		}
+		page.After = &cursor
+	}
+	return page, true
+}
+
+func (page Page) Lookahead() Page {
+	page.Limit++
+	return page
+}
+
+func WritePage[T Sortable](w http.ResponseWriter, r *http.Request, page Page, items []T, total int) []T {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	if len(items) <= page.Limit {
+		return items
+	}
+	items = items[:page.Limit]
+	key, id := items[len(items)-1].SortKey(page.Sort)
+	query := r.URL.Query()
+	query.Set("cursor", EncodeCursor(Cursor{page.Sort, page.Desc, key, id}))
+	query.Del("offset")
+	next := *r.URL
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", "<"+next.RequestURI()+">; rel=\"next\"")
+	return items
+}
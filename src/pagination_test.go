@@ -0,0 +1,184 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var nextLink = regexp.MustCompile(`^<([^>]+)>; rel="next"$`)
+
+func (env *testEnv) collectTenders(path string) ([]string, int) {
+	env.t.Helper()
+	var names []string
+	pages := 0
+	for path != "" {
+		var tenders []Tender
+		w := env.expect("GET", path, keyBob, nil, http.StatusOK, &tenders)
+		if total := w.Header().Get("X-Total-Count"); total != "7" {
+			env.t.Fatalf("got X-Total-Count %q, want 7", total)
+		}
+		for _, tender := range tenders {
+			names = append(names, tender.Name)
+		}
+		pages++
+		path = ""
+		if link := w.Header().Get("Link"); link != "" {
+			match := nextLink.FindStringSubmatch(link)
+			if match == nil {
+				env.t.Fatalf("malformed Link header %q", link)
+			}
+			path = match[1]
+		}
+	}
+	return names, pages
+}
+
+func TestTenderKeysetPagination(t *testing.T) {
+	env := newTestEnv(t)
+	for _, name := range []string{"e", "b", "g", "a", "d", "f", "c"} {
+		env.publishTender(name, nil)
+	}
+	env.createTender("draft", nil)
+
+	names, pages := env.collectTenders("/api/tenders?limit=3")
+	if got := joinNames(names); got != "abcdefg" || pages != 3 {
+		t.Fatalf("got %q in %d pages", got, pages)
+	}
+	names, _ = env.collectTenders("/api/tenders?limit=2&sort=-name")
+	if got := joinNames(names); got != "gfedcba" {
+		t.Fatalf("got %q in descending order", got)
+	}
+	names, pages = env.collectTenders("/api/tenders?limit=50&sort=createdAt")
+	if got := joinNames(names); got != "ebgadfc" || pages != 1 {
+		t.Fatalf("got %q in %d pages sorted by creation", got, pages)
+	}
+}
+
+func TestPaginationCursorStaysStable(t *testing.T) {
+	env := newTestEnv(t)
+	for _, name := range []string{"a", "b", "c", "d"} {
+		env.publishTender(name, nil)
+	}
+	w := env.expect("GET", "/api/tenders?limit=2", keyBob, nil, http.StatusOK, nil)
+	next := nextLink.FindStringSubmatch(w.Header().Get("Link"))[1]
+	env.publishTender("aa", nil)
+	var tenders []Tender
+	env.expect("GET", next, keyBob, nil, http.StatusOK, &tenders)
+	if len(tenders) != 2 || tenders[0].Name != "c" || tenders[1].Name != "d" {
+		t.Fatalf("page after cursor shifted: %+v", tenders)
+	}
+}
+
+func TestPaginationInvalidParameters(t *testing.T) {
+	env := newTestEnv(t)
+	env.publishTender("a", nil)
+	w := env.expect("GET", "/api/tenders?limit=1&sort=name", keyBob, nil, http.StatusOK, nil)
+	if w.Header().Get("Link") != "" {
+		t.Fatal("last page has a next link")
+	}
+	env.publishTender("b", nil)
+	w = env.expect("GET", "/api/tenders?limit=1&sort=name", keyBob, nil, http.StatusOK, nil)
+	next, err := url.Parse(nextLink.FindStringSubmatch(w.Header().Get("Link"))[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := next.Query().Get("cursor")
+	for _, query := range []string{
+		"limit=" + strconv.Itoa(maxPageLimit+1),
+		"limit=-1",
+		"offset=x",
+		"sort=budget",
+		"cursor=not-a-cursor",
+		"cursor=" + cursor + "&sort=-name",
+		"cursor=" + cursor + "&offset=1",
+	} {
+		env.expect("GET", "/api/tenders?"+query, keyBob, nil, http.StatusBadRequest, nil)
+	}
+}
+
+func TestBidListPagination(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", map[string]interface{}{"currency": "RUB"})
+	for _, amount := range []string{"30", "10", "20"} {
+		env.submitBid(tender, map[string]interface{}{"name": amount, "amount": amount, "currency": "RUB"})
+	}
+	var bids []Bid
+	w := env.expect("GET", "/api/bids/"+tender.ID.String()+"/list?limit=2&sort=amount", keyAlice, nil, http.StatusOK, &bids)
+	if len(bids) != 2 || bids[0].Name != "10" || bids[1].Name != "20" || w.Header().Get("X-Total-Count") != "3" {
+		t.Fatalf("got %+v", bids)
+	}
+	env.expect("GET", nextLink.FindStringSubmatch(w.Header().Get("Link"))[1], keyAlice, nil, http.StatusOK, &bids)
+	if len(bids) != 1 || bids[0].Name != "30" {
+		t.Fatalf("got %+v on the second page", bids)
+	}
+}
+
+func (env *testEnv) collectFeed(path string) []string {
+	env.t.Helper()
+	var tenders []string
+	for path != "" {
+		var items []map[string]interface{}
+		w := env.expect("GET", path, keyBob, nil, http.StatusOK, &items)
+		if total := w.Header().Get("X-Total-Count"); total != "3" {
+			env.t.Fatalf("%s: got X-Total-Count %q, want 3", path, total)
+		}
+		if len(items) > 2 {
+			env.t.Fatalf("%s: got %d items, want at most 2", path, len(items))
+		}
+		for _, item := range items {
+			tenders = append(tenders, item["tenderId"].(string))
+		}
+		path = ""
+		if match := nextLink.FindStringSubmatch(w.Header().Get("Link")); match != nil {
+			path = match[1]
+		}
+	}
+	return tenders
+}
+
+func TestFeedPaginationNewestFirst(t *testing.T) {
+	feeds := []struct {
+		path  string
+		setup func(env *testEnv, tender Tender)
+	}{
+		{"/api/invitations/my", func(env *testEnv, tender Tender) {
+			env.expect("POST", "/api/tenders/"+tender.ID.String()+"/invitations", keyAlice,
+				map[string]interface{}{"inviteeType": "User", "inviteeId": testBob}, http.StatusOK, nil)
+		}},
+		{"/api/notifications/my", func(env *testEnv, tender Tender) {
+			env.submitBid(tender, nil)
+			env.expect("PUT", "/api/tenders/"+tender.ID.String()+"/cancel", keyAlice, map[string]string{"reason": "budget cut"}, http.StatusOK, nil)
+		}},
+		{"/api/contracts/my", func(env *testEnv, tender Tender) {
+			env.awardBid(tender, nil)
+		}},
+	}
+	for _, feed := range feeds {
+		t.Run(feed.path, func(t *testing.T) {
+			env := newTestEnv(t)
+			var want []string
+			for _, name := range []string{"a", "b", "c"} {
+				tender := env.publishTender(name, nil)
+				feed.setup(env, tender)
+				want = append([]string{tender.ID.String()}, want...)
+			}
+			if got := env.collectFeed(feed.path + "?limit=2"); joinNames(got) != joinNames(want) {
+				t.Fatalf("got tenders %v, want %v", got, want)
+			}
+			env.expect("GET", feed.path+"?sort=createdAt", keyBob, nil, http.StatusOK, nil)
+			env.expect("GET", feed.path+"?limit="+strconv.Itoa(maxPageLimit+1), keyBob, nil, http.StatusBadRequest, nil)
+			env.expect("GET", feed.path+"?sort=name", keyBob, nil, http.StatusBadRequest, nil)
+		})
+	}
+}
+
+func joinNames(names []string) string {
+	s := ""
+	for _, name := range names {
+		s += name
+	}
+	return s
+}
@@ -0,0 +1,1497 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+const tenderColumns = "id, name, description, service_type, status, organization_id, creator_username, version, created_at, modified_by, COALESCE(updated_at, created_at), submission_deadline, publish_at, budget::text, reserve_price::text, max_bid_amount::text, COALESCE(currency, ''), reject_above_max, sealed, unsealed_at, mode, auction_step::text, auction_extension_seconds, require_scoring, cancellation_reason, cancelled_at, private"
+
+const tenderVersionColumns = "tender_id, name, description, service_type, status, version, modified_by, modified_at, submission_deadline, publish_at, budget::text, reserve_price::text, max_bid_amount::text, COALESCE(currency, ''), reject_above_max"
+
+const bidColumns = "id, name, description, status, tender_id, author_type, author_id, organization_id, version, decision, approved_count, created_at, modified_by, COALESCE(updated_at, created_at), amount::text, COALESCE(currency, ''), line_items, sealed, sealed_data, lot_ids, withdrawal_reason, withdrawn_at"
+
+const contractColumns = "id, tender_id, bid_id, lot_id, buyer_organization_id, supplier_type, supplier_id, amount::text, COALESCE(currency, ''), status, start_date, end_date, COALESCE(buyer_signed_by, ''), buyer_signed_at, COALESCE(supplier_signed_by, ''), supplier_signed_at, COALESCE(termination_reason, ''), version, created_at, updated_at"
+
+const questionColumns = "q.id, q.tender_id, q.author_id, e.username, q.text, q.anonymous, COALESCE(q.answer, ''), COALESCE(q.answered_by, ''), q.answered_at, q.amended_version, q.created_at"
+
+const invitationColumns = "id, tender_id, invitee_type, invitee_id, status, invited_by, created_at, responded_at"
+
+const attachmentColumns = "id, parent_type, parent_id, name, mime_type, size, sha256, uploaded_by, added_in, removed_in, created_at"
+
+const lotColumns = "id, tender_id, name, description, budget::text, status, awarded_bid_id, created_at"
+
+const bidVersionColumns = "bid_id, name, description, status, decision, approved_count, version, modified_by, modified_at, amount::text, COALESCE(currency, ''), line_items, sealed_data"
+
+type postgresTransactor struct{}
+
+type postgresTenders struct{}
+
+type postgresBids struct{}
+
+type postgresLots struct{}
+
+type postgresEvaluations struct{}
+
+type postgresPolicies struct{}
+
+type postgresReviews struct{}
+
+type postgresNotifications struct{}
+
+type postgresContracts struct{}
+
+type postgresQuestions struct{}
+
+type postgresInvitations struct{}
+
+type postgresAttachments struct{}
+
+type postgresOrganizations struct{}
+
+type postgresAPIKeys struct{}
+
+func NewPostgresStorage() Storage {
+	return Storage{
+		Tx:            postgresTransactor{},
+		Tenders:       postgresTenders{},
+		Bids:          postgresBids{},
+		Lots:          postgresLots{},
+		Evaluations:   postgresEvaluations{},
+		Policies:      postgresPolicies{},
+		Reviews:       postgresReviews{},
+		Notifications: postgresNotifications{},
+		Contracts:     postgresContracts{},
+		Questions:     postgresQuestions{},
+		Invitations:   postgresInvitations{},
+		Attachments:   postgresAttachments{},
+		Organizations: postgresOrganizations{},
+		APIKeys:       postgresAPIKeys{},
+	}
+}
+
+func notFound(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func appendPage(query string, args []interface{}, page Page) (string, []interface{}) {
+	if page.Limit > 0 {
+		query += "\nLIMIT $" + strconv.Itoa(len(args)+1)
+		args = append(args, page.Limit)
+	}
+	if page.Offset > 0 {
+		query += "\nOFFSET $" + strconv.Itoa(len(args)+1)
+		args = append(args, page.Offset)
+	}
+	return query, args
+}
+
+func appendKeyset(query string, args []interface{}, page Page, prefix string) (string, []interface{}) {
+	sort := page.Sort
+	if sort == "" {
+		sort = "name"
+	}
+	column, id := prefix+sortColumns[sort], prefix+"id"
+	dir, cmp := "ASC", ">"
+	if page.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if cursor := page.After; cursor != nil {
+		args = append(args, cursor.ID)
+		after := id + " > $" + strconv.Itoa(len(args))
+		if cursor.Key == nil {
+			query += "\nAND " + column + " IS NULL AND " + after
+		} else {
+			args = append(args, *cursor.Key)
+			key := "$" + strconv.Itoa(len(args)) + "::text::" + sortKinds[sort]
+			query += "\nAND (" + column + " " + cmp + " " + key + " OR " + column + " = " + key + " AND " + after + " OR " + column + " IS NULL)"
+		}
+	}
+	query += "\nORDER BY " + column + " " + dir + " NULLS LAST, " + id + " ASC"
+	return appendPage(query, args, page)
+}
+
+func countRows(ctx context.Context, from string, args ...interface{}) (int, error) {
+	var total int
+	err := dbConn(ctx).QueryRow(ctx, "SELECT COUNT(*)"+from, args...).Scan(&total)
+	return total, err
+}
+
+func (postgresTransactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+	var tx pgx.Tx
+	var err error
+	if conn, ok := ctx.Value(connKey{}).(*pgxpool.Conn); ok {
+		tx, err = conn.Begin(ctx)
+	} else {
+		tx, err = db.Begin(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func exists(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	var exists bool
+	err := dbConn(ctx).QueryRow(ctx, query, args...).Scan(&exists)
+	return exists, err
+}
+
+func scanTender(row pgx.Row, tender *Tender) error {
+	var budget, reserve, max, step decimal.NullDecimal
+	err := row.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.CreatorUsername, &tender.Version, &tender.CreatedAt, &tender.ModifiedBy, &tender.ModifiedAt, &tender.SubmissionDeadline, &tender.PublishAt, &budget, &reserve, &max, &tender.Currency, &tender.RejectAboveMax, &tender.Sealed, &tender.UnsealedAt, &tender.Mode, &step, &tender.AuctionExtension, &tender.RequireScoring, &tender.CancellationReason, &tender.CancelledAt, &tender.Private)
+	tender.Budget, tender.ReservePrice, tender.MaxBidAmount = nullAmount(budget), nullAmount(reserve), nullAmount(max)
+	tender.AuctionStep = nullAmount(step)
+	return err
+}
+
+func scanTenderVersion(row pgx.Row, tender *Tender) error {
+	var budget, reserve, max decimal.NullDecimal
+	err := row.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Status, &tender.Version, &tender.ModifiedBy, &tender.ModifiedAt, &tender.SubmissionDeadline, &tender.PublishAt, &budget, &reserve, &max, &tender.Currency, &tender.RejectAboveMax)
+	tender.Budget, tender.ReservePrice, tender.MaxBidAmount = nullAmount(budget), nullAmount(reserve), nullAmount(max)
+	return err
+}
+
+func queryTenders(ctx context.Context, query string, args ...interface{}) ([]Tender, error) {
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tenders []Tender
+	for rows.Next() {
+		var tender Tender
+		if err := scanTender(rows, &tender); err != nil {
+			return nil, err
+		}
+		tenders = append(tenders, tender)
+	}
+	return tenders, rows.Err()
+}
+
+func (postgresTenders) Create(ctx context.Context, tender *Tender) error {
+	tender.ModifiedBy = tender.CreatorUsername
+	query := `INSERT INTO tender (name, description, service_type, organization_id, creator_username, modified_by, submission_deadline, publish_at,
+              budget, reserve_price, max_bid_amount, currency, reject_above_max, sealed, mode, auction_step, auction_extension_seconds, require_scoring, private)
+              VALUES ($1, $2, $3, $4, $5, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, $13, $14, $15, $16, $17, $18)
+              RETURNING id, status, version, created_at, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, tender.Name, tender.Description, tender.ServiceType, tender.OrganizationID, tender.CreatorUsername, tender.SubmissionDeadline, tender.PublishAt,
+		tender.Budget, tender.ReservePrice, tender.MaxBidAmount, tender.Currency, tender.RejectAboveMax, tender.Sealed,
+		tender.Mode, tender.AuctionStep, tender.AuctionExtension, tender.RequireScoring, tender.Private).Scan(&tender.ID, &tender.Status, &tender.Version, &tender.CreatedAt, &tender.ModifiedAt)
+}
+
+func (postgresTenders) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM tender
+			  WHERE id = $1);`
+	return exists(ctx, query, id)
+}
+
+func (postgresTenders) Get(ctx context.Context, id uuid.UUID) (Tender, error) {
+	var tender Tender
+	query := `SELECT ` + tenderColumns + `
+			  FROM tender t
+			  WHERE t.id = $1`
+	err := scanTender(dbConn(ctx).QueryRow(ctx, query, id), &tender)
+	return tender, notFound(err)
+}
+
+func (postgresTenders) GetForUpdate(ctx context.Context, id uuid.UUID) (Tender, error) {
+	var tender Tender
+	query := `SELECT ` + tenderColumns + `
+			  FROM tender t
+			  WHERE t.id = $1
+			  FOR UPDATE`
+	err := scanTender(dbConn(ctx).QueryRow(ctx, query, id), &tender)
+	return tender, notFound(err)
+}
+
+func (postgresTenders) ListPublished(ctx context.Context, serviceType string, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]Tender, int, error) {
+	from := "\nFROM tender\nWHERE status = 'Published'" +
+		"\nAND (NOT private OR organization_id = $1 OR EXISTS (" +
+		"\n  SELECT 1 FROM tender_invitation i WHERE i.tender_id = tender.id AND i.status <> 'Declined'" +
+		"\n  AND (i.invitee_type = 'User' AND i.invitee_id = $2 OR i.invitee_type = 'Organization' AND i.invitee_id = $1)))"
+	args := []interface{}{viewerOrganizationID, viewerID}
+	if serviceType != "" {
+		from += " AND service_type = $" + strconv.Itoa(len(args)+1)
+		args = append(args, serviceType)
+	}
+	total, err := countRows(ctx, from, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT "+tenderColumns+from, args, page, "")
+	tenders, err := queryTenders(ctx, query, args...)
+	return tenders, total, err
+}
+
+const searchQuery = "(SELECT websearch_to_tsquery('russian', $3) || websearch_to_tsquery('english', $3) AS tsq) q"
+
+const searchColumns = "ts_rank(search_vector, q.tsq)::float8 AS rank, " +
+	"CASE WHEN $3 = '' THEN '' ELSE ts_headline('russian', name || ' ' || description, q.tsq, 'StartSel=<b>, StopSel=</b>, MinWords=10, MaxWords=30, MaxFragments=2') END"
+
+const tenderVisible = "(status = 'Published' AND (NOT private OR EXISTS (" +
+	"\n  SELECT 1 FROM tender_invitation i WHERE i.tender_id = tender.id AND i.status <> 'Declined'" +
+	"\n  AND (i.invitee_type = 'User' AND i.invitee_id = $2 OR i.invitee_type = 'Organization' AND i.invitee_id = $1)))" +
+	"\nOR organization_id = $1)"
+
+type searchRow struct {
+	pgx.Row
+	extra []interface{}
+}
+
+func (r searchRow) Scan(dest ...interface{}) error {
+	return r.Row.Scan(append(dest, r.extra...)...)
+}
+
+func appendSearchFilter(query string, args []interface{}, filter SearchFilter, serviceType, amount string) (string, []interface{}) {
+	query += "\nAND ($3 = '' OR search_vector @@ q.tsq)"
+	if len(filter.Statuses) > 0 {
+		args = append(args, filter.Statuses)
+		query += " AND status = ANY($" + strconv.Itoa(len(args)) + ")"
+	}
+	if len(filter.ServiceTypes) > 0 {
+		args = append(args, filter.ServiceTypes)
+		query += " AND " + serviceType + " = ANY($" + strconv.Itoa(len(args)) + ")"
+	}
+	if filter.OrganizationID != uuid.Nil {
+		args = append(args, filter.OrganizationID)
+		query += " AND organization_id = $" + strconv.Itoa(len(args))
+	}
+	if filter.CreatedFrom != nil {
+		args = append(args, *filter.CreatedFrom)
+		query += " AND created_at >= $" + strconv.Itoa(len(args))
+	}
+	if filter.CreatedTo != nil {
+		args = append(args, *filter.CreatedTo)
+		query += " AND created_at <= $" + strconv.Itoa(len(args))
+	}
+	if filter.MinAmount != nil {
+		args = append(args, *filter.MinAmount)
+		query += " AND " + amount + " >= $" + strconv.Itoa(len(args))
+	}
+	if filter.MaxAmount != nil {
+		args = append(args, *filter.MaxAmount)
+		query += " AND " + amount + " <= $" + strconv.Itoa(len(args))
+	}
+	return query + "\nORDER BY rank DESC, name ASC", args
+}
+
+func (postgresTenders) Search(ctx context.Context, filter SearchFilter, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]TenderHit, error) {
+	query := "SELECT " + tenderColumns + ", " + searchColumns + "\nFROM tender, " + searchQuery + "\nWHERE " + tenderVisible
+	query, args := appendSearchFilter(query, []interface{}{viewerOrganizationID, viewerID, filter.Query}, filter, "service_type", "budget")
+	query, args = appendPage(query, args, page)
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []TenderHit
+	for rows.Next() {
+		var hit TenderHit
+		if err := scanTender(searchRow{rows, []interface{}{&hit.Rank, &hit.Snippet}}, &hit.Tender); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func (postgresTenders) ListByCreator(ctx context.Context, username string, page Page) ([]Tender, int, error) {
+	from := "\nFROM tender\nWHERE creator_username = $1"
+	total, err := countRows(ctx, from, username)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT "+tenderColumns+from, []interface{}{username}, page, "")
+	tenders, err := queryTenders(ctx, query, args...)
+	return tenders, total, err
+}
+
+func queryIDs(ctx context.Context, query string, args ...interface{}) ([]uuid.UUID, error) {
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (postgresTenders) ListDueForPublish(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	query := `SELECT id
+			  FROM tender
+			  WHERE status = 'Created' AND publish_at <= $1`
+	return queryIDs(ctx, query, now)
+}
+
+func (postgresTenders) ListDueForClose(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	query := `SELECT id
+			  FROM tender
+			  WHERE status = 'Published' AND submission_deadline <= $1`
+	return queryIDs(ctx, query, now)
+}
+
+func (postgresTenders) Update(ctx context.Context, tender *Tender) error {
+	query := `UPDATE tender
+			  SET name = $1, description = $2, service_type = $3, status = $4, version = $5, modified_by = $6,
+			  submission_deadline = $7, publish_at = $8, budget = $9, reserve_price = $10, max_bid_amount = $11, currency = NULLIF($12, ''),
+			  reject_above_max = $13, sealed = $14, unsealed_at = $15, mode = $16, auction_step = $17, auction_extension_seconds = $18, require_scoring = $19,
+			  cancellation_reason = $20, cancelled_at = $21, private = $22, updated_at = NOW()
+			  WHERE id = $23
+			  RETURNING version, updated_at`
+	err := dbConn(ctx).QueryRow(ctx, query, tender.Name, tender.Description, tender.ServiceType, tender.Status, tender.Version, tender.ModifiedBy, tender.SubmissionDeadline, tender.PublishAt,
+		tender.Budget, tender.ReservePrice, tender.MaxBidAmount, tender.Currency, tender.RejectAboveMax, tender.Sealed, tender.UnsealedAt,
+		tender.Mode, tender.AuctionStep, tender.AuctionExtension, tender.RequireScoring, tender.CancellationReason, tender.CancelledAt, tender.Private, tender.ID).Scan(&tender.Version, &tender.ModifiedAt)
+	return notFound(err)
+}
+
+func (postgresTenders) AddVersion(ctx context.Context, tender Tender) error {
+	query := `INSERT INTO tender_version (tender_id, version, name, description, service_type, status, modified_by, modified_at, submission_deadline, publish_at,
+              budget, reserve_price, max_bid_amount, currency, reject_above_max)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NULLIF($14, ''), $15)`
+	_, err := dbConn(ctx).Exec(ctx, query, tender.ID, tender.Version, tender.Name, tender.Description, tender.ServiceType, tender.Status, tender.ModifiedBy, tender.ModifiedAt, tender.SubmissionDeadline, tender.PublishAt,
+		tender.Budget, tender.ReservePrice, tender.MaxBidAmount, tender.Currency, tender.RejectAboveMax)
+	return err
+}
+
+func (postgresTenders) VersionExists(ctx context.Context, id uuid.UUID, version int) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM tender_version
+			  WHERE tender_id = $1 AND version = $2);`
+	return exists(ctx, query, id, version)
+}
+
+func (postgresTenders) GetVersion(ctx context.Context, id uuid.UUID, version int) (Tender, error) {
+	var tender Tender
+	query := `SELECT ` + tenderVersionColumns + `
+			  FROM tender_version t
+			  WHERE t.tender_id = $1 AND t.version = $2
+			  ORDER BY t.modified_at DESC
+			  LIMIT 1`
+	err := scanTenderVersion(dbConn(ctx).QueryRow(ctx, query, id, version), &tender)
+	return tender, notFound(err)
+}
+
+func (postgresTenders) ListVersions(ctx context.Context, id uuid.UUID) ([]Tender, error) {
+	query := `SELECT DISTINCT ON (version) ` + tenderVersionColumns + `
+			  FROM tender_version
+			  WHERE tender_id = $1
+			  ORDER BY version, modified_at DESC`
+	rows, err := dbConn(ctx).Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []Tender
+	for rows.Next() {
+		var tender Tender
+		if err := scanTenderVersion(rows, &tender); err != nil {
+			return nil, err
+		}
+		versions = append(versions, tender)
+	}
+	return versions, rows.Err()
+}
+
+func nullAmount(amount decimal.NullDecimal) *decimal.Decimal {
+	if !amount.Valid {
+		return nil
+	}
+	return &amount.Decimal
+}
+
+func lineItems(items []BidLineItem) []BidLineItem {
+	if items == nil {
+		return []BidLineItem{}
+	}
+	return items
+}
+
+func lotIDs(ids []uuid.UUID) []uuid.UUID {
+	if ids == nil {
+		return []uuid.UUID{}
+	}
+	return ids
+}
+
+func nullID(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+func (postgresTenders) AddUnsealEvent(ctx context.Context, event *UnsealEvent) error {
+	query := `INSERT INTO tender_unseal_event (tender_id, bid_count, reason, unsealed_by)
+              VALUES ($1, $2, $3, $4)
+              RETURNING id, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, event.TenderID, event.BidCount, event.Reason, event.UnsealedBy).Scan(&event.ID, &event.CreatedAt)
+}
+
+func (postgresTenders) GetUnsealEvent(ctx context.Context, tenderID uuid.UUID) (UnsealEvent, error) {
+	var event UnsealEvent
+	query := `SELECT id, tender_id, bid_count, reason, unsealed_by, created_at
+			  FROM tender_unseal_event
+			  WHERE tender_id = $1
+			  ORDER BY created_at DESC
+			  LIMIT 1`
+	err := dbConn(ctx).QueryRow(ctx, query, tenderID).Scan(&event.ID, &event.TenderID, &event.BidCount, &event.Reason, &event.UnsealedBy, &event.CreatedAt)
+	return event, notFound(err)
+}
+
+func scanBid(row pgx.Row, bid *Bid) error {
+	var amount decimal.NullDecimal
+	err := row.Scan(&bid.ID, &bid.Name, &bid.Description, &bid.Status, &bid.TenderID, &bid.AuthorType, &bid.AuthorID, &bid.OrganizationID, &bid.Version, &bid.Decision, &bid.ApprovedCount, &bid.CreatedAt, &bid.ModifiedBy, &bid.ModifiedAt, &amount, &bid.Currency, &bid.LineItems, &bid.Sealed, &bid.SealedData, &bid.LotIDs, &bid.WithdrawalReason, &bid.WithdrawnAt)
+	bid.Amount = nullAmount(amount)
+	return err
+}
+
+func scanBidVersion(row pgx.Row, bid *Bid) error {
+	var amount decimal.NullDecimal
+	err := row.Scan(&bid.ID, &bid.Name, &bid.Description, &bid.Status, &bid.Decision, &bid.ApprovedCount, &bid.Version, &bid.ModifiedBy, &bid.ModifiedAt, &amount, &bid.Currency, &bid.LineItems, &bid.SealedData)
+	bid.Amount = nullAmount(amount)
+	return err
+}
+
+func queryBids(ctx context.Context, query string, args ...interface{}) ([]Bid, error) {
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var bids []Bid
+	for rows.Next() {
+		var bid Bid
+		if err := scanBid(rows, &bid); err != nil {
+			return nil, err
+		}
+		bids = append(bids, bid)
+	}
+	return bids, rows.Err()
+}
+
+func (postgresBids) Create(ctx context.Context, bid *Bid) error {
+	query := `INSERT INTO bid (name, description, tender_id, author_type, author_id, organization_id, modified_by, amount, currency, line_items, sealed, sealed_data, lot_ids)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12, $13)
+              RETURNING id, status, version, decision, approved_count, created_at, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, bid.Name, bid.Description, bid.TenderID, bid.AuthorType, bid.AuthorID, bid.OrganizationID, bid.ModifiedBy, bid.Amount, bid.Currency, lineItems(bid.LineItems), bid.Sealed, bid.SealedData, lotIDs(bid.LotIDs)).Scan(&bid.ID, &bid.Status, &bid.Version, &bid.Decision, &bid.ApprovedCount, &bid.CreatedAt, &bid.ModifiedAt)
+}
+
+func (postgresBids) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM bid
+			  WHERE id = $1);`
+	return exists(ctx, query, id)
+}
+
+func (postgresBids) Get(ctx context.Context, id uuid.UUID) (Bid, error) {
+	var bid Bid
+	query := `SELECT ` + bidColumns + `
+			  FROM bid b
+			  WHERE b.id = $1`
+	err := scanBid(dbConn(ctx).QueryRow(ctx, query, id), &bid)
+	return bid, notFound(err)
+}
+
+func (postgresBids) GetForUpdate(ctx context.Context, id uuid.UUID) (Bid, error) {
+	var bid Bid
+	query := `SELECT ` + bidColumns + `
+			  FROM bid b
+			  WHERE b.id = $1
+			  FOR UPDATE`
+	err := scanBid(dbConn(ctx).QueryRow(ctx, query, id), &bid)
+	return bid, notFound(err)
+}
+
+func (postgresBids) ListByAuthor(ctx context.Context, authorID uuid.UUID, page Page) ([]Bid, int, error) {
+	from := "\nFROM bid\nWHERE author_id = $1"
+	total, err := countRows(ctx, from, authorID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT "+bidColumns+from, []interface{}{authorID}, page, "")
+	bids, err := queryBids(ctx, query, args...)
+	return bids, total, err
+}
+
+func (postgresBids) ListForTender(ctx context.Context, tenderID, organizationID uuid.UUID, filter BidFilter, page Page) ([]Bid, int, error) {
+	from := "\nFROM bid\nWHERE (status = 'Published' OR organization_id = $1) AND tender_id = $2"
+	args := []interface{}{organizationID, tenderID}
+	if filter.Currency != "" {
+		args = append(args, filter.Currency)
+		from += " AND currency = $" + strconv.Itoa(len(args))
+	}
+	if filter.LotID != uuid.Nil {
+		args = append(args, filter.LotID.String())
+		from += " AND lot_ids @> jsonb_build_array($" + strconv.Itoa(len(args)) + "::text)"
+	}
+	if filter.MinAmount != nil {
+		args = append(args, *filter.MinAmount)
+		from += " AND amount >= $" + strconv.Itoa(len(args))
+	}
+	if filter.MaxAmount != nil {
+		args = append(args, *filter.MaxAmount)
+		from += " AND amount <= $" + strconv.Itoa(len(args))
+	}
+	total, err := countRows(ctx, from, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT "+bidColumns+from, args, page, "")
+	bids, err := queryBids(ctx, query, args...)
+	return bids, total, err
+}
+
+func (postgresBids) Search(ctx context.Context, filter SearchFilter, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]BidHit, error) {
+	query := "SELECT " + bidColumns + ", " + searchColumns + "\nFROM bid, " + searchQuery +
+		"\nWHERE NOT sealed AND (author_id IN ($1, $2) OR organization_id = $1" +
+		"\nOR status = 'Published' AND tender_id IN (SELECT id FROM tender WHERE " + tenderVisible + "))"
+	query, args := appendSearchFilter(query, []interface{}{viewerOrganizationID, viewerID, filter.Query}, filter,
+		"(SELECT service_type FROM tender WHERE tender.id = bid.tender_id)", "amount")
+	query, args = appendPage(query, args, page)
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hits []BidHit
+	for rows.Next() {
+		var hit BidHit
+		if err := scanBid(searchRow{rows, []interface{}{&hit.Rank, &hit.Snippet}}, &hit.Bid); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+func (postgresBids) ListSealed(ctx context.Context, tenderID uuid.UUID) ([]Bid, error) {
+	query := "SELECT " + bidColumns + "\nFROM bid\nWHERE tender_id = $1 AND sealed"
+	return queryBids(ctx, query, tenderID)
+}
+
+func (postgresBids) ListRanked(ctx context.Context, tenderID uuid.UUID) ([]Bid, error) {
+	query := "SELECT " + bidColumns + "\nFROM bid\nWHERE tender_id = $1 AND amount IS NOT NULL AND status = 'Published' AND decision = 'None'\nORDER BY amount ASC, COALESCE(updated_at, created_at) ASC"
+	return queryBids(ctx, query, tenderID)
+}
+
+func (postgresBids) CountForTender(ctx context.Context, tenderID uuid.UUID) (int, error) {
+	var count int
+	query := `SELECT COUNT(*)
+			  FROM bid
+			  WHERE tender_id = $1`
+	err := dbConn(ctx).QueryRow(ctx, query, tenderID).Scan(&count)
+	return count, err
+}
+
+func (postgresBids) Update(ctx context.Context, bid *Bid) error {
+	query := `UPDATE bid
+			  SET name = $1, description = $2, status = $3, decision = $4, approved_count = $5, version = $6, modified_by = $7,
+			  amount = $8, currency = NULLIF($9, ''), line_items = $10, sealed = $11, sealed_data = $12,
+			  withdrawal_reason = $13, withdrawn_at = $14, updated_at = NOW()
+			  WHERE id = $15
+			  RETURNING version, updated_at`
+	err := dbConn(ctx).QueryRow(ctx, query, bid.Name, bid.Description, bid.Status, bid.Decision, bid.ApprovedCount, bid.Version, bid.ModifiedBy, bid.Amount, bid.Currency, lineItems(bid.LineItems), bid.Sealed, bid.SealedData, bid.WithdrawalReason, bid.WithdrawnAt, bid.ID).Scan(&bid.Version, &bid.ModifiedAt)
+	return notFound(err)
+}
+
+func (postgresBids) AddVersion(ctx context.Context, bid Bid) error {
+	query := `INSERT INTO bid_version (bid_id, version, name, description, decision, approved_count, status, modified_by, modified_at, amount, currency, line_items, sealed_data)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, $13)`
+	_, err := dbConn(ctx).Exec(ctx, query, bid.ID, bid.Version, bid.Name, bid.Description, bid.Decision, bid.ApprovedCount, bid.Status, bid.ModifiedBy, bid.ModifiedAt, bid.Amount, bid.Currency, lineItems(bid.LineItems), bid.SealedData)
+	return err
+}
+
+func (postgresBids) VersionExists(ctx context.Context, id uuid.UUID, version int) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM bid_version
+			  WHERE bid_id = $1 AND version = $2);`
+	return exists(ctx, query, id, version)
+}
+
+func (postgresBids) GetVersion(ctx context.Context, id uuid.UUID, version int) (Bid, error) {
+	var bid Bid
+	query := `SELECT ` + bidVersionColumns + `
+			  FROM bid_version
+			  WHERE bid_id = $1 AND version = $2
+			  ORDER BY modified_at DESC
+			  LIMIT 1`
+	err := scanBidVersion(dbConn(ctx).QueryRow(ctx, query, id, version), &bid)
+	return bid, notFound(err)
+}
+
+func (postgresBids) ListVersions(ctx context.Context, id uuid.UUID) ([]Bid, error) {
+	query := `SELECT DISTINCT ON (version) ` + bidVersionColumns + `
+			  FROM bid_version
+			  WHERE bid_id = $1
+			  ORDER BY version, modified_at DESC`
+	rows, err := dbConn(ctx).Query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var versions []Bid
+	for rows.Next() {
+		var bid Bid
+		if err := scanBidVersion(rows, &bid); err != nil {
+			return nil, err
+		}
+		versions = append(versions, bid)
+	}
+	return versions, rows.Err()
+}
+
+func (postgresBids) VoteExists(ctx context.Context, bidID, lotID uuid.UUID, username string) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM bid_approve
+			  WHERE bid_id = $1 AND username = $2 AND lot_id IS NOT DISTINCT FROM $3);`
+	return exists(ctx, query, bidID, username, nullID(lotID))
+}
+
+func (postgresBids) AddVote(ctx context.Context, vote *ApprovalVote) error {
+	query := `INSERT INTO bid_approve (bid_id, username, lot_id, decision)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING created_at`
+	return dbConn(ctx).QueryRow(ctx, query, vote.BidID, vote.Username, nullID(vote.LotID), vote.Decision).Scan(&vote.CreatedAt)
+}
+
+func (postgresBids) ListVotes(ctx context.Context, bidID, lotID uuid.UUID) ([]ApprovalVote, error) {
+	query := `SELECT username, decision, created_at
+			  FROM bid_approve
+			  WHERE bid_id = $1 AND lot_id IS NOT DISTINCT FROM $2
+			  ORDER BY created_at`
+	rows, err := dbConn(ctx).Query(ctx, query, bidID, nullID(lotID))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var votes []ApprovalVote
+	for rows.Next() {
+		vote := ApprovalVote{BidID: bidID, LotID: lotID}
+		if err := rows.Scan(&vote.Username, &vote.Decision, &vote.CreatedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+func scanLot(row pgx.Row, lot *Lot) error {
+	var budget decimal.NullDecimal
+	err := row.Scan(&lot.ID, &lot.TenderID, &lot.Name, &lot.Description, &budget, &lot.Status, &lot.AwardedBidID, &lot.CreatedAt)
+	lot.Budget = nullAmount(budget)
+	return err
+}
+
+func (postgresLots) Create(ctx context.Context, lot *Lot) error {
+	query := `INSERT INTO tender_lot (tender_id, name, description, budget)
+              VALUES ($1, $2, $3, $4)
+              RETURNING id, status, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, lot.TenderID, lot.Name, lot.Description, lot.Budget).Scan(&lot.ID, &lot.Status, &lot.CreatedAt)
+}
+
+func (postgresLots) Get(ctx context.Context, id uuid.UUID) (Lot, error) {
+	var lot Lot
+	query := `SELECT ` + lotColumns + `
+			  FROM tender_lot
+			  WHERE id = $1`
+	err := scanLot(dbConn(ctx).QueryRow(ctx, query, id), &lot)
+	return lot, notFound(err)
+}
+
+func (postgresLots) GetForUpdate(ctx context.Context, id uuid.UUID) (Lot, error) {
+	var lot Lot
+	query := `SELECT ` + lotColumns + `
+			  FROM tender_lot
+			  WHERE id = $1
+			  FOR UPDATE`
+	err := scanLot(dbConn(ctx).QueryRow(ctx, query, id), &lot)
+	return lot, notFound(err)
+}
+
+func (postgresLots) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Lot, error) {
+	query := `SELECT ` + lotColumns + `
+			  FROM tender_lot
+			  WHERE tender_id = $1
+			  ORDER BY created_at, id`
+	rows, err := dbConn(ctx).Query(ctx, query, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var lots []Lot
+	for rows.Next() {
+		var lot Lot
+		if err := scanLot(rows, &lot); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+func (postgresLots) Update(ctx context.Context, lot *Lot) error {
+	query := `UPDATE tender_lot
+			  SET name = $1, description = $2, budget = $3, status = $4, awarded_bid_id = $5, updated_at = NOW()
+			  WHERE id = $6`
+	tag, err := dbConn(ctx).Exec(ctx, query, lot.Name, lot.Description, lot.Budget, lot.Status, lot.AwardedBidID, lot.ID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func scanCriterion(row pgx.Row, criterion *Criterion) error {
+	var weight string
+	err := row.Scan(&criterion.ID, &criterion.TenderID, &criterion.Name, &criterion.Description, &weight, &criterion.MaxScore, &criterion.CreatedAt)
+	if err != nil {
+		return err
+	}
+	criterion.Weight, err = decimal.NewFromString(weight)
+	return err
+}
+
+func (postgresEvaluations) CreateCriterion(ctx context.Context, criterion *Criterion) error {
+	query := `INSERT INTO tender_criterion (tender_id, name, description, weight, max_score)
+              VALUES ($1, $2, $3, $4, $5)
+              RETURNING id, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, criterion.TenderID, criterion.Name, criterion.Description, criterion.Weight, criterion.MaxScore).Scan(&criterion.ID, &criterion.CreatedAt)
+}
+
+func (postgresEvaluations) GetCriterion(ctx context.Context, id uuid.UUID) (Criterion, error) {
+	var criterion Criterion
+	query := `SELECT id, tender_id, name, description, weight::text, max_score, created_at
+			  FROM tender_criterion
+			  WHERE id = $1`
+	err := scanCriterion(dbConn(ctx).QueryRow(ctx, query, id), &criterion)
+	return criterion, notFound(err)
+}
+
+func (postgresEvaluations) ListCriteria(ctx context.Context, tenderID uuid.UUID) ([]Criterion, error) {
+	query := `SELECT id, tender_id, name, description, weight::text, max_score, created_at
+			  FROM tender_criterion
+			  WHERE tender_id = $1
+			  ORDER BY created_at, id`
+	rows, err := dbConn(ctx).Query(ctx, query, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var criteria []Criterion
+	for rows.Next() {
+		var criterion Criterion
+		if err := scanCriterion(rows, &criterion); err != nil {
+			return nil, err
+		}
+		criteria = append(criteria, criterion)
+	}
+	return criteria, rows.Err()
+}
+
+func (postgresEvaluations) DeleteCriterion(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM tender_criterion
+			  WHERE id = $1`
+	tag, err := dbConn(ctx).Exec(ctx, query, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (postgresEvaluations) TenderHasScores(ctx context.Context, tenderID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM bid_score s
+			  JOIN tender_criterion c ON c.id = s.criterion_id
+			  WHERE c.tender_id = $1);`
+	return exists(ctx, query, tenderID)
+}
+
+func (postgresEvaluations) SetScore(ctx context.Context, score *BidScore) error {
+	query := `INSERT INTO bid_score (bid_id, criterion_id, username, score, comment)
+			  VALUES ($1, $2, $3, $4, $5)
+			  ON CONFLICT (bid_id, criterion_id, username)
+			  DO UPDATE SET score = EXCLUDED.score, comment = EXCLUDED.comment, updated_at = NOW()
+			  RETURNING updated_at`
+	return dbConn(ctx).QueryRow(ctx, query, score.BidID, score.CriterionID, score.Username, score.Score, score.Comment).Scan(&score.UpdatedAt)
+}
+
+func queryScores(ctx context.Context, query string, args ...interface{}) ([]BidScore, error) {
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var scores []BidScore
+	for rows.Next() {
+		var score BidScore
+		var value string
+		if err := rows.Scan(&score.BidID, &score.CriterionID, &score.Username, &value, &score.Comment, &score.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if score.Score, err = decimal.NewFromString(value); err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+	return scores, rows.Err()
+}
+
+func (postgresEvaluations) ListScores(ctx context.Context, tenderID uuid.UUID) ([]BidScore, error) {
+	query := `SELECT s.bid_id, s.criterion_id, s.username, s.score::text, s.comment, s.updated_at
+			  FROM bid_score s
+			  JOIN tender_criterion c ON c.id = s.criterion_id
+			  WHERE c.tender_id = $1
+			  ORDER BY s.bid_id, c.created_at, s.username`
+	return queryScores(ctx, query, tenderID)
+}
+
+func (postgresEvaluations) ListBidScores(ctx context.Context, bidID uuid.UUID) ([]BidScore, error) {
+	query := `SELECT s.bid_id, s.criterion_id, s.username, s.score::text, s.comment, s.updated_at
+			  FROM bid_score s
+			  JOIN tender_criterion c ON c.id = s.criterion_id
+			  WHERE s.bid_id = $1
+			  ORDER BY c.created_at, s.username`
+	return queryScores(ctx, query, bidID)
+}
+
+func getPolicy(ctx context.Context, column string, id uuid.UUID) (ApprovalPolicy, error) {
+	var policy ApprovalPolicy
+	var updatedAt time.Time
+	query := `SELECT policy, updated_by, updated_at
+			  FROM approval_policy
+			  WHERE ` + column + ` = $1`
+	err := dbConn(ctx).QueryRow(ctx, query, id).Scan(&policy, &policy.UpdatedBy, &updatedAt)
+	if err != nil {
+		return policy, notFound(err)
+	}
+	policy.UpdatedAt = &updatedAt
+	return policy, nil
+}
+
+func setPolicy(ctx context.Context, column string, id uuid.UUID, policy *ApprovalPolicy) error {
+	var updatedAt time.Time
+	query := `INSERT INTO approval_policy (` + column + `, policy, updated_by)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT (` + column + `)
+			  DO UPDATE SET policy = EXCLUDED.policy, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+			  RETURNING updated_at`
+	err := dbConn(ctx).QueryRow(ctx, query, id, policy, policy.UpdatedBy).Scan(&updatedAt)
+	policy.UpdatedAt = &updatedAt
+	return err
+}
+
+func (postgresPolicies) GetOrganizationPolicy(ctx context.Context, organizationID uuid.UUID) (ApprovalPolicy, error) {
+	return getPolicy(ctx, "organization_id", organizationID)
+}
+
+func (postgresPolicies) SetOrganizationPolicy(ctx context.Context, organizationID uuid.UUID, policy *ApprovalPolicy) error {
+	return setPolicy(ctx, "organization_id", organizationID, policy)
+}
+
+func (postgresPolicies) GetTenderPolicy(ctx context.Context, tenderID uuid.UUID) (ApprovalPolicy, error) {
+	return getPolicy(ctx, "tender_id", tenderID)
+}
+
+func (postgresPolicies) SetTenderPolicy(ctx context.Context, tenderID uuid.UUID, policy *ApprovalPolicy) error {
+	return setPolicy(ctx, "tender_id", tenderID, policy)
+}
+
+func (postgresPolicies) DeleteTenderPolicy(ctx context.Context, tenderID uuid.UUID) error {
+	tag, err := dbConn(ctx).Exec(ctx, `DELETE FROM approval_policy WHERE tender_id = $1`, tenderID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (postgresReviews) Create(ctx context.Context, bidID uuid.UUID, username, text string) (BidReview, error) {
+	review := BidReview{Description: text}
+	query := `INSERT INTO bid_review (bid_id, username, review)
+              VALUES ($1, $2, $3)
+              RETURNING id, created_at`
+	err := dbConn(ctx).QueryRow(ctx, query, bidID, username, text).Scan(&review.ID, &review.CreatedAt)
+	return review, err
+}
+
+func (postgresReviews) Get(ctx context.Context, id uuid.UUID) (BidReview, error) {
+	var review BidReview
+	query := `SELECT id, review, created_at, bid_id, username
+			  FROM bid_review
+			  WHERE id = $1`
+	err := dbConn(ctx).QueryRow(ctx, query, id).Scan(&review.ID, &review.Description, &review.CreatedAt, &review.BidID, &review.Username)
+	return review, notFound(err)
+}
+
+func (postgresReviews) ListByAuthor(ctx context.Context, authorID, excludeTenderID uuid.UUID, page Page) ([]BidReview, int, error) {
+	from := `
+			  FROM bid b
+			  JOIN bid_review br
+			  ON b.id = br.bid_id
+			  WHERE author_id = $1 and author_type = 'User' and tender_id != $2`
+	total, err := countRows(ctx, from, authorID, excludeTenderID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT br.id, br.review, br.created_at"+from, []interface{}{authorID, excludeTenderID}, page, "br.")
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var reviews []BidReview
+	for rows.Next() {
+		var br BidReview
+		if err := rows.Scan(&br.ID, &br.Description, &br.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		reviews = append(reviews, br)
+	}
+	return reviews, total, rows.Err()
+}
+
+func (postgresNotifications) Create(ctx context.Context, notification *Notification) error {
+	query := `INSERT INTO notification (event, recipient_type, recipient_id, tender_id, bid_id, message)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, notification.Event, notification.RecipientType, notification.RecipientID, notification.TenderID, notification.BidID, notification.Message).Scan(&notification.ID, &notification.CreatedAt)
+}
+
+func (postgresNotifications) ListForRecipient(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Notification, int, error) {
+	from := `
+			  FROM notification
+			  WHERE ((recipient_type = 'User' AND recipient_id = $1)
+			  OR (recipient_type = 'Organization' AND recipient_id = $2))`
+	total, err := countRows(ctx, from, userID, organizationID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT id, event, recipient_type, recipient_id, tender_id, bid_id, message, created_at"+from, []interface{}{userID, organizationID}, page, "")
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var notifications []Notification
+	for rows.Next() {
+		var notification Notification
+		if err := rows.Scan(&notification.ID, &notification.Event, &notification.RecipientType, &notification.RecipientID, &notification.TenderID, &notification.BidID, &notification.Message, &notification.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, total, rows.Err()
+}
+
+func scanContract(row pgx.Row, contract *Contract) error {
+	var amount decimal.NullDecimal
+	err := row.Scan(&contract.ID, &contract.TenderID, &contract.BidID, &contract.LotID, &contract.BuyerID, &contract.SupplierType, &contract.SupplierID,
+		&amount, &contract.Currency, &contract.Status, &contract.StartDate, &contract.EndDate, &contract.BuyerSignedBy, &contract.BuyerSignedAt,
+		&contract.SupplierSignedBy, &contract.SupplierSignedAt, &contract.TerminationReason, &contract.Version, &contract.CreatedAt, &contract.UpdatedAt)
+	contract.Amount = nullAmount(amount)
+	return err
+}
+
+func queryContracts(ctx context.Context, query string, args ...interface{}) ([]Contract, error) {
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var contracts []Contract
+	for rows.Next() {
+		var contract Contract
+		if err := scanContract(rows, &contract); err != nil {
+			return nil, err
+		}
+		contracts = append(contracts, contract)
+	}
+	return contracts, rows.Err()
+}
+
+func (postgresContracts) Create(ctx context.Context, contract *Contract) error {
+	query := `INSERT INTO contract (tender_id, bid_id, lot_id, buyer_organization_id, supplier_type, supplier_id, amount, currency, status)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+			  RETURNING id, version, created_at, updated_at`
+	return dbConn(ctx).QueryRow(ctx, query, contract.TenderID, contract.BidID, contract.LotID, contract.BuyerID, contract.SupplierType, contract.SupplierID,
+		contract.Amount, contract.Currency, contract.Status).Scan(&contract.ID, &contract.Version, &contract.CreatedAt, &contract.UpdatedAt)
+}
+
+func (postgresContracts) Get(ctx context.Context, id uuid.UUID) (Contract, error) {
+	var contract Contract
+	query := `SELECT ` + contractColumns + `
+			  FROM contract
+			  WHERE id = $1`
+	err := scanContract(dbConn(ctx).QueryRow(ctx, query, id), &contract)
+	return contract, notFound(err)
+}
+
+func (postgresContracts) GetForUpdate(ctx context.Context, id uuid.UUID) (Contract, error) {
+	var contract Contract
+	query := `SELECT ` + contractColumns + `
+			  FROM contract
+			  WHERE id = $1
+			  FOR UPDATE`
+	err := scanContract(dbConn(ctx).QueryRow(ctx, query, id), &contract)
+	return contract, notFound(err)
+}
+
+func (postgresContracts) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Contract, error) {
+	query := `SELECT ` + contractColumns + `
+			  FROM contract
+			  WHERE tender_id = $1
+			  ORDER BY created_at DESC, id`
+	return queryContracts(ctx, query, tenderID)
+}
+
+func (postgresContracts) ListForParty(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Contract, int, error) {
+	from := `
+			  FROM contract
+			  WHERE (buyer_organization_id = $2
+			  OR (supplier_type = 'User' AND supplier_id = $1)
+			  OR (supplier_type = 'Organization' AND supplier_id = $2))`
+	total, err := countRows(ctx, from, userID, organizationID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT "+contractColumns+from, []interface{}{userID, organizationID}, page, "")
+	contracts, err := queryContracts(ctx, query, args...)
+	return contracts, total, err
+}
+
+func (postgresContracts) Update(ctx context.Context, contract *Contract) error {
+	query := `UPDATE contract
+			  SET amount = $1, currency = NULLIF($2, ''), status = $3, start_date = $4, end_date = $5,
+			  buyer_signed_by = NULLIF($6, ''), buyer_signed_at = $7, supplier_signed_by = NULLIF($8, ''), supplier_signed_at = $9,
+			  termination_reason = NULLIF($10, ''), version = $11, updated_at = NOW()
+			  WHERE id = $12
+			  RETURNING updated_at`
+	err := dbConn(ctx).QueryRow(ctx, query, contract.Amount, contract.Currency, contract.Status, contract.StartDate, contract.EndDate,
+		contract.BuyerSignedBy, contract.BuyerSignedAt, contract.SupplierSignedBy, contract.SupplierSignedAt,
+		contract.TerminationReason, contract.Version, contract.ID).Scan(&contract.UpdatedAt)
+	return notFound(err)
+}
+
+func scanQuestion(row pgx.Row, question *Question) error {
+	return row.Scan(&question.ID, &question.TenderID, &question.AuthorID, &question.AuthorUsername, &question.Text, &question.Anonymous,
+		&question.Answer, &question.AnsweredBy, &question.AnsweredAt, &question.AmendedVersion, &question.CreatedAt)
+}
+
+func (postgresQuestions) Create(ctx context.Context, question *Question) error {
+	query := `INSERT INTO tender_question (tender_id, author_id, text, anonymous)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, question.TenderID, question.AuthorID, question.Text, question.Anonymous).Scan(&question.ID, &question.CreatedAt)
+}
+
+func (postgresQuestions) Get(ctx context.Context, id uuid.UUID) (Question, error) {
+	var question Question
+	query := `SELECT ` + questionColumns + `
+			  FROM tender_question q
+			  JOIN employee e ON e.id = q.author_id
+			  WHERE q.id = $1`
+	err := scanQuestion(dbConn(ctx).QueryRow(ctx, query, id), &question)
+	return question, notFound(err)
+}
+
+func (postgresQuestions) GetForUpdate(ctx context.Context, id uuid.UUID) (Question, error) {
+	var question Question
+	query := `SELECT ` + questionColumns + `
+			  FROM tender_question q
+			  JOIN employee e ON e.id = q.author_id
+			  WHERE q.id = $1
+			  FOR UPDATE OF q`
+	err := scanQuestion(dbConn(ctx).QueryRow(ctx, query, id), &question)
+	return question, notFound(err)
+}
+
+func (postgresQuestions) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Question, error) {
+	query := `SELECT ` + questionColumns + `
+			  FROM tender_question q
+			  JOIN employee e ON e.id = q.author_id
+			  WHERE q.tender_id = $1
+			  ORDER BY q.created_at, q.id`
+	rows, err := dbConn(ctx).Query(ctx, query, tenderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var questions []Question
+	for rows.Next() {
+		var question Question
+		if err := scanQuestion(rows, &question); err != nil {
+			return nil, err
+		}
+		questions = append(questions, question)
+	}
+	return questions, rows.Err()
+}
+
+func (postgresQuestions) Update(ctx context.Context, question *Question) error {
+	query := `UPDATE tender_question
+			  SET anonymous = $1, answer = $2, answered_by = $3, answered_at = $4, amended_version = $5
+			  WHERE id = $6`
+	tag, err := dbConn(ctx).Exec(ctx, query, question.Anonymous, question.Answer, question.AnsweredBy, question.AnsweredAt, question.AmendedVersion, question.ID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func scanInvitation(row pgx.Row, invitation *Invitation) error {
+	return row.Scan(&invitation.ID, &invitation.TenderID, &invitation.InviteeType, &invitation.InviteeID, &invitation.Status, &invitation.InvitedBy, &invitation.CreatedAt, &invitation.RespondedAt)
+}
+
+func queryInvitations(ctx context.Context, query string, args ...interface{}) ([]Invitation, error) {
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var invitations []Invitation
+	for rows.Next() {
+		var invitation Invitation
+		if err := scanInvitation(rows, &invitation); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+	return invitations, rows.Err()
+}
+
+func (postgresInvitations) Create(ctx context.Context, invitation *Invitation) error {
+	query := `INSERT INTO tender_invitation (tender_id, invitee_type, invitee_id, invited_by)
+			  VALUES ($1, $2, $3, $4)
+			  RETURNING id, status, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, invitation.TenderID, invitation.InviteeType, invitation.InviteeID, invitation.InvitedBy).Scan(&invitation.ID, &invitation.Status, &invitation.CreatedAt)
+}
+
+func (postgresInvitations) Get(ctx context.Context, id uuid.UUID) (Invitation, error) {
+	var invitation Invitation
+	query := `SELECT ` + invitationColumns + `
+			  FROM tender_invitation
+			  WHERE id = $1`
+	err := scanInvitation(dbConn(ctx).QueryRow(ctx, query, id), &invitation)
+	return invitation, notFound(err)
+}
+
+func (postgresInvitations) Find(ctx context.Context, tenderID uuid.UUID, inviteeType string, inviteeID uuid.UUID) (Invitation, error) {
+	var invitation Invitation
+	query := `SELECT ` + invitationColumns + `
+			  FROM tender_invitation
+			  WHERE tender_id = $1 AND invitee_type = $2 AND invitee_id = $3`
+	err := scanInvitation(dbConn(ctx).QueryRow(ctx, query, tenderID, inviteeType, inviteeID), &invitation)
+	return invitation, notFound(err)
+}
+
+func (postgresInvitations) ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Invitation, error) {
+	query := `SELECT ` + invitationColumns + `
+			  FROM tender_invitation
+			  WHERE tender_id = $1
+			  ORDER BY created_at DESC, id`
+	return queryInvitations(ctx, query, tenderID)
+}
+
+func (postgresInvitations) ListForInvitee(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Invitation, int, error) {
+	from := `
+			  FROM tender_invitation
+			  WHERE ((invitee_type = 'User' AND invitee_id = $1)
+			  OR (invitee_type = 'Organization' AND invitee_id = $2))`
+	total, err := countRows(ctx, from, userID, organizationID)
+	if err != nil {
+		return nil, 0, err
+	}
+	query, args := appendKeyset("SELECT "+invitationColumns+from, []interface{}{userID, organizationID}, page, "")
+	invitations, err := queryInvitations(ctx, query, args...)
+	return invitations, total, err
+}
+
+func (postgresInvitations) Update(ctx context.Context, invitation *Invitation) error {
+	query := `UPDATE tender_invitation
+			  SET status = $1, responded_at = $2
+			  WHERE id = $3`
+	tag, err := dbConn(ctx).Exec(ctx, query, invitation.Status, invitation.RespondedAt, invitation.ID)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (postgresInvitations) Delete(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM tender_invitation
+			  WHERE id = $1`
+	tag, err := dbConn(ctx).Exec(ctx, query, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func scanAttachment(row pgx.Row, attachment *Attachment) error {
+	return row.Scan(&attachment.ID, &attachment.ParentType, &attachment.ParentID, &attachment.Name, &attachment.MimeType, &attachment.Size,
+		&attachment.SHA256, &attachment.UploadedBy, &attachment.AddedIn, &attachment.RemovedIn, &attachment.CreatedAt)
+}
+
+func (postgresAttachments) Create(ctx context.Context, attachment *Attachment) error {
+	query := `INSERT INTO attachment (parent_type, parent_id, name, mime_type, size, sha256, uploaded_by, added_in)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			  RETURNING id, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, attachment.ParentType, attachment.ParentID, attachment.Name, attachment.MimeType, attachment.Size,
+		attachment.SHA256, attachment.UploadedBy, attachment.AddedIn).Scan(&attachment.ID, &attachment.CreatedAt)
+}
+
+func (postgresAttachments) Get(ctx context.Context, id uuid.UUID) (Attachment, error) {
+	var attachment Attachment
+	query := `SELECT ` + attachmentColumns + `
+			  FROM attachment
+			  WHERE id = $1`
+	err := scanAttachment(dbConn(ctx).QueryRow(ctx, query, id), &attachment)
+	return attachment, notFound(err)
+}
+
+func (postgresAttachments) ListActive(ctx context.Context, parentType string, parentID uuid.UUID, version int) ([]Attachment, error) {
+	query := `SELECT ` + attachmentColumns + `
+			  FROM attachment
+			  WHERE parent_type = $1 AND parent_id = $2
+			  AND added_in <= $3 AND (removed_in IS NULL OR removed_in > $3)
+			  ORDER BY created_at, id`
+	rows, err := dbConn(ctx).Query(ctx, query, parentType, parentID, version)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var attachments []Attachment
+	for rows.Next() {
+		var attachment Attachment
+		if err := scanAttachment(rows, &attachment); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func (postgresAttachments) Remove(ctx context.Context, id uuid.UUID, version int) error {
+	query := `UPDATE attachment
+			  SET removed_in = $1
+			  WHERE id = $2`
+	tag, err := dbConn(ctx).Exec(ctx, query, version, id)
+	if err == nil && tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (postgresOrganizations) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM organization
+			  WHERE id = $1);`
+	return exists(ctx, query, id)
+}
+
+func (postgresOrganizations) UserExists(ctx context.Context, username string) (bool, error) {
+	query := `SELECT EXISTS (
+			  SELECT 1
+			  FROM employee
+			  WHERE username = $1);`
+	return exists(ctx, query, username)
+}
+
+func (postgresOrganizations) GetUserID(ctx context.Context, username string) (uuid.UUID, error) {
+	var id uuid.UUID
+	query := `SELECT id
+			  FROM employee e
+			  WHERE e.username = $1`
+	err := dbConn(ctx).QueryRow(ctx, query, username).Scan(&id)
+	return id, notFound(err)
+}
+
+func (postgresOrganizations) GetUsername(ctx context.Context, userID uuid.UUID) (string, error) {
+	username := ""
+	query := `SELECT username
+			  FROM employee e
+			  WHERE e.id = $1`
+	err := dbConn(ctx).QueryRow(ctx, query, userID).Scan(&username)
+	return username, notFound(err)
+}
+
+func (postgresOrganizations) GetUserOrganization(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	var id uuid.UUID
+	query := `SELECT organization_id
+			  FROM organization_responsible
+			  WHERE user_id = $1`
+	err := dbConn(ctx).QueryRow(ctx, query, userID).Scan(&id)
+	return id, notFound(err)
+}
+
+func (postgresOrganizations) Lock(ctx context.Context, id uuid.UUID) error {
+	query := `SELECT id
+			  FROM organization
+			  WHERE id = $1
+			  FOR UPDATE`
+	err := dbConn(ctx).QueryRow(ctx, query, id).Scan(&id)
+	return notFound(err)
+}
+
+func (postgresOrganizations) GetRole(ctx context.Context, organizationID uuid.UUID, username string) (string, error) {
+	role := ""
+	query := `SELECT ore.role
+			  FROM organization_responsible ore
+			  JOIN employee e ON ore.user_id = e.id
+			  WHERE ore.organization_id = $1
+			  AND e.username = $2`
+	err := dbConn(ctx).QueryRow(ctx, query, organizationID, username).Scan(&role)
+	return role, notFound(err)
+}
+
+func (postgresOrganizations) CountAdmins(ctx context.Context, organizationID uuid.UUID) (int, error) {
+	count := 0
+	query := `SELECT count(user_id)
+			  FROM organization_responsible
+			  WHERE organization_id = $1 AND role = 'admin'`
+	err := dbConn(ctx).QueryRow(ctx, query, organizationID).Scan(&count)
+	return count, err
+}
+
+func (postgresOrganizations) ListMembers(ctx context.Context, organizationID uuid.UUID) ([]Membership, error) {
+	query := `SELECT ore.organization_id, ore.user_id, e.username, ore.role
+			  FROM organization_responsible ore
+			  JOIN employee e ON ore.user_id = e.id
+			  WHERE ore.organization_id = $1
+			  ORDER BY e.username ASC`
+	rows, err := dbConn(ctx).Query(ctx, query, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var members []Membership
+	for rows.Next() {
+		var ms Membership
+		if err := rows.Scan(&ms.OrganizationID, &ms.UserID, &ms.Username, &ms.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, ms)
+	}
+	return members, rows.Err()
+}
+
+func (postgresOrganizations) SetRole(ctx context.Context, organizationID, userID uuid.UUID, role string) error {
+	query := `UPDATE organization_responsible
+			  SET role = $3
+			  WHERE organization_id = $1 AND user_id = $2`
+	tag, err := dbConn(ctx).Exec(ctx, query, organizationID, userID, role)
+	if err != nil || tag.RowsAffected() > 0 {
+		return err
+	}
+	query = `INSERT INTO organization_responsible (organization_id, user_id, role)
+			 VALUES ($1, $2, $3)`
+	_, err = dbConn(ctx).Exec(ctx, query, organizationID, userID, role)
+	return err
+}
+
+func (postgresOrganizations) RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error {
+	query := `DELETE FROM organization_responsible
+			  WHERE organization_id = $1 AND user_id = $2`
+	tag, err := dbConn(ctx).Exec(ctx, query, organizationID, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (postgresOrganizations) AddRoleChange(ctx context.Context, change *RoleChange) error {
+	query := `INSERT INTO organization_role_audit (organization_id, username, action, old_role, new_role, changed_by)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, created_at`
+	return dbConn(ctx).QueryRow(ctx, query, change.OrganizationID, change.Username, change.Action, change.OldRole, change.NewRole, change.ChangedBy).Scan(&change.ID, &change.CreatedAt)
+}
+
+func (postgresOrganizations) ListRoleChanges(ctx context.Context, organizationID uuid.UUID, page Page) ([]RoleChange, error) {
+	query := "SELECT id, organization_id, username, action, old_role, new_role, changed_by, created_at\nFROM organization_role_audit\nWHERE organization_id = $1\nORDER BY created_at DESC"
+	query, args := appendPage(query, []interface{}{organizationID}, page)
+	rows, err := dbConn(ctx).Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []RoleChange
+	for rows.Next() {
+		var rc RoleChange
+		if err := rows.Scan(&rc.ID, &rc.OrganizationID, &rc.Username, &rc.Action, &rc.OldRole, &rc.NewRole, &rc.ChangedBy, &rc.CreatedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, rc)
+	}
+	return changes, rows.Err()
+}
+
+func (postgresAPIKeys) Create(ctx context.Context, employeeID uuid.UUID, name, hash string) (APIKey, error) {
+	key := APIKey{EmployeeID: employeeID, Name: name}
+	query := `WITH k AS (
+			  INSERT INTO employee_api_key (employee_id, name, key_hash)
+			  VALUES ($1, $2, $3)
+			  RETURNING id, employee_id, created_at)
+			  SELECT k.id, e.username, k.created_at
+			  FROM k
+			  JOIN employee e ON e.id = k.employee_id`
+	err := dbConn(ctx).QueryRow(ctx, query, employeeID, name, hash).Scan(&key.ID, &key.Username, &key.CreatedAt)
+	return key, notFound(err)
+}
+
+func (postgresAPIKeys) FindByHash(ctx context.Context, hash string) (APIKey, error) {
+	var key APIKey
+	query := `SELECT k.id, k.employee_id, e.username, k.name, k.created_at
+			  FROM employee_api_key k
+			  JOIN employee e ON e.id = k.employee_id
+			  WHERE k.key_hash = $1 AND k.revoked_at IS NULL`
+	err := dbConn(ctx).QueryRow(ctx, query, hash).Scan(&key.ID, &key.EmployeeID, &key.Username, &key.Name, &key.CreatedAt)
+	return key, notFound(err)
+}
+
+func (postgresAPIKeys) Revoke(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE employee_api_key
+			  SET revoked_at = NOW()
+			  WHERE id = $1 AND revoked_at IS NULL`
+	tag, err := dbConn(ctx).Exec(ctx, query, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type BidLineItem struct {
+	Description string          `json:"description"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	Unit        string          `json:"unit"`
+	UnitPrice   decimal.Decimal `json:"unitPrice"`
+}
+
+type BidFilter struct {
+	LotID     uuid.UUID
+	Currency  string
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+}
+
+func (item BidLineItem) Total() decimal.Decimal {
+	return item.Quantity.Mul(item.UnitPrice)
+}
+
+func sameAmount(a, b *decimal.Decimal) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func LineItemsTotal(items []BidLineItem) decimal.Decimal {
+	total := decimal.Zero
+	for _, item := range items {
+		total = total.Add(item.Total())
+	}
+	return total
+}
+
+func (filter BidFilter) Matches(bid Bid) bool {
+	if filter.LotID != uuid.Nil && !bidTargetsLot(bid, filter.LotID) {
+		return false
+	}
+	if filter.Currency != "" && bid.Currency != filter.Currency {
+		return false
+	}
+	if filter.MinAmount != nil && (bid.Amount == nil || bid.Amount.LessThan(*filter.MinAmount)) {
+		return false
+	}
+	if filter.MaxAmount != nil && (bid.Amount == nil || bid.Amount.GreaterThan(*filter.MaxAmount)) {
+		return false
+	}
+	return true
+}
+
+func CheckBidPricing(w http.ResponseWriter, bid *Bid) bool {
+	for i, item := range bid.LineItems {
+		if item.Unit == "" || !item.Quantity.IsPositive() || item.UnitPrice.IsNegative() {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Invalid line item %d", i+1)}, http.StatusBadRequest)
+			return false
+		}
+	}
+	if bid.Amount == nil && len(bid.LineItems) > 0 {
+		total := LineItemsTotal(bid.LineItems)
+		bid.Amount = &total
+	}
+	if bid.Amount == nil {
+		if bid.Currency != "" {
+			SendErrorResponse(w, ErrorResponse{"Currency requires amount"}, http.StatusBadRequest)
+			return false
+		}
+		return true
+	}
+	if bid.Amount.IsNegative() {
+		SendErrorResponse(w, ErrorResponse{"Amount must not be negative"}, http.StatusBadRequest)
+		return false
+	}
+	if !currencyPattern.MatchString(bid.Currency) {
+		SendErrorResponse(w, ErrorResponse{"Invalid currency"}, http.StatusBadRequest)
+		return false
+	}
+	if len(bid.LineItems) > 0 {
+		if total := LineItemsTotal(bid.LineItems); !bid.Amount.Equal(total) {
+			SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Amount %s does not match line items total %s", bid.Amount, total)}, http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
+
+func CheckTenderPricing(w http.ResponseWriter, tender Tender) bool {
+	priced := false
+	for _, amount := range []*decimal.Decimal{tender.Budget, tender.ReservePrice, tender.MaxBidAmount} {
+		if amount == nil {
+			continue
+		}
+		if amount.IsNegative() {
+			SendErrorResponse(w, ErrorResponse{"Amounts must not be negative"}, http.StatusBadRequest)
+			return false
+		}
+		priced = true
+	}
+	if (priced || tender.Currency != "") && !currencyPattern.MatchString(tender.Currency) {
+		SendErrorResponse(w, ErrorResponse{"Invalid currency"}, http.StatusBadRequest)
+		return false
+	}
+	if tender.RejectAboveMax && tender.MaxBidAmount == nil {
+		SendErrorResponse(w, ErrorResponse{"rejectAboveMax requires maxBidAmount"}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func CopyTenderPricing(tender *Tender, from Tender) {
+	tender.Budget, tender.ReservePrice, tender.MaxBidAmount = from.Budget, from.ReservePrice, from.MaxBidAmount
+	tender.Currency, tender.RejectAboveMax = from.Currency, from.RejectAboveMax
+}
+
+func CanSeeReservePrice(ctx context.Context, tender Tender, username string) bool {
+	role, err := storage.Organizations.GetRole(ctx, tender.OrganizationID, username)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			log.Println(err.Error())
+		}
+		return false
+	}
+	return RoleAllows(role, PermViewTender)
+}
+
+func CheckBidWithinTender(w http.ResponseWriter, tender Tender, bid Bid) bool {
+	if tender.Currency != "" && bid.Currency != "" && bid.Currency != tender.Currency {
+		SendErrorResponse(w, ErrorResponse{"Bid currency must match tender currency " + tender.Currency}, http.StatusBadRequest)
+		return false
+	}
+	if !tender.RejectAboveMax || tender.MaxBidAmount == nil {
+		return true
+	}
+	if bid.Amount == nil {
+		SendErrorResponse(w, ErrorResponse{"Bid amount is required"}, http.StatusBadRequest)
+		return false
+	}
+	if bid.Amount.GreaterThan(*tender.MaxBidAmount) {
+		SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Bid amount exceeds tender maximum %s", tender.MaxBidAmount)}, http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func FlagBidsAboveReserve(bids []Bid, tender Tender) {
+	if tender.ReservePrice == nil {
+		return
+	}
+	for i := range bids {
+		if bids[i].Amount != nil && (tender.Currency == "" || bids[i].Currency == tender.Currency) {
+			bids[i].AboveReserve = bids[i].Amount.GreaterThan(*tender.ReservePrice)
+		}
+	}
+}
+
+func ApplyPricingPatch(bid *Bid, patch Bid, body []byte) {
+	var fields map[string]json.RawMessage
+	json.Unmarshal(body, &fields)
+	if _, ok := fields["lineItems"]; ok {
+		if _, ok := fields["amount"]; !ok {
+			patch.Amount = nil
+		}
+	}
+	bid.Amount, bid.Currency, bid.LineItems = patch.Amount, patch.Currency, patch.LineItems
+}
+
+func ParseBidFilter(w http.ResponseWriter, url url.Values) (BidFilter, bool) {
+	var filter BidFilter
+	if lot := url.Get("lotId"); lot != "" {
+		id, ok := ParseID(w, lot, "lot")
+		if !ok {
+			return filter, false
+		}
+		filter.LotID = id
+	}
+	if cur := url.Get("currency"); cur != "" {
+		if !currencyPattern.MatchString(cur) {
+			SendErrorResponse(w, ErrorResponse{"Invalid currency parameter"}, http.StatusBadRequest)
+			return filter, false
+		}
+		filter.Currency = cur
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **decimal.Decimal
+	}{{"minAmount", &filter.MinAmount}, {"maxAmount", &filter.MaxAmount}} {
+		if v := url.Get(bound.name); v != "" {
+			amount, err := decimal.NewFromString(v)
+			if err != nil {
+				SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Invalid %s parameter", bound.name)}, http.StatusBadRequest)
+				return filter, false
+			}
+			*bound.dst = &amount
+		}
+	}
+	return filter, true
+}
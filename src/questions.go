@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type Question struct {
+	ID             uuid.UUID  `json:"id"`
+	TenderID       uuid.UUID  `json:"tenderId"`
+	AuthorID       uuid.UUID  `json:"-"`
+	AuthorUsername string     `json:"authorUsername,omitempty"`
+	Text           string     `json:"text"`
+	Anonymous      bool       `json:"anonymous"`
+	Answer         string     `json:"answer,omitempty"`
+	AnsweredBy     string     `json:"answeredBy,omitempty"`
+	AnsweredAt     *time.Time `json:"answeredAt,omitempty"`
+	AmendedVersion *int       `json:"amendedVersion,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type QuestionAnswer struct {
+	Answer    string          `json:"answer"`
+	Anonymous *bool           `json:"anonymous"`
+	Amendment json.RawMessage `json:"amendment"`
+}
+
+func GetQuestionInfo(ctx context.Context, w http.ResponseWriter, tenderId, questionId uuid.UUID, forUpdate bool) (Question, bool) {
+	get := storage.Questions.Get
+	if forUpdate {
+		get = storage.Questions.GetForUpdate
+	}
+	question, err := get(ctx, questionId)
+	if errors.Is(err, ErrNotFound) || err == nil && question.TenderID != tenderId {
+		SendErrorResponse(w, ErrorResponse{"No such question"}, http.StatusNotFound)
+		return question, false
+	}
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find question"}, http.StatusInternalServerError)
+		return question, false
+	}
+	return question, true
+}
+
+func NotifyTenderAmended(ctx context.Context, w http.ResponseWriter, tender Tender, question Question) bool {
+	bids, _, err := storage.Bids.ListForTender(ctx, tender.ID, tender.OrganizationID, BidFilter{}, Page{})
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
+		return false
+	}
+	message := fmt.Sprintf("Tender %q was amended to version %d after a clarification: %s", tender.Name, tender.Version, question.Answer)
+	for _, bid := range bids {
+		if bid.Status == BidCanceled {
+			continue
+		}
+		if err := NotifyBidAuthor(ctx, bid, EventTenderAmended, message); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to notify bid authors"}, http.StatusInternalServerError)
+			return false
+		}
+	}
+	return true
+}
+
+func CreateQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("CreateQuestionHandler started")
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	member, err := allows(ctx, tender.OrganizationID, username, PermViewTender)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to authorize"}, http.StatusInternalServerError)
+		return
+	}
+	if member {
+		SendErrorResponse(w, ErrorResponse{"Tender organization can't ask questions about its own tender"}, http.StatusForbidden)
+		return
+	}
+	if !CheckSubmissionOpen(w, tender) {
+		return
+	}
+	if tender.Status != "Published" {
+		SendErrorResponse(w, ErrorResponse{"Tender is not accepting questions"}, http.StatusConflict)
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var question Question
+	if err := json.Unmarshal(buf.Bytes(), &question); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	question.Text = strings.TrimSpace(question.Text)
+	if question.Text == "" {
+		SendErrorResponse(w, ErrorResponse{"Question text is required"}, http.StatusBadRequest)
+		return
+	}
+	question.TenderID, question.AuthorID, question.AuthorUsername = tender.ID, user.ID, user.Username
+	if err := storage.Questions.Create(ctx, &question); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to create question"}, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(question)
+}
+
+func ShowQuestionsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowQuestionsHandler started")
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	member, err := allows(ctx, tender.OrganizationID, username, PermViewTender)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to authorize"}, http.StatusInternalServerError)
+		return
+	}
+	questions, err := storage.Questions.ListForTender(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find questions"}, http.StatusInternalServerError)
+		return
+	}
+	visible := []Question{}
+	for _, question := range questions {
+		own := question.AuthorID == user.ID
+		if !member && !own && question.AnsweredAt == nil {
+			continue
+		}
+		if !member && !own && question.Anonymous {
+			question.AuthorUsername = ""
+		}
+		visible = append(visible, question)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(visible)
+}
+
+func AnswerQuestionHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("AnswerQuestionHandler started")
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	questionId, ok := ParseID(w, vars["questionId"], "question")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return
+	}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
+		return
+	}
+	buf := new(bytes.Buffer)
+	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
+		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
+		return
+	}
+	var answer QuestionAnswer
+	if err := json.Unmarshal(buf.Bytes(), &answer); err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+		return
+	}
+	answer.Answer = strings.TrimSpace(answer.Answer)
+	if answer.Answer == "" {
+		SendErrorResponse(w, ErrorResponse{"Answer is required"}, http.StatusBadRequest)
+		return
+	}
+	amend := len(answer.Amendment) > 0 && string(answer.Amendment) != "null"
+	var question Question
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if qs, ok := GetQuestionInfo(ctx, w, tenderId, questionId, true); ok {
+			question = qs
+		} else {
+			return false
+		}
+		if question.AnsweredAt != nil {
+			SendErrorResponse(w, ErrorResponse{"Question is already answered"}, http.StatusConflict)
+			return false
+		}
+		now := time.Now()
+		question.Answer, question.AnsweredBy, question.AnsweredAt = answer.Answer, username, &now
+		if answer.Anonymous != nil {
+			question.Anonymous = *answer.Anonymous
+		}
+		if amend {
+			if tender.Status != "Created" && tender.Status != "Published" {
+				SendErrorResponse(w, ErrorResponse{"Tender can no longer be amended"}, http.StatusConflict)
+				return false
+			}
+			patch := tender
+			if err := json.Unmarshal(answer.Amendment, &patch); err != nil {
+				log.Println(err.Error())
+				SendErrorResponse(w, ErrorResponse{"Can't unmarshal amendment"}, http.StatusBadRequest)
+				return false
+			}
+			if !AmendTender(ctx, w, &tender, patch, username) {
+				return false
+			}
+			question.AmendedVersion = &tender.Version
+			if !NotifyTenderAmended(ctx, w, tender, question) {
+				return false
+			}
+		}
+		if err := storage.Questions.Update(ctx, &question); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to answer question"}, http.StatusInternalServerError)
+			return false
+		}
+		message := fmt.Sprintf("Your question about tender %q has been answered", tender.Name)
+		if err := NotifyUser(ctx, question.AuthorID, tender.ID, EventQuestionAnswered, message); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to notify question author"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(question)
+}
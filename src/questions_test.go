@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestAnonymousQuestions(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	path := "/api/tenders/" + tender.ID.String() + "/questions"
+	env.expect("POST", path, keyAlice, map[string]interface{}{"text": "own tender?"}, http.StatusForbidden, nil)
+	env.expect("POST", path, keyBob, map[string]interface{}{"text": "  "}, http.StatusBadRequest, nil)
+	var question Question
+	env.expect("POST", path, keyBob, map[string]interface{}{"text": "Which asphalt?", "anonymous": true}, http.StatusOK, &question)
+
+	var questions []Question
+	env.expect("GET", path, keyCarol, nil, http.StatusOK, &questions)
+	if len(questions) != 0 {
+		t.Fatalf("unanswered question visible to others: %+v", questions)
+	}
+	env.expect("GET", path, keyAlice, nil, http.StatusOK, &questions)
+	if len(questions) != 1 || questions[0].AuthorUsername != "bob" {
+		t.Fatalf("tender organization got %+v", questions)
+	}
+
+	answer := "/api/tenders/" + tender.ID.String() + "/questions/" + question.ID.String() + "/answer"
+	env.expect("PUT", answer, keyBob, map[string]interface{}{"answer": "self"}, http.StatusForbidden, nil)
+	env.expect("PUT", answer, keyAlice, map[string]interface{}{"answer": "Grade A"}, http.StatusOK, nil)
+	env.expect("PUT", answer, keyAlice, map[string]interface{}{"answer": "again"}, http.StatusConflict, nil)
+
+	questions = nil
+	env.expect("GET", path, keyCarol, nil, http.StatusOK, &questions)
+	if len(questions) != 1 || questions[0].Answer != "Grade A" || questions[0].AuthorUsername != "" {
+		t.Fatalf("other suppliers got %+v", questions)
+	}
+	questions = nil
+	env.expect("GET", path, keyBob, nil, http.StatusOK, &questions)
+	if len(questions) != 1 || questions[0].AuthorUsername != "bob" {
+		t.Fatalf("author got %+v", questions)
+	}
+	var notifications []Notification
+	env.expect("GET", "/api/notifications/my", keyBob, nil, http.StatusOK, &notifications)
+	if len(notifications) != 1 || notifications[0].Event != EventQuestionAnswered {
+		t.Fatalf("got notifications %+v", notifications)
+	}
+}
+
+func TestAnswerWithAmendment(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	env.submitBid(tender, nil)
+	path := "/api/tenders/" + tender.ID.String() + "/questions"
+	var question Question
+	env.expect("POST", path, keyCarol, map[string]interface{}{"text": "Length?"}, http.StatusOK, &question)
+	var answered Question
+	env.expect("PUT", path+"/"+question.ID.String()+"/answer", keyAlice,
+		map[string]interface{}{"answer": "12 km", "amendment": map[string]interface{}{"description": "Road, 12 km"}}, http.StatusOK, &answered)
+	if answered.AmendedVersion == nil || *answered.AmendedVersion != tender.Version+1 {
+		t.Fatalf("got amended version %v, tender was at %d", answered.AmendedVersion, tender.Version)
+	}
+	amended, err := storage.Tenders.Get(context.Background(), tender.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amended.Description != "Road, 12 km" || amended.Version != *answered.AmendedVersion {
+		t.Fatalf("tender description is %q", amended.Description)
+	}
+	var notifications []Notification
+	env.expect("GET", "/api/notifications/my", keyBob, nil, http.StatusOK, &notifications)
+	if len(notifications) != 1 || notifications[0].Event != EventTenderAmended {
+		t.Fatalf("bid author got notifications %+v", notifications)
+	}
+}
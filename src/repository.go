@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Page struct {
+	Limit  int
+	Offset int
+	Sort   string
+	Desc   bool
+	After  *Cursor
+}
+
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type TenderRepository interface {
+	Create(ctx context.Context, tender *Tender) error
+	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	Get(ctx context.Context, id uuid.UUID) (Tender, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (Tender, error)
+	ListPublished(ctx context.Context, serviceType string, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]Tender, int, error)
+	ListByCreator(ctx context.Context, username string, page Page) ([]Tender, int, error)
+	Search(ctx context.Context, filter SearchFilter, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]TenderHit, error)
+	ListDueForPublish(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	ListDueForClose(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+	Update(ctx context.Context, tender *Tender) error
+	AddVersion(ctx context.Context, tender Tender) error
+	VersionExists(ctx context.Context, id uuid.UUID, version int) (bool, error)
+	GetVersion(ctx context.Context, id uuid.UUID, version int) (Tender, error)
+	ListVersions(ctx context.Context, id uuid.UUID) ([]Tender, error)
+	AddUnsealEvent(ctx context.Context, event *UnsealEvent) error
+	GetUnsealEvent(ctx context.Context, tenderID uuid.UUID) (UnsealEvent, error)
+}
+
+type BidRepository interface {
+	Create(ctx context.Context, bid *Bid) error
+	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	Get(ctx context.Context, id uuid.UUID) (Bid, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (Bid, error)
+	ListByAuthor(ctx context.Context, authorID uuid.UUID, page Page) ([]Bid, int, error)
+	ListForTender(ctx context.Context, tenderID, organizationID uuid.UUID, filter BidFilter, page Page) ([]Bid, int, error)
+	Search(ctx context.Context, filter SearchFilter, viewerID, viewerOrganizationID uuid.UUID, page Page) ([]BidHit, error)
+	ListSealed(ctx context.Context, tenderID uuid.UUID) ([]Bid, error)
+	CountForTender(ctx context.Context, tenderID uuid.UUID) (int, error)
+	ListRanked(ctx context.Context, tenderID uuid.UUID) ([]Bid, error)
+	Update(ctx context.Context, bid *Bid) error
+	AddVersion(ctx context.Context, bid Bid) error
+	VersionExists(ctx context.Context, id uuid.UUID, version int) (bool, error)
+	GetVersion(ctx context.Context, id uuid.UUID, version int) (Bid, error)
+	ListVersions(ctx context.Context, id uuid.UUID) ([]Bid, error)
+	VoteExists(ctx context.Context, bidID, lotID uuid.UUID, username string) (bool, error)
+	AddVote(ctx context.Context, vote *ApprovalVote) error
+	ListVotes(ctx context.Context, bidID, lotID uuid.UUID) ([]ApprovalVote, error)
+}
+
+type LotRepository interface {
+	Create(ctx context.Context, lot *Lot) error
+	Get(ctx context.Context, id uuid.UUID) (Lot, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (Lot, error)
+	ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Lot, error)
+	Update(ctx context.Context, lot *Lot) error
+}
+
+type EvaluationRepository interface {
+	CreateCriterion(ctx context.Context, criterion *Criterion) error
+	GetCriterion(ctx context.Context, id uuid.UUID) (Criterion, error)
+	ListCriteria(ctx context.Context, tenderID uuid.UUID) ([]Criterion, error)
+	DeleteCriterion(ctx context.Context, id uuid.UUID) error
+	TenderHasScores(ctx context.Context, tenderID uuid.UUID) (bool, error)
+	SetScore(ctx context.Context, score *BidScore) error
+	ListScores(ctx context.Context, tenderID uuid.UUID) ([]BidScore, error)
+	ListBidScores(ctx context.Context, bidID uuid.UUID) ([]BidScore, error)
+}
+
+type PolicyRepository interface {
+	GetOrganizationPolicy(ctx context.Context, organizationID uuid.UUID) (ApprovalPolicy, error)
+	SetOrganizationPolicy(ctx context.Context, organizationID uuid.UUID, policy *ApprovalPolicy) error
+	GetTenderPolicy(ctx context.Context, tenderID uuid.UUID) (ApprovalPolicy, error)
+	SetTenderPolicy(ctx context.Context, tenderID uuid.UUID, policy *ApprovalPolicy) error
+	DeleteTenderPolicy(ctx context.Context, tenderID uuid.UUID) error
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, notification *Notification) error
+	ListForRecipient(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Notification, int, error)
+}
+
+type ContractRepository interface {
+	Create(ctx context.Context, contract *Contract) error
+	Get(ctx context.Context, id uuid.UUID) (Contract, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (Contract, error)
+	ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Contract, error)
+	ListForParty(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Contract, int, error)
+	Update(ctx context.Context, contract *Contract) error
+}
+
+type QuestionRepository interface {
+	Create(ctx context.Context, question *Question) error
+	Get(ctx context.Context, id uuid.UUID) (Question, error)
+	GetForUpdate(ctx context.Context, id uuid.UUID) (Question, error)
+	ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Question, error)
+	Update(ctx context.Context, question *Question) error
+}
+
+type InvitationRepository interface {
+	Create(ctx context.Context, invitation *Invitation) error
+	Get(ctx context.Context, id uuid.UUID) (Invitation, error)
+	Find(ctx context.Context, tenderID uuid.UUID, inviteeType string, inviteeID uuid.UUID) (Invitation, error)
+	ListForTender(ctx context.Context, tenderID uuid.UUID) ([]Invitation, error)
+	ListForInvitee(ctx context.Context, userID, organizationID uuid.UUID, page Page) ([]Invitation, int, error)
+	Update(ctx context.Context, invitation *Invitation) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type AttachmentRepository interface {
+	Create(ctx context.Context, attachment *Attachment) error
+	Get(ctx context.Context, id uuid.UUID) (Attachment, error)
+	ListActive(ctx context.Context, parentType string, parentID uuid.UUID, version int) ([]Attachment, error)
+	Remove(ctx context.Context, id uuid.UUID, version int) error
+}
+
+type ReviewRepository interface {
+	Create(ctx context.Context, bidID uuid.UUID, username, text string) (BidReview, error)
+	Get(ctx context.Context, id uuid.UUID) (BidReview, error)
+	ListByAuthor(ctx context.Context, authorID, excludeTenderID uuid.UUID, page Page) ([]BidReview, int, error)
+}
+
+type OrganizationRepository interface {
+	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	UserExists(ctx context.Context, username string) (bool, error)
+	GetUserID(ctx context.Context, username string) (uuid.UUID, error)
+	GetUsername(ctx context.Context, userID uuid.UUID) (string, error)
+	GetUserOrganization(ctx context.Context, userID uuid.UUID) (uuid.UUID, error)
+	Lock(ctx context.Context, id uuid.UUID) error
+	GetRole(ctx context.Context, organizationID uuid.UUID, username string) (string, error)
+	CountAdmins(ctx context.Context, organizationID uuid.UUID) (int, error)
+	ListMembers(ctx context.Context, organizationID uuid.UUID) ([]Membership, error)
+	SetRole(ctx context.Context, organizationID, userID uuid.UUID, role string) error
+	RemoveMember(ctx context.Context, organizationID, userID uuid.UUID) error
+	AddRoleChange(ctx context.Context, change *RoleChange) error
+	ListRoleChanges(ctx context.Context, organizationID uuid.UUID, page Page) ([]RoleChange, error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, employeeID uuid.UUID, name, hash string) (APIKey, error)
+	FindByHash(ctx context.Context, hash string) (APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+}
+
+type Storage struct {
+	Tx            Transactor
+	Tenders       TenderRepository
+	Bids          BidRepository
+	Lots          LotRepository
+	Evaluations   EvaluationRepository
+	Policies      PolicyRepository
+	Reviews       ReviewRepository
+	Notifications NotificationRepository
+	Contracts     ContractRepository
+	Questions     QuestionRepository
+	Invitations   InvitationRepository
+	Attachments   AttachmentRepository
+	Organizations OrganizationRepository
+	APIKeys       APIKeyRepository
+}
+
+var storage Storage
+
+func initStorage(backend string) error {
+	switch backend {
+	case "", "postgres":
+		pool, err := initDB()
+		if err != nil {
+			return err
+		}
+		db = pool
+		if err := CheckMigrations(context.Background(), os.Getenv("DB_AUTO_MIGRATE") != "false"); err != nil {
+			return err
+		}
+		storage = NewPostgresStorage()
+	case "memory":
+		store := NewMemoryStore()
+		if seed := os.Getenv("MEMORY_SEED"); seed != "" {
+			if err := store.LoadSeed(seed); err != nil {
+				return err
+			}
+		}
+		storage = store.Storage()
+	default:
+		return fmt.Errorf("unknown storage backend: %q", backend)
+	}
+	return nil
+}
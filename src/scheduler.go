@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const schedulerUsername = "scheduler"
+
+var schedulerInterval = 30 * time.Second
+
+func initScheduler() error {
+	if d, ok, err := envDuration("SCHEDULER_INTERVAL"); err != nil {
+		return err
+	} else if ok {
+		schedulerInterval = d
+	}
+	return nil
+}
+
+func RunScheduler(ctx context.Context) {
+	if schedulerInterval == 0 {
+		log.Println("Tender scheduler is disabled")
+		return
+	}
+	log.Printf("Tender scheduler started (interval: %s)\n", schedulerInterval)
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		RunScheduledTransitions(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func RunScheduledTransitions(ctx context.Context, now time.Time) {
+	publish, err := storage.Tenders.ListDueForPublish(ctx, now)
+	if err != nil {
+		log.Println(err.Error())
+	}
+	for _, id := range publish {
+		err := applyScheduledStatus(ctx, id, "Published", func(tender Tender) bool {
+			return tender.Status == "Created" && tender.PublishAt != nil && !tender.PublishAt.After(now)
+		})
+		if err != nil {
+			log.Printf("Failed to publish tender %s: %v\n", id, err)
+		}
+	}
+	close, err := storage.Tenders.ListDueForClose(ctx, now)
+	if err != nil {
+		log.Println(err.Error())
+	}
+	for _, id := range close {
+		err := applyScheduledStatus(ctx, id, "Closed", func(tender Tender) bool {
+			return tender.Status == "Published" && tender.SubmissionDeadline != nil && !tender.SubmissionDeadline.After(now)
+		})
+		if err != nil {
+			log.Printf("Failed to close tender %s: %v\n", id, err)
+		}
+	}
+}
+
+func applyScheduledStatus(ctx context.Context, id uuid.UUID, status string, due func(tender Tender) bool) error {
+	return storage.Tx.WithTx(ctx, func(ctx context.Context) error {
+		tender, err := storage.Tenders.GetForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		if !due(tender) {
+			return nil
+		}
+		cause := "schedule"
+		if status == "Closed" {
+			cause = "deadline"
+		}
+		if err := ApplyTenderTransition(ctx, &tender, status, schedulerUsername, cause); err != nil {
+			return err
+		}
+		log.Printf("Tender %s moved to %s by schedule\n", id, status)
+		return nil
+	})
+}
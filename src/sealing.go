@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type UnsealEvent struct {
+	ID         uuid.UUID `json:"id"`
+	TenderID   uuid.UUID `json:"tenderId"`
+	BidCount   int       `json:"bidCount"`
+	Reason     string    `json:"reason"`
+	UnsealedBy string    `json:"unsealedBy"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type SealState struct {
+	Sealed             bool         `json:"sealed"`
+	BidCount           int          `json:"bidCount"`
+	SubmissionDeadline *time.Time   `json:"submissionDeadline,omitempty"`
+	UnsealedAt         *time.Time   `json:"unsealedAt,omitempty"`
+	Event              *UnsealEvent `json:"event,omitempty"`
+}
+
+type sealedBidContent struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Amount      *decimal.Decimal `json:"amount,omitempty"`
+	Currency    string           `json:"currency,omitempty"`
+	LineItems   []BidLineItem    `json:"lineItems,omitempty"`
+}
+
+type sealingBids struct {
+	BidRepository
+	aead cipher.AEAD
+}
+
+var sealingEnabled bool
+
+func initSealing() error {
+	key := os.Getenv("BID_SEAL_KEY")
+	if key == "" {
+		log.Println("BID_SEAL_KEY is not set, sealed tenders are disabled")
+		return nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("invalid BID_SEAL_KEY: %w", err)
+	}
+	bids, err := NewSealingBids(storage.Bids, raw)
+	if err != nil {
+		return err
+	}
+	storage.Bids = bids
+	sealingEnabled = true
+	return nil
+}
+
+func NewSealingBids(bids BidRepository, key []byte) (BidRepository, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BID_SEAL_KEY: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return sealingBids{bids, aead}, nil
+}
+
+func withContent(bid Bid, from Bid) Bid {
+	bid.Name, bid.Description = from.Name, from.Description
+	bid.Amount, bid.Currency, bid.LineItems = from.Amount, from.Currency, from.LineItems
+	bid.SealedData = nil
+	return bid
+}
+
+func (s sealingBids) seal(bid Bid) (Bid, error) {
+	if !bid.Sealed {
+		bid.SealedData = nil
+		return bid, nil
+	}
+	plain, err := json.Marshal(sealedBidContent{bid.Name, bid.Description, bid.Amount, bid.Currency, bid.LineItems})
+	if err != nil {
+		return bid, err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return bid, err
+	}
+	sealed := withContent(bid, Bid{})
+	sealed.SealedData = s.aead.Seal(nonce, nonce, plain, nil)
+	return sealed, nil
+}
+
+func (s sealingBids) open(bid Bid, err error) (Bid, error) {
+	if err != nil || len(bid.SealedData) == 0 {
+		return bid, err
+	}
+	size := s.aead.NonceSize()
+	if len(bid.SealedData) < size {
+		return bid, errors.New("sealed bid data is corrupted")
+	}
+	plain, err := s.aead.Open(nil, bid.SealedData[:size], bid.SealedData[size:], nil)
+	if err != nil {
+		return bid, fmt.Errorf("failed to unseal bid %s: %w", bid.ID, err)
+	}
+	var content sealedBidContent
+	if err := json.Unmarshal(plain, &content); err != nil {
+		return bid, err
+	}
+	return withContent(bid, Bid{Name: content.Name, Description: content.Description, Amount: content.Amount, Currency: content.Currency, LineItems: content.LineItems}), nil
+}
+
+func (s sealingBids) openAll(bids []Bid, err error) ([]Bid, error) {
+	if err != nil {
+		return nil, err
+	}
+	for i := range bids {
+		if bids[i], err = s.open(bids[i], nil); err != nil {
+			return nil, err
+		}
+	}
+	return bids, nil
+}
+
+func (s sealingBids) Create(ctx context.Context, bid *Bid) error {
+	sealed, err := s.seal(*bid)
+	if err != nil {
+		return err
+	}
+	if err := s.BidRepository.Create(ctx, &sealed); err != nil {
+		return err
+	}
+	*bid = withContent(sealed, *bid)
+	return nil
+}
+
+func (s sealingBids) Get(ctx context.Context, id uuid.UUID) (Bid, error) {
+	return s.open(s.BidRepository.Get(ctx, id))
+}
+
+func (s sealingBids) GetForUpdate(ctx context.Context, id uuid.UUID) (Bid, error) {
+	return s.open(s.BidRepository.GetForUpdate(ctx, id))
+}
+
+func (s sealingBids) ListByAuthor(ctx context.Context, authorID uuid.UUID, page Page) ([]Bid, int, error) {
+	bids, total, err := s.BidRepository.ListByAuthor(ctx, authorID, page)
+	bids, err = s.openAll(bids, err)
+	return bids, total, err
+}
+
+func (s sealingBids) ListForTender(ctx context.Context, tenderID, organizationID uuid.UUID, filter BidFilter, page Page) ([]Bid, int, error) {
+	bids, total, err := s.BidRepository.ListForTender(ctx, tenderID, organizationID, filter, page)
+	bids, err = s.openAll(bids, err)
+	return bids, total, err
+}
+
+func (s sealingBids) ListSealed(ctx context.Context, tenderID uuid.UUID) ([]Bid, error) {
+	return s.openAll(s.BidRepository.ListSealed(ctx, tenderID))
+}
+
+func (s sealingBids) Update(ctx context.Context, bid *Bid) error {
+	sealed, err := s.seal(*bid)
+	if err != nil {
+		return err
+	}
+	if err := s.BidRepository.Update(ctx, &sealed); err != nil {
+		return err
+	}
+	*bid = withContent(sealed, *bid)
+	return nil
+}
+
+func (s sealingBids) AddVersion(ctx context.Context, bid Bid) error {
+	sealed, err := s.seal(bid)
+	if err != nil {
+		return err
+	}
+	return s.BidRepository.AddVersion(ctx, sealed)
+}
+
+func (s sealingBids) GetVersion(ctx context.Context, id uuid.UUID, version int) (Bid, error) {
+	return s.open(s.BidRepository.GetVersion(ctx, id, version))
+}
+
+func (s sealingBids) ListVersions(ctx context.Context, id uuid.UUID) ([]Bid, error) {
+	return s.openAll(s.BidRepository.ListVersions(ctx, id))
+}
+
+func (tender Tender) BidsSealed() bool {
+	return tender.Sealed && tender.UnsealedAt == nil
+}
+
+func UnsealTenderBids(ctx context.Context, tender *Tender, username, reason string) error {
+	if !tender.BidsSealed() {
+		return nil
+	}
+	if !sealingEnabled {
+		return errors.New("BID_SEAL_KEY is required to unseal bids")
+	}
+	bids, err := storage.Bids.ListSealed(ctx, tender.ID)
+	if err != nil {
+		return err
+	}
+	for i := range bids {
+		bids[i].Sealed = false
+		if err := storage.Bids.Update(ctx, &bids[i]); err != nil {
+			return err
+		}
+	}
+	now := time.Now()
+	tender.UnsealedAt = &now
+	log.Printf("Unsealed %d bids of tender %s (%s)\n", len(bids), tender.ID, reason)
+	return storage.Tenders.AddUnsealEvent(ctx, &UnsealEvent{TenderID: tender.ID, BidCount: len(bids), Reason: reason, UnsealedBy: username})
+}
+
+func UnsealIfDue(ctx context.Context, w http.ResponseWriter, tender *Tender) bool {
+	if !tender.BidsSealed() || tender.SubmissionDeadline == nil || tender.SubmissionDeadline.After(time.Now()) {
+		return true
+	}
+	return RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tender.ID); ok {
+			*tender = tn
+		} else {
+			return false
+		}
+		if !tender.BidsSealed() {
+			return true
+		}
+		if err := UnsealTenderBids(ctx, tender, schedulerUsername, "deadline"); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to unseal bids"}, http.StatusInternalServerError)
+			return false
+		}
+		if err := storage.Tenders.Update(ctx, tender); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to unseal bids"}, http.StatusInternalServerError)
+			return false
+		}
+		return true
+	})
+}
+
+func CheckTenderSealing(ctx context.Context, w http.ResponseWriter, tender, current Tender) bool {
+	if tender.Sealed && !sealingEnabled {
+		SendErrorResponse(w, ErrorResponse{"Sealed bids are disabled: BID_SEAL_KEY is not set"}, http.StatusBadRequest)
+		return false
+	}
+	if current.ID == uuid.Nil || tender.Sealed == current.Sealed {
+		return true
+	}
+	count, err := storage.Bids.CountForTender(ctx, current.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
+		return false
+	}
+	if count > 0 || current.UnsealedAt != nil {
+		SendErrorResponse(w, ErrorResponse{"Sealed mode can't be changed after bids were submitted"}, http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+func CheckBidNotSealed(w http.ResponseWriter, bid Bid) bool {
+	if bid.Sealed {
+		SendErrorResponse(w, ErrorResponse{"Bid is sealed until the submission deadline"}, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func ShowTenderSealHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderSealHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermViewBid) {
+		return
+	}
+	if !UnsealIfDue(ctx, w, &tender) {
+		return
+	}
+	count, err := storage.Bids.CountForTender(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bids"}, http.StatusInternalServerError)
+		return
+	}
+	state := SealState{tender.BidsSealed(), count, tender.SubmissionDeadline, tender.UnsealedAt, nil}
+	event, err := storage.Tenders.GetUnsealEvent(ctx, tender.ID)
+	if err == nil {
+		state.Event = &event
+	} else if !errors.Is(err, ErrNotFound) {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find unseal event"}, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(state)
+}
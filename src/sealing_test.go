@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func (env *testEnv) enableSealing() {
+	env.t.Helper()
+	bids, err := NewSealingBids(storage.Bids, make([]byte, 32))
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	storage.Bids, sealingEnabled = bids, true
+	env.t.Cleanup(func() { sealingEnabled = false })
+}
+
+// passDeadline moves the tender's submission deadline into the past.
+func (env *testEnv) passDeadline(tender Tender) {
+	env.t.Helper()
+	ctx := context.Background()
+	stored, err := storage.Tenders.Get(ctx, tender.ID)
+	if err != nil {
+		env.t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+	stored.SubmissionDeadline = &past
+	if err := storage.Tenders.Update(ctx, &stored); err != nil {
+		env.t.Fatal(err)
+	}
+}
+
+func TestSealedBidContentIsEncrypted(t *testing.T) {
+	env := newTestEnv(t)
+	env.enableSealing()
+	deadline := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	tender := env.publishTender("Road", map[string]interface{}{"sealed": true, "submissionDeadline": deadline})
+	bid := env.createBid(tender, map[string]interface{}{"name": "secret offer", "amount": "100", "currency": "RUB"})
+	if bid.Name != "secret offer" {
+		t.Fatalf("author got name %q", bid.Name)
+	}
+	raw, err := env.store.Storage().Bids.Get(context.Background(), bid.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !raw.Sealed || len(raw.SealedData) == 0 || raw.Name != "" || raw.Amount != nil {
+		t.Fatalf("bid is stored in the clear: %+v", raw)
+	}
+	other, err := NewSealingBids(env.store.Storage().Bids, []byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Get(context.Background(), bid.ID); err == nil {
+		t.Fatal("bid opened with a different key")
+	}
+}
+
+func TestSealedBidsUnsealAfterDeadline(t *testing.T) {
+	env := newTestEnv(t)
+	env.enableSealing()
+	deadline := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	tender := env.publishTender("Road", map[string]interface{}{"sealed": true, "submissionDeadline": deadline})
+	bid := env.createBid(tender, map[string]interface{}{"name": "secret offer"})
+	env.expect("GET", "/api/bids/"+tender.ID.String()+"/list", keyAlice, nil, http.StatusForbidden, nil)
+	env.expect("GET", "/api/bids/"+bid.ID.String()+"/versions", keyAlice, nil, http.StatusForbidden, nil)
+
+	env.passDeadline(tender)
+	var state SealState
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/seal", keyAlice, nil, http.StatusOK, &state)
+	if state.Sealed || state.BidCount != 1 || state.Event == nil || state.Event.Reason != "deadline" || state.Event.BidCount != 1 {
+		t.Fatalf("got seal state %+v", state)
+	}
+	var bids []Bid
+	env.expect("GET", "/api/bids/"+tender.ID.String()+"/list", keyAlice, nil, http.StatusOK, &bids)
+	if len(bids) != 1 || bids[0].Name != "secret offer" {
+		t.Fatalf("got %+v", bids)
+	}
+	raw, err := env.store.Storage().Bids.Get(context.Background(), bid.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.Sealed || raw.Name != "secret offer" {
+		t.Fatalf("bid was not unsealed in storage: %+v", raw)
+	}
+}
+
+func TestSealedBidsListedRightAfterDeadline(t *testing.T) {
+	env := newTestEnv(t)
+	env.enableSealing()
+	tender := env.publishTender("Road", map[string]interface{}{"sealed": true})
+	env.createBid(tender, map[string]interface{}{"name": "secret offer", "amount": "100", "currency": "RUB"})
+
+	env.passDeadline(tender)
+	var bids []Bid
+	env.expect("GET", "/api/bids/"+tender.ID.String()+"/list?minAmount=50", keyAlice, nil, http.StatusOK, &bids)
+	if len(bids) != 1 || bids[0].Name != "secret offer" {
+		t.Fatalf("first listing after the deadline missed the unsealed bid: %+v", bids)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type SearchFilter struct {
+	Query          string
+	Statuses       []string
+	ServiceTypes   []string
+	OrganizationID uuid.UUID
+	CreatedFrom    *time.Time
+	CreatedTo      *time.Time
+	MinAmount      *decimal.Decimal
+	MaxAmount      *decimal.Decimal
+}
+
+type TenderHit struct {
+	Tender
+	Snippet string  `json:"snippet,omitempty"`
+	Rank    float64 `json:"rank"`
+}
+
+type BidHit struct {
+	Bid
+	Snippet string  `json:"snippet,omitempty"`
+	Rank    float64 `json:"rank"`
+}
+
+type AuctionBidHit struct {
+	AuctionBid
+	Snippet string  `json:"snippet,omitempty"`
+	Rank    float64 `json:"rank"`
+}
+
+const snippetWords = 30
+
+func (filter SearchFilter) Matches(status, serviceType string, organizationID uuid.UUID, createdAt time.Time, amount *decimal.Decimal) bool {
+	if len(filter.Statuses) > 0 && !hasStatus(filter.Statuses, status) {
+		return false
+	}
+	if len(filter.ServiceTypes) > 0 && !hasStatus(filter.ServiceTypes, serviceType) {
+		return false
+	}
+	if filter.OrganizationID != uuid.Nil && organizationID != filter.OrganizationID {
+		return false
+	}
+	if filter.CreatedFrom != nil && createdAt.Before(*filter.CreatedFrom) {
+		return false
+	}
+	if filter.CreatedTo != nil && createdAt.After(*filter.CreatedTo) {
+		return false
+	}
+	if filter.MinAmount != nil && (amount == nil || amount.LessThan(*filter.MinAmount)) {
+		return false
+	}
+	if filter.MaxAmount != nil && (amount == nil || amount.GreaterThan(*filter.MaxAmount)) {
+		return false
+	}
+	return true
+}
+
+func (filter SearchFilter) terms() []string {
+	terms := strings.FieldsFunc(strings.ToLower(filter.Query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, term := range terms {
+		if runes := []rune(term); len(runes) > 5 {
+			terms[i] = string(runes[:len(runes)-2])
+		}
+	}
+	return terms
+}
+
+func (filter SearchFilter) MatchText(name, description string) (float64, string, bool) {
+	terms := filter.terms()
+	if len(terms) == 0 {
+		return 0, "", true
+	}
+	var rank float64
+	for _, term := range terms {
+		inName, inDescription := strings.Count(strings.ToLower(name), term), strings.Count(strings.ToLower(description), term)
+		if inName+inDescription == 0 {
+			return 0, "", false
+		}
+		rank += float64(inName) + 0.4*float64(inDescription)
+	}
+	words := strings.Fields(name + " " + description)
+	first := -1
+	for i, word := range words {
+		lower := strings.ToLower(word)
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				if first < 0 {
+					first = i
+				}
+				words[i] = "<b>" + word + "</b>"
+				break
+			}
+		}
+	}
+	start := max(0, min(first-snippetWords/3, len(words)-snippetWords))
+	end := min(len(words), start+snippetWords)
+	return rank, strings.Join(words[start:end], " "), true
+}
+
+func listParam(url url.Values, name string) []string {
+	var values []string
+	for _, value := range url[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+func ParseSearchFilter(w http.ResponseWriter, url url.Values, statuses []string, minName, maxName string) (SearchFilter, bool) {
+	filter := SearchFilter{
+		Query:        strings.TrimSpace(url.Get("q")),
+		Statuses:     listParam(url, "status"),
+		ServiceTypes: listParam(url, "serviceType"),
+	}
+	for _, status := range filter.Statuses {
+		if !hasStatus(statuses, status) {
+			SendErrorResponse(w, ErrorResponse{"Invalid status parameter"}, http.StatusBadRequest)
+			return filter, false
+		}
+	}
+	if org := url.Get("organizationId"); org != "" {
+		id, ok := ParseID(w, org, "organization")
+		if !ok {
+			return filter, false
+		}
+		filter.OrganizationID = id
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **time.Time
+	}{{"createdFrom", &filter.CreatedFrom}, {"createdTo", &filter.CreatedTo}} {
+		if v := url.Get(bound.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Invalid %s parameter", bound.name)}, http.StatusBadRequest)
+				return filter, false
+			}
+			*bound.dst = &t
+		}
+	}
+	for _, bound := range []struct {
+		name string
+		dst  **decimal.Decimal
+	}{{minName, &filter.MinAmount}, {maxName, &filter.MaxAmount}} {
+		if v := url.Get(bound.name); v != "" {
+			amount, err := decimal.NewFromString(v)
+			if err != nil {
+				SendErrorResponse(w, ErrorResponse{fmt.Sprintf("Invalid %s parameter", bound.name)}, http.StatusBadRequest)
+				return filter, false
+			}
+			*bound.dst = &amount
+		}
+	}
+	return filter, true
+}
+
+func SearchTendersHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SearchTendersHandler started")
+	ctx := r.Context()
+	var err error
+	url := r.URL.Query()
+	var page Page
+	if lim := url.Get("limit"); lim != "" {
+		if page.Limit, err = strconv.Atoi(lim); err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if off := url.Get("offset"); off != "" {
+		if page.Offset, err = strconv.Atoi(off); err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
+			return
+		}
+	}
+	filter, ok := ParseSearchFilter(w, url, tenderStatuses, "minBudget", "maxBudget")
+	if !ok {
+		return
+	}
+	var viewerId, organizationId uuid.UUID
+	if user, ok := CurrentPrincipal(ctx); ok {
+		viewerId = user.ID
+		if oi, ok := GetOrganizationId(ctx, w, user.ID); ok {
+			organizationId = oi
+		}
+	}
+	hits, err := storage.Tenders.Search(ctx, filter, viewerId, organizationId, page)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to search tenders"}, http.StatusInternalServerError)
+		return
+	}
+	for i := range hits {
+		if hits[i].OrganizationID != organizationId {
+			hits[i].CreatorUsername = ""
+			hits[i].ReservePrice = nil
+		}
+	}
+	if hits == nil {
+		hits = []TenderHit{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hits)
+}
+
+func SearchBidsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("SearchBidsHandler started")
+	ctx := r.Context()
+	var err error
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	url := r.URL.Query()
+	var page Page
+	if lim := url.Get("limit"); lim != "" {
+		if page.Limit, err = strconv.Atoi(lim); err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if off := url.Get("offset"); off != "" {
+		if page.Offset, err = strconv.Atoi(off); err != nil {
+			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
+			return
+		}
+	}
+	filter, ok := ParseSearchFilter(w, url, bidStatuses, "minAmount", "maxAmount")
+	if !ok {
+		return
+	}
+	var organizationId uuid.UUID
+	if oi, ok := GetOrganizationId(ctx, w, user.ID); ok {
+		organizationId = oi
+	}
+	hits, err := storage.Bids.Search(ctx, filter, user.ID, organizationId, page)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to search bids"}, http.StatusInternalServerError)
+		return
+	}
+	tenders := map[uuid.UUID]Tender{}
+	views := []AuctionBidHit{}
+	for _, hit := range hits {
+		tender, found := tenders[hit.TenderID]
+		if !found {
+			if tn, ok := GetTenderInfo(ctx, w, hit.TenderID); ok {
+				tender = tn
+			} else {
+				return
+			}
+			tenders[tender.ID] = tender
+		}
+		views = append(views, AuctionBidHit{MaskAuctionBid(ctx, tender, hit.Bid, user), hit.Snippet, hit.Rank})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(views)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSearchMatchText(t *testing.T) {
+	filter := SearchFilter{Query: "ремонтов дорог"}
+	rank, snippet, ok := filter.MatchText("Ремонт дороги", "Плановый ремонт дорог района")
+	if !ok || rank != 1+1+0.4*2 {
+		t.Fatalf("got rank %v, ok %v", rank, ok)
+	}
+	if snippet != "<b>Ремонт</b> <b>дороги</b> Плановый <b>ремонт</b> <b>дорог</b> района" {
+		t.Fatalf("got snippet %q", snippet)
+	}
+	if _, _, ok := filter.MatchText("Ремонт моста", "Покраска"); ok {
+		t.Fatal("matched without every term")
+	}
+	if _, _, ok := (SearchFilter{}).MatchText("anything", ""); !ok {
+		t.Fatal("empty query must match everything")
+	}
+}
+
+func TestSearchTenders(t *testing.T) {
+	env := newTestEnv(t)
+	env.publishTender("Road repair", map[string]interface{}{"description": "asphalt"})
+	env.publishTender("Bridge", map[string]interface{}{"description": "road access"})
+	env.publishTender("Road secret", map[string]interface{}{"private": true})
+	env.createTender("Road draft", nil)
+
+	var hits []TenderHit
+	env.expect("GET", "/api/tenders/search?q=road", keyBob, nil, http.StatusOK, &hits)
+	if len(hits) != 2 || hits[0].Name != "Road repair" || hits[1].Name != "Bridge" || hits[0].Rank <= hits[1].Rank {
+		t.Fatalf("got hits %+v", hits)
+	}
+	if hits[0].CreatorUsername != "" {
+		t.Fatalf("creator exposed to another organization: %+v", hits[0])
+	}
+	env.expect("GET", "/api/tenders/search?q=road&status=Created", keyAlice, nil, http.StatusOK, &hits)
+	if len(hits) != 1 || hits[0].Name != "Road draft" || hits[0].CreatorUsername != "alice" {
+		t.Fatalf("tender organization got %+v", hits)
+	}
+	env.expect("GET", "/api/tenders/search?status=Unknown", keyBob, nil, http.StatusBadRequest, nil)
+	env.expect("GET", "/api/tenders/search?createdFrom=yesterday", keyBob, nil, http.StatusBadRequest, nil)
+}
+
+func TestSearchBids(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	env.createBid(tender, map[string]interface{}{"name": "Cheap asphalt"})
+	env.createBid(tender, map[string]interface{}{"name": "Concrete"})
+
+	var hits []BidHit
+	env.expect("GET", "/api/bids/search?q=asphalt", keyBob, nil, http.StatusOK, &hits)
+	if len(hits) != 1 || hits[0].Name != "Cheap asphalt" {
+		t.Fatalf("author got %+v", hits)
+	}
+	env.expect("GET", "/api/bids/search?q=asphalt", keyCarol, nil, http.StatusOK, &hits)
+	if len(hits) != 0 {
+		t.Fatalf("draft bid found by another supplier: %+v", hits)
+	}
+	env.expect("GET", "/api/bids/search", "", nil, http.StatusUnauthorized, nil)
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var tenderStatuses = []string{"Created", "Published", "Evaluation", "Closed", "Cancelled"}
+
+var bidStatuses = []string{BidDraft, BidSubmitted, BidWithdrawn, BidCanceled}
+
+type TransitionError struct {
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	return e.Reason
+}
+
+type tenderTransition struct {
+	Action string
+	From   []string
+	To     string
+	Guard  func(ctx context.Context, tender Tender) (string, error)
+}
+
+type bidTransition struct {
+	Action string
+	From   []string
+	To     string
+	Guard  func(ctx context.Context, bid Bid) (string, error)
+}
+
+type AvailableTransition struct {
+	Action  string `json:"action"`
+	To      string `json:"to"`
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+type TransitionList struct {
+	Status      string                `json:"status"`
+	Transitions []AvailableTransition `json:"transitions"`
+}
+
+var tenderMachine = []tenderTransition{
+	{"publish", []string{"Created"}, "Published", guardTenderPublish},
+	{"unpublish", []string{"Published"}, "Created", guardTenderUnpublish},
+	{"evaluate", []string{"Published"}, "Evaluation", nil},
+	{"close", []string{"Created", "Published", "Evaluation"}, "Closed", nil},
+	{"cancel", []string{"Created", "Published", "Evaluation"}, "Cancelled", nil},
+}
+
+var bidMachine = []bidTransition{
+	{"submit", []string{BidDraft}, BidSubmitted, guardBidSubmission},
+	{"withdraw", []string{BidSubmitted}, BidWithdrawn, guardBidSubmission},
+	{"resubmit", []string{BidWithdrawn}, BidSubmitted, guardBidSubmission},
+	{"cancel", []string{BidDraft, BidSubmitted, BidWithdrawn}, BidCanceled, nil},
+}
+
+func hasStatus(statuses []string, status string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func guardTenderPublish(ctx context.Context, tender Tender) (string, error) {
+	if tender.SubmissionDeadline == nil {
+		return "Tender has no submission deadline", nil
+	}
+	if !tender.SubmissionDeadline.After(time.Now()) {
+		return "Submission deadline has passed", nil
+	}
+	if !tender.RequireScoring {
+		return "", nil
+	}
+	criteria, err := storage.Evaluations.ListCriteria(ctx, tender.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(criteria) == 0 {
+		return "Tender requires scoring but has no criteria", nil
+	}
+	return "", nil
+}
+
+func guardTenderUnpublish(ctx context.Context, tender Tender) (string, error) {
+	count, err := storage.Bids.CountForTender(ctx, tender.ID)
+	if err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "Tender already has bids", nil
+	}
+	return "", nil
+}
+
+func guardBidSubmission(ctx context.Context, bid Bid) (string, error) {
+	tender, err := storage.Tenders.Get(ctx, bid.TenderID)
+	if err != nil {
+		return "", err
+	}
+	if tender.Status != "Published" {
+		return "Tender is not accepting bids", nil
+	}
+	if tender.SubmissionDeadline != nil && !tender.SubmissionDeadline.After(time.Now()) {
+		return "Submission deadline has passed", nil
+	}
+	if tender.UnsealedAt != nil {
+		return "Bids of this tender have been unsealed", nil
+	}
+	return "", nil
+}
+
+func findTenderTransition(tender Tender, to string) (tenderTransition, error) {
+	for _, t := range tenderMachine {
+		if t.To == to && hasStatus(t.From, tender.Status) {
+			return t, nil
+		}
+	}
+	return tenderTransition{}, &TransitionError{"Tender can't be moved from " + tender.Status + " to " + to}
+}
+
+func findBidTransition(bid Bid, to string) (bidTransition, error) {
+	if bid.Decision != "None" {
+		return bidTransition{}, &TransitionError{"Decision already made"}
+	}
+	for _, t := range bidMachine {
+		if t.To == to && hasStatus(t.From, bid.Status) {
+			return t, nil
+		}
+	}
+	return bidTransition{}, &TransitionError{"Bid can't be moved from " + bid.Status + " to " + to}
+}
+
+func ApplyTenderTransition(ctx context.Context, tender *Tender, to, username, cause string) error {
+	transition, err := findTenderTransition(*tender, to)
+	if err != nil {
+		return err
+	}
+	if transition.Guard != nil {
+		blocked, err := transition.Guard(ctx, *tender)
+		if err != nil {
+			return err
+		}
+		if blocked != "" {
+			return &TransitionError{blocked}
+		}
+	}
+	if err := storage.Tenders.AddVersion(ctx, *tender); err != nil {
+		return err
+	}
+	if to == "Evaluation" || to == "Closed" {
+		if err := UnsealTenderBids(ctx, tender, username, cause); err != nil {
+			return err
+		}
+	}
+	if to == "Closed" {
+		if err := cancelUndecidedBids(ctx, *tender, username); err != nil {
+			return err
+		}
+	}
+	if to == "Cancelled" {
+		if err := cancelTenderBids(ctx, *tender, username, cause); err != nil {
+			return err
+		}
+		now := time.Now()
+		tender.CancellationReason, tender.CancelledAt = cause, &now
+	}
+	tender.Status = to
+	tender.ModifiedBy = username
+	tender.Version++
+	return storage.Tenders.Update(ctx, tender)
+}
+
+func cancelUndecidedBids(ctx context.Context, tender Tender, username string) error {
+	bids, _, err := storage.Bids.ListForTender(ctx, tender.ID, tender.OrganizationID, BidFilter{}, Page{})
+	if err != nil {
+		return err
+	}
+	for _, bid := range bids {
+		if bid.Decision != "None" || bid.Status == BidCanceled {
+			continue
+		}
+		if err := storage.Bids.AddVersion(ctx, bid); err != nil {
+			return err
+		}
+		bid.Status = BidCanceled
+		bid.ModifiedBy = username
+		bid.Version++
+		if err := storage.Bids.Update(ctx, &bid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ApplyBidTransition(ctx context.Context, bid *Bid, to, reason, username string) error {
+	transition, err := findBidTransition(*bid, to)
+	if err != nil {
+		return err
+	}
+	if transition.Guard != nil {
+		blocked, err := transition.Guard(ctx, *bid)
+		if err != nil {
+			return err
+		}
+		if blocked != "" {
+			return &TransitionError{blocked}
+		}
+	}
+	if err := storage.Bids.AddVersion(ctx, *bid); err != nil {
+		return err
+	}
+	bid.Status = to
+	bid.WithdrawalReason, bid.WithdrawnAt = "", nil
+	if to == BidWithdrawn {
+		now := time.Now()
+		bid.WithdrawalReason, bid.WithdrawnAt = strings.TrimSpace(reason), &now
+	}
+	bid.ModifiedBy = username
+	bid.Version++
+	return storage.Bids.Update(ctx, bid)
+}
+
+func SendTransitionError(w http.ResponseWriter, err error, message string) {
+	var transitionErr *TransitionError
+	if errors.As(err, &transitionErr) {
+		SendErrorResponse(w, ErrorResponse{transitionErr.Reason}, http.StatusConflict)
+		return
+	}
+	log.Println(err.Error())
+	SendErrorResponse(w, ErrorResponse{message}, http.StatusInternalServerError)
+}
+
+func allows(ctx context.Context, organizationId uuid.UUID, username string, perm Permission) (bool, error) {
+	role, err := storage.Organizations.GetRole(ctx, organizationId, username)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return RoleAllows(role, perm), err
+}
+
+func ShowTenderTransitionsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderTransitionsHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	list := TransitionList{tender.Status, []AvailableTransition{}}
+	permitted, err := allows(ctx, tender.OrganizationID, username, PermChangeTenderStatus)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to authorize"}, http.StatusInternalServerError)
+		return
+	}
+	for _, t := range tenderMachine {
+		if !permitted || !hasStatus(t.From, tender.Status) {
+			continue
+		}
+		available := AvailableTransition{Action: t.Action, To: t.To, Allowed: true}
+		if t.Guard != nil {
+			reason, err := t.Guard(ctx, tender)
+			if err != nil {
+				log.Println(err.Error())
+				SendErrorResponse(w, ErrorResponse{"Failed to check transitions"}, http.StatusInternalServerError)
+				return
+			}
+			available.Allowed, available.Reason = reason == "", reason
+		}
+		list.Transitions = append(list.Transitions, available)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(list)
+}
+
+func ShowBidTransitionsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowBidTransitionsHandler started")
+	ctx := r.Context()
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return
+	}
+	bidId, ok := ParseID(w, mux.Vars(r)["bidId"], "bid")
+	if !ok {
+		return
+	}
+	if !CheckBidExists(ctx, w, bidId) {
+		return
+	}
+	var bid Bid
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
+		bid = bd
+	} else {
+		return
+	}
+	author := IsBidAuthor(ctx, bid, user)
+	if !author && !Authorize(ctx, w, bid.OrganizationID, user.Username, PermViewBid) {
+		return
+	}
+	list := TransitionList{bid.Status, []AvailableTransition{}}
+	for _, t := range bidMachine {
+		if !author || !hasStatus(t.From, bid.Status) {
+			continue
+		}
+		available := AvailableTransition{Action: t.Action, To: t.To, Allowed: true}
+		if bid.Decision != "None" {
+			available.Allowed, available.Reason = false, "Decision already made"
+		} else if t.Guard != nil {
+			reason, err := t.Guard(ctx, bid)
+			if err != nil {
+				log.Println(err.Error())
+				SendErrorResponse(w, ErrorResponse{"Failed to check transitions"}, http.StatusInternalServerError)
+				return
+			}
+			available.Allowed, available.Reason = reason == "", reason
+		}
+		list.Transitions = append(list.Transitions, available)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(list)
+}
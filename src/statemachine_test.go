@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTenderPublishRequiresDeadline(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.createTender("Road", map[string]interface{}{"submissionDeadline": nil})
+	status := "/api/tenders/" + tender.ID.String() + "/status?status=Published"
+	env.expect("PUT", status, keyAlice, nil, http.StatusConflict, nil)
+
+	var list TransitionList
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/transitions", keyAlice, nil, http.StatusOK, &list)
+	for _, transition := range list.Transitions {
+		if transition.Action == "publish" && (transition.Allowed || transition.Reason == "") {
+			t.Fatalf("publish is offered without a deadline: %+v", transition)
+		}
+	}
+	env.expect("PATCH", "/api/tenders/"+tender.ID.String()+"/edit", keyAlice,
+		map[string]interface{}{"submissionDeadline": testDeadline()}, http.StatusOK, nil)
+	env.expect("PUT", status, keyAlice, nil, http.StatusOK, nil)
+}
+
+func TestTenderTransitions(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	path := "/api/tenders/" + tender.ID.String() + "/status?status="
+	env.expect("PUT", path+"Published", keyAlice, nil, http.StatusConflict, nil)
+	env.expect("PUT", path+"Closed", keyDave, nil, http.StatusForbidden, nil)
+	env.expect("PUT", path+"Unknown", keyAlice, nil, http.StatusBadRequest, nil)
+	draft := env.createBid(tender, nil)
+	submitted := env.submitBid(tender, nil)
+	env.expect("PUT", path+"Created", keyAlice, nil, http.StatusConflict, nil)
+	env.expect("PUT", path+"Evaluation", keyAlice, nil, http.StatusOK, nil)
+	env.expect("PUT", path+"Closed", keyAlice, nil, http.StatusOK, nil)
+	env.expect("PUT", path+"Published", keyAlice, nil, http.StatusConflict, nil)
+	if got := env.storedBid(draft.ID); got.Status != BidCanceled {
+		t.Fatalf("draft bid is %s after close", got.Status)
+	}
+	if got := env.storedBid(submitted.ID); got.Status != BidCanceled {
+		t.Fatalf("submitted bid is %s after close", got.Status)
+	}
+}
+
+func TestTransitionListings(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	bid := env.createBid(tender, nil)
+	var list TransitionList
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/transitions", keyAlice, nil, http.StatusOK, &list)
+	allowed := map[string]bool{}
+	for _, transition := range list.Transitions {
+		allowed[transition.To] = transition.Allowed
+	}
+	if !allowed["Evaluation"] || !allowed["Closed"] || allowed["Created"] {
+		t.Fatalf("got tender transitions %+v", list.Transitions)
+	}
+	var bidList TransitionList
+	env.expect("GET", "/api/bids/"+bid.ID.String()+"/transitions", keyBob, nil, http.StatusOK, &bidList)
+	targets := map[string]bool{}
+	for _, transition := range bidList.Transitions {
+		targets[transition.To] = transition.Allowed
+	}
+	if !targets[BidSubmitted] || len(targets) != 2 {
+		t.Fatalf("got draft bid transitions %+v", bidList.Transitions)
+	}
+}
+
+func TestTenderCloseCancelsUndecidedBids(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	status := "/api/tenders/" + tender.ID.String() + "/status"
+	draft := env.createBid(tender, nil)
+	submitted := env.submitBid(tender, nil)
+	rejected := env.submitBid(tender, nil)
+	winner := env.submitBid(tender, nil)
+	env.decide(rejected, keyAlice, "Rejected", "", http.StatusOK)
+
+	if w := env.expect("GET", status, keyAlice, nil, http.StatusOK, nil); w.Body.String() != "Published" {
+		t.Fatalf("tender is %s after a rejection, want Published", w.Body.String())
+	}
+	if got := env.storedBid(submitted.ID); got.Status != BidSubmitted {
+		t.Fatalf("competing bid is %s after a rejection", got.Status)
+	}
+
+	env.decide(winner, keyAlice, "Approved", "", http.StatusOK)
+	env.decide(winner, keyDave, "Approved", "", http.StatusOK)
+	if w := env.expect("GET", status, keyAlice, nil, http.StatusOK, nil); w.Body.String() != "Closed" {
+		t.Fatalf("tender is %s after the award, want Closed", w.Body.String())
+	}
+	for _, id := range []Bid{draft, submitted} {
+		if got := env.storedBid(id.ID); got.Status != BidCanceled || got.Decision != "None" {
+			t.Fatalf("undecided bid is %s/%s after close", got.Status, got.Decision)
+		}
+	}
+	if got := env.storedBid(rejected.ID); got.Decision != "Rejected" {
+		t.Fatalf("decided bid changed to %s", got.Decision)
+	}
+	if got := env.storedBid(winner.ID); got.Decision != "Approved" {
+		t.Fatalf("winning bid is %s", got.Decision)
+	}
+	env.expect("PUT", "/api/bids/"+submitted.ID.String()+"/submit", keyBob, nil, http.StatusConflict, nil)
+}
@@ -6,27 +6,47 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
 )
 
 type Tender struct {
-	ID              uuid.UUID `json:"id"`
-	Name            string    `json:"name"`
-	Description     string    `json:"description"`
-	ServiceType     string    `json:"serviceType"`
-	Status          string    `json:"status"`
-	OrganizationID  uuid.UUID `json:"organizationId"`
-	CreatorUsername string    `json:"creatorUsername"`
-	Version         int       `json:"version"`
-	CreatedAt       time.Time `json:"createdAt"`
+	ID                 uuid.UUID        `json:"id"`
+	Name               string           `json:"name"`
+	Description        string           `json:"description"`
+	ServiceType        string           `json:"serviceType"`
+	Status             string           `json:"status"`
+	OrganizationID     uuid.UUID        `json:"organizationId"`
+	CreatorUsername    string           `json:"creatorUsername,omitempty"`
+	Version            int              `json:"version"`
+	CreatedAt          time.Time        `json:"createdAt"`
+	ModifiedBy         string           `json:"-"`
+	ModifiedAt         time.Time        `json:"-"`
+	SubmissionDeadline *time.Time       `json:"submissionDeadline,omitempty"`
+	PublishAt          *time.Time       `json:"publishAt,omitempty"`
+	Budget             *decimal.Decimal `json:"budget,omitempty"`
+	ReservePrice       *decimal.Decimal `json:"reservePrice,omitempty"`
+	MaxBidAmount       *decimal.Decimal `json:"maxBidAmount,omitempty"`
+	Currency           string           `json:"currency,omitempty"`
+	RejectAboveMax     bool             `json:"rejectAboveMax,omitempty"`
+	Sealed             bool             `json:"sealed,omitempty"`
+	UnsealedAt         *time.Time       `json:"unsealedAt,omitempty"`
+	Mode               string           `json:"mode,omitempty"`
+	AuctionStep        *decimal.Decimal `json:"auctionStep,omitempty"`
+	AuctionExtension   int              `json:"auctionExtensionSeconds,omitempty"`
+	RequireScoring     bool             `json:"requireScoring,omitempty"`
+	Private            bool             `json:"private,omitempty"`
+	CancellationReason string           `json:"cancellationReason,omitempty"`
+	CancelledAt        *time.Time       `json:"cancelledAt,omitempty"`
 }
 
 func CreateTenderHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("CreateTenderHandler started")
+	ctx := r.Context()
 	buf := new(bytes.Buffer)
 	if n, err := buf.ReadFrom(r.Body); err != nil || n == 0 {
 		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
@@ -38,19 +58,40 @@ func CreateTenderHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
 		return
 	}
-	if !CheckUsernameExists(w, tender.CreatorUsername) {
+	if user, ok := CurrentPrincipal(ctx); ok {
+		if tender.CreatorUsername == "" {
+			tender.CreatorUsername = user.Username
+		} else if tender.CreatorUsername != user.Username {
+			SendErrorResponse(w, ErrorResponse{"Don't have rights"}, http.StatusForbidden)
+			return
+		}
+	} else if !legacyAuth {
+		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+		return
+	}
+	if !CheckUsernameExists(ctx, w, tender.CreatorUsername) {
+		return
+	}
+	if !CheckTenderSchedule(w, tender, Tender{}) {
 		return
 	}
-	if !CheckOrganizationExists(w, tender.OrganizationID.String()) {
+	if !CheckTenderPricing(w, tender) {
 		return
 	}
-	if !CheckOrganizationUser(w, tender.OrganizationID, tender.CreatorUsername) {
+	tender.UnsealedAt = nil
+	if !CheckTenderSealing(ctx, w, tender, Tender{}) {
 		return
 	}
-	query := `INSERT INTO tender (name, description, service_type, organization_id, creator_username)
-              VALUES ($1, $2, $3, $4, $5)
-              RETURNING id, status, version, created_at`
-	err := db.QueryRow(context.Background(), query, tender.Name, tender.Description, tender.ServiceType, tender.OrganizationID, tender.CreatorUsername).Scan(&tender.ID, &tender.Status, &tender.Version, &tender.CreatedAt)
+	if !CheckTenderMode(ctx, w, &tender, Tender{}) {
+		return
+	}
+	if !CheckOrganizationExists(ctx, w, tender.OrganizationID) {
+		return
+	}
+	if !Authorize(ctx, w, tender.OrganizationID, tender.CreatorUsername, PermCreateTender) {
+		return
+	}
+	err := storage.Tenders.Create(ctx, &tender)
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to create tender"}, http.StatusInternalServerError)
@@ -63,51 +104,30 @@ func CreateTenderHandler(w http.ResponseWriter, r *http.Request) {
 
 func ShowTendersHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ShowTendersHandler started")
-	var err error
+	ctx := r.Context()
 	url := r.URL.Query()
-	query := "SELECT id, name, description, service_type, status, organization_id, version, created_at\nFROM tender\nWHERE status = 'Published'"
-	args := []interface{}{}
-	if st := url.Get("service_type"); st != "" {
-		query += " AND service_type = $" + strconv.Itoa(len(args)+1)
-		args = append(args, st)
-	}
-	query += "\nORDER BY name ASC"
-	if lim := url.Get("limit"); lim != "" {
-		limit := 5
-		if limit, err = strconv.Atoi(lim); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nLIMIT $" + strconv.Itoa(len(args)+1)
-		args = append(args, limit)
+	page, ok := ParsePage(w, url, tenderSorts)
+	if !ok {
+		return
 	}
-	if off := url.Get("offset"); off != "" {
-		offset := 0
-		if offset, err = strconv.Atoi(off); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
-			return
+	var viewerId, organizationId uuid.UUID
+	if user, ok := CurrentPrincipal(ctx); ok {
+		viewerId = user.ID
+		if oi, ok := GetOrganizationId(ctx, w, user.ID); ok {
+			organizationId = oi
 		}
-		query += "\nOFFSET $" + strconv.Itoa(len(args)+1)
-		args = append(args, offset)
 	}
-	rows, err := db.Query(context.Background(), query, args...)
+	tenders, total, err := storage.Tenders.ListPublished(ctx, url.Get("service_type"), viewerId, organizationId, page.Lookahead())
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find tenders"}, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	var tenders []Tender
-	for rows.Next() {
-		var tender Tender
-		err := rows.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.Version, &tender.CreatedAt)
-		if err != nil {
-			log.Println(err.Error())
-			SendErrorResponse(w, ErrorResponse{"Can't scan rows"}, http.StatusInternalServerError)
-			return
-		}
-		tenders = append(tenders, tender)
+	for i := range tenders {
+		tenders[i].CreatorUsername = ""
+		tenders[i].ReservePrice = nil
 	}
+	tenders = WritePage(w, r, page, tenders, total)
 	answer, er := json.Marshal(tenders)
 	if er != nil {
 		log.Println(er.Error())
@@ -121,57 +141,28 @@ func ShowTendersHandler(w http.ResponseWriter, r *http.Request) {
 
 func ShowUsersTendersHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ShowUsersTendersHandler started")
-	var err error
-	url := r.URL.Query()
-	query := "SELECT id, name, description, service_type, status, organization_id, version, creator_username, created_at\nFROM tender"
-	args := []interface{}{}
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		query += "\nWHERE creator_username = $" + strconv.Itoa(len(args)+1)
-		args = append(args, us)
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
-	query += "\nORDER BY name ASC"
-	if lim := url.Get("limit"); lim != "" {
-		limit := 5
-		if limit, err = strconv.Atoi(lim); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid limit parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nLIMIT $" + strconv.Itoa(len(args)+1)
-		args = append(args, limit)
-	}
-	if off := url.Get("offset"); off != "" {
-		offset := 0
-		if offset, err = strconv.Atoi(off); err != nil {
-			SendErrorResponse(w, ErrorResponse{"Invalid offset parameter"}, http.StatusBadRequest)
-			return
-		}
-		query += "\nOFFSET $" + strconv.Itoa(len(args)+1)
-		args = append(args, offset)
+	url := r.URL.Query()
+	page, ok := ParsePage(w, url, tenderSorts)
+	if !ok {
+		return
 	}
-	rows, err := db.Query(context.Background(), query, args...)
+	tenders, total, err := storage.Tenders.ListByCreator(ctx, username, page.Lookahead())
 	if err != nil {
 		log.Println(err.Error())
 		SendErrorResponse(w, ErrorResponse{"Failed to find tenders"}, http.StatusInternalServerError)
 		return
 	}
-	defer rows.Close()
-	var tenders []Tender
-	for rows.Next() {
-		var tender Tender
-		err := rows.Scan(&tender.ID, &tender.Name, &tender.Description, &tender.ServiceType, &tender.Status, &tender.OrganizationID, &tender.Version, &tender.CreatorUsername, &tender.CreatedAt)
-		if err != nil {
-			log.Println(err.Error())
-			SendErrorResponse(w, ErrorResponse{"Can't scan rows"}, http.StatusInternalServerError)
-			return
+	for i := range tenders {
+		if !CanSeeReservePrice(ctx, tenders[i], username) {
+			tenders[i].ReservePrice = nil
 		}
-		tenders = append(tenders, tender)
 	}
+	tenders = WritePage(w, r, page, tenders, total)
 	answer, er := json.Marshal(tenders)
 	if er != nil {
 		log.Println(er.Error())
@@ -185,75 +176,64 @@ func ShowUsersTendersHandler(w http.ResponseWriter, r *http.Request) {
 
 func ShowTenderStatusHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("ShowTenderStatusHandler started")
-	var err error
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
 	vars := mux.Vars(r)
-	tenderId := vars["tenderId"]
-	if !CheckTenderExists(w, tenderId) {
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
 		return
 	}
-	query := `SELECT status, organization_id
-			  FROM tender t
-			  WHERE t.id = $1`
-	status := ""
-	var org uuid.UUID
-	err = db.QueryRow(context.Background(), query, tenderId).Scan(&status, &org)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to find tender"}, http.StatusInternalServerError)
+	if !CheckTenderExists(ctx, w, tenderId) {
 		return
 	}
-	if status != "Published" {
-		if !CheckOrganizationUser(w, org, username) {
-			return
-		}
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return
+	}
+	if !CheckTenderVisible(ctx, w, tender, username, PermViewTender) {
+		return
+	}
+	if CheckNotModified(w, r, tender.ID, tender.Version) {
+		return
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(status))
+	w.Write([]byte(tender.Status))
 }
 
 func EditTenderStatusHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("EditTenderStatusHandler started")
-	var err error
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
+	url := r.URL.Query()
 	vars := mux.Vars(r)
-	tenderId := vars["tenderId"]
-	if !CheckTenderExists(w, tenderId) {
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
 		return
 	}
 	var tender Tender
-	if tn, ok := GetTenderInfo(w, tenderId); ok {
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
 		tender = tn
 	} else {
 		return
 	}
-	if !CheckOrganizationUser(w, tender.OrganizationID, username) {
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermChangeTenderStatus) {
 		return
 	}
 	status := ""
 	if st := url.Get("status"); st != "" {
-		if st == "Published" || st == "Created" || st == "Closed" {
+		if hasStatus(tenderStatuses, st) {
 			status = st
 		} else {
 			SendErrorResponse(w, ErrorResponse{"Undefined status provided"}, http.StatusBadRequest)
@@ -263,19 +243,15 @@ func EditTenderStatusHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"No status provided"}, http.StatusBadRequest)
 		return
 	}
-	if !AddTenderToVersionsList(w, tender) {
-		return
+	cause := strings.ToLower(status)
+	if status == "Cancelled" {
+		cause = url.Get("reason")
 	}
-	query := `UPDATE tender
-			 SET status = $1, version = $2, updated_at = NOW()
-			 WHERE id = $3
-			 RETURNING status, version`
-	err = db.QueryRow(context.Background(), query, status, tender.Version+1, tender.ID).Scan(&tender.Status, &tender.Version)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit tender status"}, http.StatusInternalServerError)
+	tender, ok = ChangeTenderStatus(ctx, w, r, tenderId, status, cause, username)
+	if !ok {
 		return
 	}
+	SetETag(w, tender.ID, tender.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(tender)
@@ -283,33 +259,26 @@ func EditTenderStatusHandler(w http.ResponseWriter, r *http.Request) {
 
 func EditTenderHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("EditTenderHandler started")
-	var err error
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
 	vars := mux.Vars(r)
-	tenderId := vars["tenderId"]
-	if !CheckTenderExists(w, tenderId) {
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
+		return
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
 		return
 	}
 	var tender Tender
-	if tn, ok := GetTenderInfo(w, tenderId); ok {
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
 		tender = tn
 	} else {
 		return
 	}
-	if !CheckOrganizationUser(w, tender.OrganizationID, username) {
-		return
-	}
-	if !AddTenderToVersionsList(w, tender) {
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermEditTender) {
 		return
 	}
 	buf := new(bytes.Buffer)
@@ -317,82 +286,140 @@ func EditTenderHandler(w http.ResponseWriter, r *http.Request) {
 		SendErrorResponse(w, ErrorResponse{"Can't read body"}, http.StatusBadRequest)
 		return
 	}
-	if err = json.Unmarshal(buf.Bytes(), &tender); err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+	expected, ok := GetExpectedVersion(w, r, buf.Bytes())
+	if !ok {
 		return
 	}
-	query := `UPDATE tender
-			  SET name = $1, description = $2, service_type = $3, version = $4, updated_at = NOW()
-			  WHERE id = $5
-			  RETURNING version`
-	err = db.QueryRow(context.Background(), query, tender.Name, tender.Description, tender.ServiceType, tender.Version+1, tender.ID).Scan(&tender.Version)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit tender"}, http.StatusInternalServerError)
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, tender.ID, tender.Version, expected) {
+			return false
+		}
+		patch := tender
+		if err := json.Unmarshal(buf.Bytes(), &patch); err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Can't unmarshal"}, http.StatusBadRequest)
+			return false
+		}
+		return AmendTender(ctx, w, &tender, patch, username)
+	})
+	if !ok {
 		return
 	}
+	SetETag(w, tender.ID, tender.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(tender)
 }
 
+func AmendTender(ctx context.Context, w http.ResponseWriter, tender *Tender, patch Tender, username string) bool {
+	if !AddTenderToVersionsList(ctx, w, *tender) {
+		return false
+	}
+	if !CheckTenderSchedule(w, patch, *tender) {
+		return false
+	}
+	if !CheckTenderPricing(w, patch) {
+		return false
+	}
+	if !CheckTenderSealing(ctx, w, patch, *tender) {
+		return false
+	}
+	if !CheckTenderMode(ctx, w, &patch, *tender) {
+		return false
+	}
+	tender.Name, tender.Description, tender.ServiceType = patch.Name, patch.Description, patch.ServiceType
+	tender.SubmissionDeadline, tender.PublishAt = patch.SubmissionDeadline, patch.PublishAt
+	CopyTenderPricing(tender, patch)
+	tender.Sealed = patch.Sealed
+	tender.Mode, tender.AuctionStep, tender.AuctionExtension = patch.Mode, patch.AuctionStep, patch.AuctionExtension
+	tender.RequireScoring = patch.RequireScoring
+	tender.Private = patch.Private
+	tender.ModifiedBy = username
+	tender.Version++
+	err := storage.Tenders.Update(ctx, tender)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to edit tender"}, http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
 func TenderRollbackHandler(w http.ResponseWriter, r *http.Request) {
 	log.Println("TenderRollbackHandler started")
-	var err error
-	username := ""
-	url := r.URL.Query()
-	if us := url.Get("username"); us != "" {
-		if !CheckUsernameExists(w, us) {
-			return
-		}
-		username = us
-	} else {
-		SendErrorResponse(w, ErrorResponse{"No user provided"}, http.StatusUnauthorized)
+	ctx := r.Context()
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
 		return
 	}
 	vars := mux.Vars(r)
-	tenderId := vars["tenderId"]
-	vers := vars["version"]
-	if !CheckTenderExists(w, tenderId) {
+	tenderId, ok := ParseID(w, vars["tenderId"], "tender")
+	if !ok {
 		return
 	}
-	var tender Tender
-	if tn, ok := GetTenderInfo(w, tenderId); ok {
-		tender = tn
-	} else {
+	vers, ok := ParseVersion(w, vars["version"])
+	if !ok {
 		return
 	}
-	if !CheckOrganizationUser(w, tender.OrganizationID, username) {
+	if !CheckTenderExists(ctx, w, tenderId) {
 		return
 	}
-	if !CheckTenderVersionExists(w, tenderId, vers) {
+	var tender Tender
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
 		return
 	}
-	if !AddTenderToVersionsList(w, tender) {
+	if !Authorize(ctx, w, tender.OrganizationID, username, PermRollbackTender) {
 		return
 	}
-	new_vers := tender.Version + 1
-	if tn, ok := GetTenderVersionInfo(w, tenderId, vers); ok {
-		tender = tn
-	} else {
+	if !CheckTenderVersionExists(ctx, w, tenderId, vers) {
 		return
 	}
-	query := `UPDATE tender
-			  SET name = $1, description = $2, service_type = $3, version = $4, status = $5, updated_at = NOW()
-			  WHERE id = $6
-			  RETURNING version`
-	err = db.QueryRow(context.Background(), query, tender.Name, tender.Description, tender.ServiceType, new_vers, tender.Status, tenderId).Scan(&tender.Version)
-	if err != nil {
-		log.Println(err.Error())
-		SendErrorResponse(w, ErrorResponse{"Failed to edit tender"}, http.StatusInternalServerError)
+	expected, ok := GetExpectedVersion(w, r, nil)
+	if !ok {
 		return
 	}
-	if tn, ok := GetTenderInfo(w, tenderId); ok {
-		tender = tn
-	} else {
+	ok = RunInTx(ctx, w, func(ctx context.Context) bool {
+		if tn, ok := GetTenderInfoForUpdate(ctx, w, tenderId); ok {
+			tender = tn
+		} else {
+			return false
+		}
+		if !CheckVersionPrecondition(w, r, tender.ID, tender.Version, expected) {
+			return false
+		}
+		if !AddTenderToVersionsList(ctx, w, tender) {
+			return false
+		}
+		var old Tender
+		if tn, ok := GetTenderVersionInfo(ctx, w, tenderId, vers); ok {
+			old = tn
+		} else {
+			return false
+		}
+		tender.Name, tender.Description, tender.ServiceType = old.Name, old.Description, old.ServiceType
+		tender.SubmissionDeadline, tender.PublishAt = old.SubmissionDeadline, old.PublishAt
+		CopyTenderPricing(&tender, old)
+		tender.ModifiedBy = username
+		tender.Version++
+		err := storage.Tenders.Update(ctx, &tender)
+		if err != nil {
+			log.Println(err.Error())
+			SendErrorResponse(w, ErrorResponse{"Failed to edit tender"}, http.StatusInternalServerError)
+			return false
+		}
+		return RestoreAttachments(ctx, w, AttachmentTender, tender.ID, vers, tender.Version)
+	})
+	if !ok {
 		return
 	}
+	SetETag(w, tender.ID, tender.Version)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(tender)
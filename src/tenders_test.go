@@ -0,0 +1,27 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestTenderListOmitsCreator(t *testing.T) {
+	env := newTestEnv(t)
+	tender := env.publishTender("Road", nil)
+	for _, path := range []string{"/api/tenders", "/api/tenders/search?q=road"} {
+		var items []map[string]json.RawMessage
+		env.expect("GET", path, keyBob, nil, http.StatusOK, &items)
+		if len(items) != 1 {
+			t.Fatalf("%s: got %d tenders", path, len(items))
+		}
+		if _, ok := items[0]["creatorUsername"]; ok {
+			t.Fatalf("%s exposes creatorUsername", path)
+		}
+	}
+	var own Tender
+	env.expect("GET", "/api/tenders/"+tender.ID.String()+"/versions/2", keyAlice, nil, http.StatusOK, &own)
+	if own.CreatorUsername != "alice" {
+		t.Fatalf("got creator %q for the owner", own.CreatorUsername)
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type VersionSummary struct {
+	Version    int       `json:"version"`
+	Status     string    `json:"status"`
+	ModifiedBy string    `json:"modifiedBy,omitempty"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	Current    bool      `json:"current"`
+}
+
+type TenderVersion struct {
+	Tender
+	ModifiedBy string    `json:"modifiedBy"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+type BidVersion struct {
+	Bid
+	ModifiedBy string    `json:"modifiedBy"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+type AuctionBidVersion struct {
+	AuctionBid
+	ModifiedBy string    `json:"modifiedBy,omitempty"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+type VersionDiff struct {
+	From    int           `json:"from"`
+	To      int           `json:"to"`
+	Changes []FieldChange `json:"changes"`
+}
+
+type versionField struct {
+	Name  string
+	Value interface{}
+}
+
+func tenderVersionFields(tender Tender) []versionField {
+	return []versionField{
+		{"name", tender.Name},
+		{"description", tender.Description},
+		{"serviceType", tender.ServiceType},
+		{"status", tender.Status},
+		{"submissionDeadline", tender.SubmissionDeadline},
+		{"publishAt", tender.PublishAt},
+		{"budget", tender.Budget},
+		{"reservePrice", tender.ReservePrice},
+		{"maxBidAmount", tender.MaxBidAmount},
+		{"currency", tender.Currency},
+		{"rejectAboveMax", tender.RejectAboveMax},
+	}
+}
+
+func bidVersionFields(bid Bid) []versionField {
+	return []versionField{
+		{"name", bid.Name},
+		{"description", bid.Description},
+		{"status", bid.Status},
+		{"amount", bid.Amount},
+		{"currency", bid.Currency},
+		{"lineItems", bid.LineItems},
+	}
+}
+
+func diffVersionFields(from, to []versionField) []FieldChange {
+	changes := []FieldChange{}
+	for i := range from {
+		before, _ := json.Marshal(from[i].Value)
+		after, _ := json.Marshal(to[i].Value)
+		if string(before) != string(after) {
+			changes = append(changes, FieldChange{from[i].Name, from[i].Value, to[i].Value})
+		}
+	}
+	return changes
+}
+
+func GetVisibleTender(ctx context.Context, w http.ResponseWriter, r *http.Request) (Tender, string, bool) {
+	var tender Tender
+	username, ok := GetCurrentUsername(ctx, w)
+	if !ok {
+		return tender, username, false
+	}
+	tenderId, ok := ParseID(w, mux.Vars(r)["tenderId"], "tender")
+	if !ok {
+		return tender, username, false
+	}
+	if !CheckTenderExists(ctx, w, tenderId) {
+		return tender, username, false
+	}
+	if tn, ok := GetTenderInfo(ctx, w, tenderId); ok {
+		tender = tn
+	} else {
+		return tender, username, false
+	}
+	if !CheckTenderVisible(ctx, w, tender, username, PermViewTender) {
+		return tender, username, false
+	}
+	return tender, username, true
+}
+
+func GetTenderAtVersion(ctx context.Context, w http.ResponseWriter, tender Tender, vers int) (TenderVersion, bool) {
+	if vers == tender.Version {
+		return TenderVersion{tender, tender.ModifiedBy, tender.ModifiedAt}, true
+	}
+	if !CheckTenderVersionExists(ctx, w, tender.ID, vers) {
+		return TenderVersion{}, false
+	}
+	old, ok := GetTenderVersionInfo(ctx, w, tender.ID, vers)
+	if !ok {
+		return TenderVersion{}, false
+	}
+	snapshot := tender
+	snapshot.Name, snapshot.Description, snapshot.ServiceType, snapshot.Status = old.Name, old.Description, old.ServiceType, old.Status
+	snapshot.SubmissionDeadline, snapshot.PublishAt = old.SubmissionDeadline, old.PublishAt
+	CopyTenderPricing(&snapshot, old)
+	snapshot.Version = vers
+	return TenderVersion{snapshot, old.ModifiedBy, old.ModifiedAt}, true
+}
+
+func ShowTenderVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderVersionsHandler started")
+	ctx := r.Context()
+	tender, _, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	versions, err := storage.Tenders.ListVersions(ctx, tender.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find tender versions"}, http.StatusInternalServerError)
+		return
+	}
+	summaries := []VersionSummary{}
+	for _, tn := range versions {
+		summaries = append(summaries, VersionSummary{tn.Version, tn.Status, tn.ModifiedBy, tn.ModifiedAt, false})
+	}
+	summaries = append(summaries, VersionSummary{tender.Version, tender.Status, tender.ModifiedBy, tender.ModifiedAt, true})
+	SetETag(w, tender.ID, tender.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(summaries)
+}
+
+func ShowTenderVersionHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowTenderVersionHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	vers, ok := ParseVersion(w, mux.Vars(r)["version"])
+	if !ok {
+		return
+	}
+	snapshot, ok := GetTenderAtVersion(ctx, w, tender, vers)
+	if !ok {
+		return
+	}
+	if !CanSeeReservePrice(ctx, tender, username) {
+		snapshot.ReservePrice = nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(snapshot)
+}
+
+func TenderVersionsDiffHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("TenderVersionsDiffHandler started")
+	ctx := r.Context()
+	tender, username, ok := GetVisibleTender(ctx, w, r)
+	if !ok {
+		return
+	}
+	from, to, ok := ParseVersionRange(w, r, tender.Version)
+	if !ok {
+		return
+	}
+	before, ok := GetTenderAtVersion(ctx, w, tender, from)
+	if !ok {
+		return
+	}
+	after, ok := GetTenderAtVersion(ctx, w, tender, to)
+	if !ok {
+		return
+	}
+	if !CanSeeReservePrice(ctx, tender, username) {
+		before.ReservePrice, after.ReservePrice = nil, nil
+	}
+	diff := VersionDiff{from, to, diffVersionFields(tenderVersionFields(before.Tender), tenderVersionFields(after.Tender))}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diff)
+}
+
+func GetVisibleBid(ctx context.Context, w http.ResponseWriter, r *http.Request) (Bid, Tender, Principal, bool) {
+	var bid Bid
+	var tender Tender
+	user, ok := GetCurrentUser(ctx, w)
+	if !ok {
+		return bid, tender, user, false
+	}
+	bidId, ok := ParseID(w, mux.Vars(r)["bidId"], "bid")
+	if !ok {
+		return bid, tender, user, false
+	}
+	if !CheckBidExists(ctx, w, bidId) {
+		return bid, tender, user, false
+	}
+	if bd, ok := GetBidInfo(ctx, w, bidId); ok {
+		bid = bd
+	} else {
+		return bid, tender, user, false
+	}
+	if !CheckBidVisible(ctx, w, bid, user) {
+		return bid, tender, user, false
+	}
+	if !CheckBidNotSealed(w, bid) {
+		return bid, tender, user, false
+	}
+	if tn, ok := GetTenderInfo(ctx, w, bid.TenderID); ok {
+		tender = tn
+	} else {
+		return bid, tender, user, false
+	}
+	return bid, tender, user, true
+}
+
+func GetBidAtVersion(ctx context.Context, w http.ResponseWriter, bid Bid, vers int) (BidVersion, bool) {
+	if vers == bid.Version {
+		return BidVersion{bid, bid.ModifiedBy, bid.ModifiedAt}, true
+	}
+	if !CheckBidVersionExists(ctx, w, bid.ID, vers) {
+		return BidVersion{}, false
+	}
+	old, ok := GetBidVersionInfo(ctx, w, bid.ID, vers)
+	if !ok {
+		return BidVersion{}, false
+	}
+	snapshot := bid
+	snapshot.Name, snapshot.Description, snapshot.Status = old.Name, old.Description, old.Status
+	snapshot.Decision, snapshot.ApprovedCount = old.Decision, old.ApprovedCount
+	snapshot.Amount, snapshot.Currency, snapshot.LineItems = old.Amount, old.Currency, old.LineItems
+	snapshot.Version = vers
+	return BidVersion{snapshot, old.ModifiedBy, old.ModifiedAt}, true
+}
+
+func ShowBidVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowBidVersionsHandler started")
+	ctx := r.Context()
+	bid, tender, user, ok := GetVisibleBid(ctx, w, r)
+	if !ok {
+		return
+	}
+	versions, err := storage.Bids.ListVersions(ctx, bid.ID)
+	if err != nil {
+		log.Println(err.Error())
+		SendErrorResponse(w, ErrorResponse{"Failed to find bid versions"}, http.StatusInternalServerError)
+		return
+	}
+	summaries := []VersionSummary{}
+	for _, bd := range versions {
+		summaries = append(summaries, VersionSummary{bd.Version, bd.Status, bd.ModifiedBy, bd.ModifiedAt, false})
+	}
+	summaries = append(summaries, VersionSummary{bid.Version, bid.Status, bid.ModifiedBy, bid.ModifiedAt, true})
+	if AuctionBidHidden(ctx, tender, bid, user) {
+		for i := range summaries {
+			summaries[i].ModifiedBy = ""
+		}
+	}
+	SetETag(w, bid.ID, bid.Version)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(summaries)
+}
+
+func ShowBidVersionHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("ShowBidVersionHandler started")
+	ctx := r.Context()
+	bid, tender, user, ok := GetVisibleBid(ctx, w, r)
+	if !ok {
+		return
+	}
+	vers, ok := ParseVersion(w, mux.Vars(r)["version"])
+	if !ok {
+		return
+	}
+	snapshot, ok := GetBidAtVersion(ctx, w, bid, vers)
+	if !ok {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if tender.Mode != ModeAuction {
+		json.NewEncoder(w).Encode(snapshot)
+		return
+	}
+	view := AuctionBidVersion{MaskAuctionBid(ctx, tender, snapshot.Bid, user), snapshot.ModifiedBy, snapshot.ModifiedAt}
+	if AuctionBidHidden(ctx, tender, bid, user) {
+		view.ModifiedBy = ""
+	}
+	json.NewEncoder(w).Encode(view)
+}
+
+func BidVersionsDiffHandler(w http.ResponseWriter, r *http.Request) {
+	log.Println("BidVersionsDiffHandler started")
+	ctx := r.Context()
+	bid, tender, user, ok := GetVisibleBid(ctx, w, r)
+	if !ok {
+		return
+	}
+	from, to, ok := ParseVersionRange(w, r, bid.Version)
+	if !ok {
+		return
+	}
+	before, ok := GetBidAtVersion(ctx, w, bid, from)
+	if !ok {
+		return
+	}
+	after, ok := GetBidAtVersion(ctx, w, bid, to)
+	if !ok {
+		return
+	}
+	if AuctionBidHidden(ctx, tender, bid, user) {
+		for _, version := range []*BidVersion{&before, &after} {
+			version.Amount, version.Currency, version.LineItems = nil, "", nil
+		}
+	}
+	diff := VersionDiff{from, to, diffVersionFields(bidVersionFields(before.Bid), bidVersionFields(after.Bid))}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diff)
+}
+
+func ParseVersionRange(w http.ResponseWriter, r *http.Request, current int) (int, int, bool) {
+	url := r.URL.Query()
+	from, ok := ParseVersion(w, url.Get("from"))
+	if !ok {
+		return from, 0, false
+	}
+	to := current
+	if value := url.Get("to"); value != "" {
+		if to, ok = ParseVersion(w, value); !ok {
+			return from, to, false
+		}
+	}
+	return from, to, true
+}